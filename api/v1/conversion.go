@@ -0,0 +1,19 @@
+package v1
+
+// The kinds also served in v1beta2 are converted through their v1 version, which is the
+// one stored by the API server.
+
+// Hub marks GarageS3Instance as the conversion hub.
+func (*GarageS3Instance) Hub() {}
+
+// Hub marks GarageS3ClusterInstance as the conversion hub.
+func (*GarageS3ClusterInstance) Hub() {}
+
+// Hub marks GarageS3AccessKey as the conversion hub.
+func (*GarageS3AccessKey) Hub() {}
+
+// Hub marks GarageS3Bucket as the conversion hub.
+func (*GarageS3Bucket) Hub() {}
+
+// Hub marks GarageS3AdminToken as the conversion hub.
+func (*GarageS3AdminToken) Hub() {}
@@ -0,0 +1,192 @@
+package v1_test
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"abucquet.com/garage-s3-operator/api/v1beta2"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/yaml"
+)
+
+// crdDirectory holds the CustomResourceDefinitions generated from the types.
+var crdDirectory = filepath.Join("..", "..", "config", "crd")
+
+// loadCRDs reads the CustomResourceDefinitions of the crd directory.
+func loadCRDs(t *testing.T) []apiextensionsv1.CustomResourceDefinition {
+	t.Helper()
+	files, err := filepath.Glob(filepath.Join(crdDirectory, v1.GroupName+"_*.yaml"))
+	if err != nil || len(files) == 0 {
+		t.Fatalf("failed to find CRDs in %s: %v", crdDirectory, err)
+	}
+	var crds []apiextensionsv1.CustomResourceDefinition
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatalf("failed to read %s: %v", file, err)
+		}
+		crd := apiextensionsv1.CustomResourceDefinition{}
+		if err := yaml.UnmarshalStrict(data, &crd); err != nil {
+			t.Fatalf("failed to parse %s: %v", file, err)
+		}
+		crds = append(crds, crd)
+	}
+	return crds
+}
+
+// opaqueTypes are serialized as strings or handled by the API server, their schema isn't compared.
+var opaqueTypes = []reflect.Type{
+	reflect.TypeFor[metav1.Time](),
+	reflect.TypeFor[metav1.Duration](),
+	reflect.TypeFor[metav1.ObjectMeta](),
+	reflect.TypeFor[metav1.LabelSelector](),
+}
+
+// compareSchema reports the differences between the JSON serialization of the Go type
+// and the OpenAPI schema of the CRD.
+func compareSchema(t *testing.T, path string, typ reflect.Type, schema *apiextensionsv1.JSONSchemaProps) {
+	t.Helper()
+	for typ.Kind() == reflect.Pointer {
+		typ = typ.Elem()
+	}
+	if slices.Contains(opaqueTypes, typ) || schema.XPreserveUnknownFields != nil && *schema.XPreserveUnknownFields {
+		return
+	}
+	if schema.XIntOrString {
+		return
+	}
+
+	switch typ.Kind() {
+	case reflect.Struct:
+		if schema.Type != "object" {
+			t.Errorf("%s: expected type object, got %q", path, schema.Type)
+			return
+		}
+		fields := map[string]reflect.StructField{}
+		collectJSONFields(typ, fields)
+		for name, field := range fields {
+			property, found := schema.Properties[name]
+			if !found {
+				t.Errorf("%s.%s: field of %s missing from the CRD", path, name, typ.Name())
+				continue
+			}
+			compareSchema(t, path+"."+name, field.Type, &property)
+		}
+		for name := range schema.Properties {
+			if _, found := fields[name]; !found {
+				t.Errorf("%s.%s: CRD property unknown to %s", path, name, typ.Name())
+			}
+		}
+		// Fields omitted when empty can't be required without a default, or their zero value is rejected
+		for _, name := range schema.Required {
+			field, found := fields[name]
+			if found && strings.Contains(field.Tag.Get("json"), ",omitempty") && schema.Properties[name].Default == nil {
+				t.Errorf("%s.%s: required by the CRD but omitted when empty", path, name)
+			}
+		}
+	case reflect.Slice:
+		if schema.Type != "array" || schema.Items == nil || schema.Items.Schema == nil {
+			t.Errorf("%s: expected an array with items, got %q", path, schema.Type)
+			return
+		}
+		compareSchema(t, path+"[]", typ.Elem(), schema.Items.Schema)
+	case reflect.Map:
+		if schema.Type != "object" || schema.AdditionalProperties == nil || schema.AdditionalProperties.Schema == nil {
+			t.Errorf("%s: expected an object with additionalProperties, got %q", path, schema.Type)
+			return
+		}
+		compareSchema(t, path+"{}", typ.Elem(), schema.AdditionalProperties.Schema)
+	case reflect.String:
+		if schema.Type != "string" {
+			t.Errorf("%s: expected type string, got %q", path, schema.Type)
+		}
+	case reflect.Bool:
+		if schema.Type != "boolean" {
+			t.Errorf("%s: expected type boolean, got %q", path, schema.Type)
+		}
+	case reflect.Int, reflect.Int32, reflect.Int64:
+		if schema.Type != "integer" {
+			t.Errorf("%s: expected type integer, got %q", path, schema.Type)
+		}
+	}
+}
+
+// collectJSONFields indexes the serialized fields of the struct by JSON name, flattening inline ones.
+func collectJSONFields(typ reflect.Type, fields map[string]reflect.StructField) {
+	for i := range typ.NumField() {
+		field := typ.Field(i)
+		tag := field.Tag.Get("json")
+		name, _, _ := strings.Cut(tag, ",")
+		if name == "-" || !field.IsExported() {
+			continue
+		}
+		if strings.Contains(tag, ",inline") || (name == "" && field.Anonymous) {
+			collectJSONFields(field.Type, fields)
+			continue
+		}
+		fields[name] = field
+	}
+}
+
+func TestCRDsMatchTypes(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{v1.AddToScheme, v1beta2.AddToScheme} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatalf("failed to build scheme: %v", err)
+		}
+	}
+
+	crds := loadCRDs(t)
+	versions := map[schema.GroupVersionKind]bool{}
+	for _, crd := range crds {
+		for _, version := range crd.Spec.Versions {
+			versions[schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}] = true
+		}
+	}
+	for _, groupVersion := range []schema.GroupVersion{v1.SchemeGroupVersion, v1beta2.SchemeGroupVersion} {
+		for kind := range scheme.KnownTypes(groupVersion) {
+			if item, isList := strings.CutSuffix(kind, "List"); isList && item != "" && scheme.Recognizes(groupVersion.WithKind(item)) && !versions[groupVersion.WithKind(item)] {
+				t.Errorf("no CRD version for kind %s/%s", groupVersion.Version, item)
+			}
+		}
+	}
+	for _, crd := range crds {
+		t.Run(crd.Spec.Names.Kind, func(t *testing.T) {
+			if crd.Spec.Group != v1.GroupName {
+				t.Errorf("expected group %s, got %s", v1.GroupName, crd.Spec.Group)
+			}
+			storage := 0
+			for _, version := range crd.Spec.Versions {
+				gvk := schema.GroupVersionKind{Group: crd.Spec.Group, Version: version.Name, Kind: crd.Spec.Names.Kind}
+				obj, err := scheme.New(gvk)
+				if err != nil {
+					t.Fatalf("no Go type for version %s: %v", version.Name, err)
+				}
+				if version.Storage {
+					storage++
+					if version.Name != v1.GroupVersion {
+						t.Errorf("expected %s to be stored in %s, got %s", crd.Spec.Names.Kind, v1.GroupVersion, version.Name)
+					}
+				}
+				if version.Subresources == nil || version.Subresources.Status == nil {
+					t.Errorf("%s: expected the status subresource", version.Name)
+				}
+				if version.Schema == nil || version.Schema.OpenAPIV3Schema == nil {
+					t.Fatalf("%s: missing schema", version.Name)
+				}
+				compareSchema(t, version.Name, reflect.TypeOf(obj), version.Schema.OpenAPIV3Schema)
+			}
+			if storage != 1 {
+				t.Errorf("expected one storage version, got %d", storage)
+			}
+		})
+	}
+}
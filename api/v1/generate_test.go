@@ -0,0 +1,89 @@
+package v1_test
+
+import (
+	"bytes"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+)
+
+// repositoryRoot is the directory make generate runs controller-gen from.
+var repositoryRoot = filepath.Join("..", "..")
+
+// apiVersions are the packages of api/ holding a version of the types.
+var apiVersions = []string{"v1", "v1beta2"}
+
+// controllerGen returns the controller-gen binary set in CONTROLLER_GEN, installed in bin/
+// by make controller-gen, or found in the PATH, skipping the test when there is none.
+func controllerGen(t *testing.T) string {
+	t.Helper()
+	if path := os.Getenv("CONTROLLER_GEN"); path != "" {
+		return path
+	}
+	if path, err := filepath.Abs(filepath.Join(repositoryRoot, "bin", "controller-gen")); err == nil {
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	if path, err := exec.LookPath("controller-gen"); err == nil {
+		return path
+	}
+	t.Skip("controller-gen not found, install it with make controller-gen")
+	return ""
+}
+
+// compareGenerated fails the test when the committed file differs from the generated one.
+func compareGenerated(t *testing.T, generatedPath string, committedPath string) {
+	t.Helper()
+	generated, err := os.ReadFile(generatedPath)
+	if err != nil {
+		t.Fatalf("failed to read %s: %v", generatedPath, err)
+	}
+	committed, err := os.ReadFile(committedPath)
+	if err != nil {
+		t.Errorf("%s is not committed, run make generate: %v", committedPath, err)
+		return
+	}
+	if !bytes.Equal(generated, committed) {
+		t.Errorf("%s is stale, run make generate", committedPath)
+	}
+}
+
+func TestGeneratedFilesUpToDate(t *testing.T) {
+	gen := controllerGen(t)
+	out := t.TempDir()
+
+	// Same generators as make generate, writing to a temporary directory. Each version is
+	// generated on its own since they all write a zz_generated.deepcopy.go.
+	for _, version := range apiVersions {
+		cmd := exec.Command(gen, "object", "paths=./api/"+version, "output:object:dir="+filepath.Join(out, version))
+		cmd.Dir = repositoryRoot
+		if output, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("controller-gen failed: %v\n%s", err, output)
+		}
+		compareGenerated(t, filepath.Join(out, version, "zz_generated.deepcopy.go"), filepath.Join("..", version, "zz_generated.deepcopy.go"))
+	}
+
+	cmd := exec.Command(gen, "crd", "paths=./api/...", "output:crd:dir="+filepath.Join(out, "crd"))
+	cmd.Dir = repositoryRoot
+	if output, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("controller-gen failed: %v\n%s", err, output)
+	}
+	generatedCRDs, err := filepath.Glob(filepath.Join(out, "crd", "*.yaml"))
+	if err != nil || len(generatedCRDs) == 0 {
+		t.Fatalf("controller-gen generated no CRD: %v", err)
+	}
+	for _, generated := range generatedCRDs {
+		compareGenerated(t, generated, filepath.Join(crdDirectory, filepath.Base(generated)))
+	}
+	committedCRDs, err := filepath.Glob(filepath.Join(crdDirectory, v1.GroupName+"_*.yaml"))
+	if err != nil {
+		t.Fatalf("failed to list CRDs: %v", err)
+	}
+	if len(committedCRDs) != len(generatedCRDs) {
+		t.Errorf("expected %d CRDs in %s, got %d, run make generate", len(generatedCRDs), crdDirectory, len(committedCRDs))
+	}
+}
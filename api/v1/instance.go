@@ -0,0 +1,36 @@
+package v1
+
+// Accessors shared by GarageS3Instance and GarageS3ClusterInstance, so that
+// controllers can handle both kinds the same way.
+
+// GetInstanceSpec returns the Garage connection settings of the instance.
+func (in *GarageS3Instance) GetInstanceSpec() *GarageS3InstanceSpec {
+	return &in.Spec
+}
+
+// GetInstanceStatus returns the observed status of the instance.
+func (in *GarageS3Instance) GetInstanceStatus() *GarageS3InstanceStatus {
+	return &in.Status
+}
+
+// GetAdminTokenSecretNamespace returns the namespace of the admin token Secret,
+// which is the namespace of the instance itself.
+func (in *GarageS3Instance) GetAdminTokenSecretNamespace() string {
+	return in.Namespace
+}
+
+// GetInstanceSpec returns the Garage connection settings of the instance.
+func (in *GarageS3ClusterInstance) GetInstanceSpec() *GarageS3InstanceSpec {
+	return &in.Spec.GarageS3InstanceSpec
+}
+
+// GetInstanceStatus returns the observed status of the instance.
+func (in *GarageS3ClusterInstance) GetInstanceStatus() *GarageS3InstanceStatus {
+	return &in.Status
+}
+
+// GetAdminTokenSecretNamespace returns the namespace of the admin token Secret,
+// as set in the spec since the instance itself is cluster-scoped.
+func (in *GarageS3ClusterInstance) GetAdminTokenSecretNamespace() string {
+	return in.Spec.AdminTokenSecretNamespace
+}
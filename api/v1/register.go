@@ -1,3 +1,7 @@
+// Package v1 contains the v1 API types of the operator. The CustomResourceDefinitions of
+// config/crd and zz_generated.deepcopy.go are generated from them with make generate.
+// +kubebuilder:object:generate=true
+// +groupName=garage-s3-operator.abucquet.com
 package v1
 
 import (
@@ -20,10 +24,20 @@ func addKnownTypes(scheme *runtime.Scheme) error {
 	scheme.AddKnownTypes(SchemeGroupVersion,
 		&GarageS3Instance{},
 		&GarageS3InstanceList{},
+		&GarageS3ClusterInstance{},
+		&GarageS3ClusterInstanceList{},
 		&GarageS3AccessKey{},
 		&GarageS3AccessKeyList{},
 		&GarageS3Bucket{},
 		&GarageS3BucketList{},
+		&GarageS3AdminToken{},
+		&GarageS3AdminTokenList{},
+		&GarageS3Node{},
+		&GarageS3NodeList{},
+		&GarageS3MetadataSnapshotSchedule{},
+		&GarageS3MetadataSnapshotScheduleList{},
+		&GarageS3RepairJob{},
+		&GarageS3RepairJobList{},
 	)
 
 	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
@@ -1,12 +1,24 @@
 package v1
 
-import metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+import (
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
 
 /* *************************************
    GarageS3Instance API Schema and types
    *************************************/
 
 // GarageS3Instance is the Schema for a Garage S3 instance.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3i
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.activeEndpoint`
+// +kubebuilder:printcolumn:name="Layout",type=integer,JSONPath=`.status.layoutVersion`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type GarageS3Instance struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -16,28 +28,403 @@ type GarageS3Instance struct {
 }
 
 // GarageS3InstanceList contains a list of GarageS3Instance
+// +kubebuilder:object:root=true
 type GarageS3InstanceList struct {
 	metav1.TypeMeta `json:",inline"`
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []GarageS3Instance `json:"items"`
 }
 
+// GarageS3InstanceSpec describes how to reach a Garage cluster and how the operator manages it.
 type GarageS3InstanceSpec struct {
-	Url              string `json:"url"`
-	Port             int    `json:"port"`
+	// Address of the Garage admin API
+	// +kubebuilder:default="127.0.0.1"
+	// +optional
+	Url string `json:"url"`
+
+	// Port of the Garage admin API
+	// +kubebuilder:default=3903
+	// +optional
+	Port int `json:"port"`
+
+	// Secret holding the Garage admin API token in its token field
 	AdminTokenSecret string `json:"adminTokenSecret"`
+
+	// Optional admin API endpoints as <host> or <host>:<port>, the operator failing over
+	// to the next one when an endpoint is down. Replaces url when set.
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Optional S3 API of the instance, used to apply the bucket settings only exposed there (e.g. CORS)
+	S3 *GarageS3S3API `json:"s3,omitempty"`
+
+	// Optional Service of the web endpoint, targeted by the Ingresses and HTTPRoutes of website buckets
+	Web *GarageS3WebService `json:"web,omitempty"`
+
+	// Optional policy restricting which namespaces may use this instance and how much
+	Tenancy *GarageS3TenancyPolicy `json:"tenancy,omitempty"`
+
+	// Optional settings inherited by the buckets of this instance
+	BucketDefaults *GarageS3BucketDefaults `json:"bucketDefaults,omitempty"`
+
+	// Optional policy used to assign the first layout of a fresh cluster
+	Bootstrap *GarageS3BootstrapPolicy `json:"bootstrap,omitempty"`
+
+	// Optional static peers the cluster nodes are connected to, as <node id>@<address>:<rpc port>
+	Peers []string `json:"peers,omitempty"`
+
+	// Optional headless Service whose endpoints are the Garage pods to connect
+	PeerService *GarageS3PeerService `json:"peerService,omitempty"`
+
+	// Whether staged layout changes wait for the approve-layout annotation, instead of being
+	// applied by the instance reconciler
+	RequireLayoutApproval bool `json:"requireLayoutApproval,omitempty"`
+
+	// Optional policy handling the blocks which failed to resync
+	BlockErrors *GarageS3BlockErrorPolicy `json:"blockErrors,omitempty"`
+
+	// Optional background worker variables set on every node (e.g. resync-tranquility)
+	WorkerVariables map[string]string `json:"workerVariables,omitempty"`
+
+	// Optional worker variables of specific nodes, overriding workerVariables
+	NodeWorkerVariables []GarageS3NodeWorkerVariables `json:"nodeWorkerVariables,omitempty"`
+}
+
+// GarageS3S3API describes the S3 API of an instance.
+type GarageS3S3API struct {
+	// URL of the S3 API, as http(s)://<host>[:<port>]
+	// +kubebuilder:validation:Pattern=`^https?://[^/?#]+/?$`
+	Endpoint string `json:"endpoint"`
+
+	// Region of the S3 API, s3_region in the Garage configuration
+	// +kubebuilder:default="garage"
+	// +optional
+	Region string `json:"region,omitempty"`
+}
+
+// GarageS3WebService is the Service exposing the web endpoint of the Garage nodes.
+type GarageS3WebService struct {
+	Name string `json:"name"`
+
+	// Namespace of the Service, defaults to the namespace of the admin token Secret
+	Namespace string `json:"namespace,omitempty"`
+
+	// Web port of the Service (default: 3902)
+	Port int `json:"port,omitempty"`
+
+	// Annotation keys the website routes of buckets may set on their Ingresses and HTTPRoutes,
+	// as exact keys or <prefix>* wildcards. Other route annotations are dropped.
+	RouteAnnotations []string `json:"routeAnnotations,omitempty"`
+}
+
+// GarageS3NodeWorkerVariables are the worker variables of a node.
+type GarageS3NodeWorkerVariables struct {
+	// ID or unique ID prefix of the node
+	NodeID string `json:"nodeId"`
+
+	Variables map[string]string `json:"variables"`
+}
+
+// Annotations approving or reverting the staged layout changes of an instance,
+// their value being the pending layout version reported in the instance status.
+const (
+	LayoutApproveAnnotation = "garage-s3-operator.abucquet.com/approve-layout"
+	LayoutRevertAnnotation  = "garage-s3-operator.abucquet.com/revert-layout"
+)
+
+// Annotation purging unrecoverable blocks of an instance, its value being a comma-separated
+// list of block hashes. Only blocks reported unrecoverable in the instance status are purged.
+const BlockPurgeAnnotation = "garage-s3-operator.abucquet.com/purge-blocks"
+
+// GarageS3BlockErrorPolicy describes how the operator handles blocks which failed to resync.
+type GarageS3BlockErrorPolicy struct {
+	// Whether the resync of errored blocks is retried at every reconciliation,
+	// instead of waiting for the backoff of Garage
+	// +kubebuilder:default=false
+	AutoRetry bool `json:"autoRetry,omitempty"`
+
+	// Number of failed resyncs after which a block is reported unrecoverable
+	// and no longer retried (default: 10)
+	// +kubebuilder:validation:Minimum=1
+	UnrecoverableAfter int64 `json:"unrecoverableAfter,omitempty"`
+}
+
+// GarageS3PeerService references the headless Service used to discover Garage peers.
+type GarageS3PeerService struct {
+	Name string `json:"name"`
+
+	// Namespace of the Service, defaults to the namespace of the admin token Secret
+	Namespace string `json:"namespace,omitempty"`
+
+	// RPC port of the Garage nodes (default: the rpc port of the EndpointSlices, or 3901)
+	RPCPort int `json:"rpcPort,omitempty"`
+}
+
+// GarageS3BootstrapPolicy describes how roles are assigned to the nodes of a cluster
+// without layout. Garage nodes are matched to pods by hostname.
+// +kubebuilder:validation:XValidation:rule="has(self.capacity) || has(self.capacityLabel)",message="capacity or capacityLabel is required, a layout needs storage nodes"
+type GarageS3BootstrapPolicy struct {
+	// Namespace of the Garage pods, defaults to the namespace of the admin token Secret
+	PodNamespace string `json:"podNamespace,omitempty"`
+
+	// Pod label holding the zone of a node
+	ZoneLabel string `json:"zoneLabel,omitempty"`
+
+	// Zone of nodes without a zone label (default: garage)
+	Zone string `json:"zone,omitempty"`
+
+	// Pod label holding the capacity of a node (e.g. 100Gi)
+	CapacityLabel string `json:"capacityLabel,omitempty"`
+
+	// Capacity of nodes without a capacity label, nodes without capacity are gateways
+	Capacity *resource.Quantity `json:"capacity,omitempty"`
+
+	// Number of nodes which must be up before bootstrapping, defaults to all known nodes
+	// +kubebuilder:validation:Minimum=1
+	ExpectedNodes *int `json:"expectedNodes,omitempty"`
+}
+
+// GarageS3BucketDefaults describes settings inherited by buckets, unless set in their own spec.
+type GarageS3BucketDefaults struct {
+	// Quota applied to buckets which don't set their own
+	Quota *GarageS3BucketQuota `json:"quota,omitempty"`
+
+	// Website access configuration applied to buckets which don't set their own
+	WebsiteAccess *GarageS3WebsiteAccess `json:"websiteAccess,omitempty"`
+
+	// Access key granted read-only access on every bucket
+	ReadOnlyAccessKey *GarageS3AccessKeyRef `json:"readOnlyAccessKey,omitempty"`
+
+	// Pattern of an alias added to every bucket, {name} and {namespace} are replaced
+	// by the bucket name and namespace (e.g. {namespace}-{name})
+	AliasPattern string `json:"aliasPattern,omitempty"`
+}
+
+// GarageS3AccessKeyRef references a GarageS3AccessKey by name and namespace.
+type GarageS3AccessKeyRef struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+}
+
+// GarageS3TenancyPolicy restricts the buckets and access keys provisioned on an instance.
+type GarageS3TenancyPolicy struct {
+	// Namespaces allowed to reference the instance, all namespaces when unset
+	NamespaceSelector *metav1.LabelSelector `json:"namespaceSelector,omitempty"`
+
+	// Maximum number of buckets per namespace
+	// +kubebuilder:validation:Minimum=0
+	MaxBucketsPerNamespace *int `json:"maxBucketsPerNamespace,omitempty"`
+
+	// Maximum number of access keys per namespace
+	// +kubebuilder:validation:Minimum=0
+	MaxAccessKeysPerNamespace *int `json:"maxAccessKeysPerNamespace,omitempty"`
+
+	// Quota applied to buckets which don't set their own
+	DefaultBucketQuota *GarageS3BucketQuota `json:"defaultBucketQuota,omitempty"`
+
+	// Maximum quota a bucket may request
+	MaxBucketQuota *GarageS3BucketQuota `json:"maxBucketQuota,omitempty"`
+
+	// Prefix bucket names and additional aliases must start with, {namespace} is replaced by
+	// the bucket namespace
+	BucketNamePrefix string `json:"bucketNamePrefix,omitempty"`
+
+	// Domains additional aliases may use besides the name prefix, as exact names or
+	// *.<domain> wildcards, {namespace} being replaced by the bucket namespace
+	WebsiteDomains []string `json:"websiteDomains,omitempty"`
 }
 
 // GarageS3InstanceStatus represents the observed state of the GarageS3Instance.
 type GarageS3InstanceStatus struct {
+	// Admin API endpoint currently used
+	ActiveEndpoint string `json:"activeEndpoint,omitempty"`
+
+	// Reachability of the admin API endpoints
+	Endpoints []GarageS3EndpointStatus `json:"endpoints,omitempty"`
+
+	// Version of the cluster layout currently applied
+	LayoutVersion int64 `json:"layoutVersion,omitempty"`
+
+	// Connection state of the known peers
+	Peers []GarageS3PeerStatus `json:"peers,omitempty"`
+
+	// Staged layout changes, not applied yet
+	PendingLayout *GarageS3PendingLayout `json:"pendingLayout,omitempty"`
+
+	// Blocks which failed to resync, per node
+	BlockErrors []GarageS3NodeBlockErrors `json:"blockErrors,omitempty"`
+
+	// Values of the managed worker variables, per node
+	WorkerVariables []GarageS3NodeWorkerVariablesStatus `json:"workerVariables,omitempty"`
+
+	// Summary of the background workers, per node
+	Workers []GarageS3NodeWorkers `json:"workers,omitempty"`
+
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
+// GarageS3NodeWorkers summarizes the background workers of a node.
+type GarageS3NodeWorkers struct {
+	NodeID string `json:"nodeId"`
+
+	// Number of workers per state: busy, throttled, idle or done
+	States map[string]int `json:"states,omitempty"`
+
+	// Number of errors encountered by all the workers
+	Errors int64 `json:"errors,omitempty"`
+
+	// Number of blocks waiting to be resynced
+	ResyncQueueLength int64 `json:"resyncQueueLength,omitempty"`
+
+	// Workers failing repeatedly or holding persistent errors
+	Unhealthy []GarageS3WorkerStatus `json:"unhealthy,omitempty"`
+
+	// Error of the last listing on this node
+	Error string `json:"error,omitempty"`
+}
+
+// GarageS3NodeWorkerVariablesStatus reports the worker variables applied on a node.
+type GarageS3NodeWorkerVariablesStatus struct {
+	NodeID string `json:"nodeId"`
+
+	// Current values of the variables set by the operator
+	Variables map[string]string `json:"variables,omitempty"`
+
+	// Error of the last reconciliation on this node
+	Error string `json:"error,omitempty"`
+}
+
+// GarageS3NodeBlockErrors reports the blocks of a node which failed to resync.
+type GarageS3NodeBlockErrors struct {
+	NodeID string `json:"nodeId"`
+
+	// Number of blocks which failed to resync
+	Count int64 `json:"count"`
+
+	// Blocks still referenced which reached the unrecoverable error count, at most 20
+	Unrecoverable []GarageS3UnrecoverableBlock `json:"unrecoverable,omitempty"`
+
+	// Error of the last collection on this node
+	Error string `json:"error,omitempty"`
+}
+
+// GarageS3UnrecoverableBlock is a block which repeatedly failed to resync.
+type GarageS3UnrecoverableBlock struct {
+	Hash string `json:"hash"`
+
+	ErrorCount int64 `json:"errorCount"`
+
+	// Objects deleted if the block is purged, as <bucket id>/<key>
+	Objects []string `json:"objects,omitempty"`
+}
+
+// GarageS3EndpointStatus is the reachability of an admin API endpoint.
+type GarageS3EndpointStatus struct {
+	Endpoint string `json:"endpoint"`
+
+	Reachable bool `json:"reachable"`
+
+	// Error of the last health check
+	Error string `json:"error,omitempty"`
+}
+
+// GarageS3PeerStatus is the connection state of a Garage peer.
+type GarageS3PeerStatus struct {
+	// Node identifier, empty when it could not be discovered
+	NodeID string `json:"nodeId,omitempty"`
+
+	// RPC address of the peer
+	Address string `json:"address"`
+
+	Connected bool `json:"connected"`
+
+	// Error of the last discovery or connection attempt
+	Error string `json:"error,omitempty"`
+}
+
+// GarageS3PendingLayout is the preview of staged layout changes.
+type GarageS3PendingLayout struct {
+	// Version the staged changes are applied as
+	Version int64 `json:"version"`
+
+	// Output of the layout preview, describing the partition moves
+	Message []string `json:"message,omitempty"`
+
+	// Roles of the nodes in the new layout
+	Roles []GarageS3LayoutRole `json:"roles,omitempty"`
+
+	// Error returned by the preview, when the new layout can't be computed
+	Error string `json:"error,omitempty"`
+}
+
+// GarageS3LayoutRole is the role of a node in a cluster layout.
+type GarageS3LayoutRole struct {
+	NodeID string `json:"nodeId"`
+
+	Zone string `json:"zone"`
+
+	// Capacity in bytes, unset for gateway nodes
+	Capacity *int64 `json:"capacity,omitempty"`
+
+	// Capacity actually usable by the layout in bytes
+	UsableCapacity *int64 `json:"usableCapacity,omitempty"`
+
+	// Number of partitions stored on the node
+	StoredPartitions *int64 `json:"storedPartitions,omitempty"`
+}
+
+/* ********************************************
+   GarageS3ClusterInstance API Schema and types
+   ********************************************/
+
+// GarageS3ClusterInstance is the Schema for a cluster-scoped Garage S3 instance,
+// usable by buckets and access keys of any namespace.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=gs3ci
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.activeEndpoint`
+// +kubebuilder:printcolumn:name="Layout",type=integer,JSONPath=`.status.layoutVersion`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3ClusterInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3ClusterInstanceSpec `json:"spec"`
+	Status GarageS3InstanceStatus      `json:"status,omitempty"`
+}
+
+// GarageS3ClusterInstanceList contains a list of GarageS3ClusterInstance
+// +kubebuilder:object:root=true
+type GarageS3ClusterInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3ClusterInstance `json:"items"`
+}
+
+// GarageS3ClusterInstanceSpec describes a cluster-scoped Garage S3 instance.
+type GarageS3ClusterInstanceSpec struct {
+	GarageS3InstanceSpec `json:",inline"`
+
+	// Namespace of the Secret referenced by AdminTokenSecret
+	AdminTokenSecretNamespace string `json:"adminTokenSecretNamespace"`
+}
+
 /* **************************************
    GarageS3AccessKey API Schema and types
    **************************************/
 
 // GarageS3AccessKey is the Schema for a Garage S3 access key.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3ak
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.spec.instanceRef.name`
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secret`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type GarageS3AccessKey struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -47,28 +434,65 @@ type GarageS3AccessKey struct {
 }
 
 // GarageS3AccessKeyList contains a list of GarageS3AccessKey
+// +kubebuilder:object:root=true
 type GarageS3AccessKeyList struct {
 	metav1.TypeMeta `json:",inline"`
 	metav1.ListMeta `json:"metadata,omitempty"`
 	Items           []GarageS3AccessKey `json:"items"`
 }
 
+// GarageS3AccessKeySpec describes the desired state of an Access Key.
 type GarageS3AccessKeySpec struct {
-	InstanceRef     GarageS3InstanceRef `json:"instanceRef"`
-	CanCreateBucket bool                `json:"canCreateBucket,omitempty"`
-	Expiration      string              `json:"expiration,omitempty"`
-	NeverExpires    bool                `json:"neverExpires,omitempty"`
+	// Reference to the instance this Access Key belongs to
+	InstanceRef GarageS3InstanceRef `json:"instanceRef"`
+
+	// Whether this Access Key can create buckets
+	// +kubebuilder:default=false
+	CanCreateBucket bool `json:"canCreateBucket,omitempty"`
+
+	// Expiration date of the Access Key in RFC3339 format
+	Expiration string `json:"expiration,omitempty"`
+
+	// Whether this Access Key never expires, set it to false for expiration to apply
+	// +kubebuilder:default=true
+	NeverExpires bool `json:"neverExpires,omitempty"`
 }
 
-// GarageS3InstanceRef references a GarageS3Instance by name and namespace.
+// Kinds a GarageS3InstanceRef can point to.
+const (
+	GarageS3InstanceKind        = "GarageS3Instance"
+	GarageS3ClusterInstanceKind = "GarageS3ClusterInstance"
+)
+
+// GarageS3InstanceRef references a GarageS3Instance by name and namespace,
+// or a GarageS3ClusterInstance by name.
+// +kubebuilder:validation:XValidation:rule="self.kind == 'GarageS3ClusterInstance' || has(self.namespace)",message="namespace is required when referencing a GarageS3Instance"
 type GarageS3InstanceRef struct {
-	Name      string `json:"name"`
-	Namespace string `json:"namespace"`
+	// Kind of the referenced instance, GarageS3Instance (default) or GarageS3ClusterInstance
+	// +kubebuilder:validation:Enum=GarageS3Instance;GarageS3ClusterInstance
+	// +kubebuilder:default=GarageS3Instance
+	Kind string `json:"kind,omitempty"`
+
+	// Name of the GarageS3Instance or GarageS3ClusterInstance
+	Name string `json:"name"`
+
+	// Namespace of the GarageS3Instance, unused for GarageS3ClusterInstance
+	Namespace string `json:"namespace,omitempty"`
+}
+
+// IsClusterScoped returns true when the reference points to a GarageS3ClusterInstance.
+func (r GarageS3InstanceRef) IsClusterScoped() bool {
+	return r.Kind == GarageS3ClusterInstanceKind
 }
 
 // GarageS3AccessKeyStatus represents the observed state of the GarageS3AccessKey.
 type GarageS3AccessKeyStatus struct {
-	Secret     string             `json:"secret,omitempty"`
+	// Secret holding the credentials of the Access Key
+	Secret string `json:"secret,omitempty"`
+
+	// ID of the Garage access key provisioned for this Access Key, the only key deleted with it
+	KeyID string `json:"keyId,omitempty"`
+
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
 }
 
@@ -77,6 +501,14 @@ type GarageS3AccessKeyStatus struct {
  **************************************/
 
 // GarageS3Bucket is the Schema for an S3 Bucket related to a Garage S3 Instance.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3b
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.spec.instanceRef.name`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
 type GarageS3Bucket struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
@@ -86,6 +518,7 @@ type GarageS3Bucket struct {
 }
 
 // GarageS3BucketList contains a list of GarageS3Bucket
+// +kubebuilder:object:root=true
 type GarageS3BucketList struct {
 	metav1.TypeMeta `json:",inline"`
 	metav1.ListMeta `json:"metadata,omitempty"`
@@ -108,6 +541,12 @@ type GarageS3BucketSpec struct {
 
 	// List of permissions to apply to this bucket
 	Permissions []GarageS3BucketPermission `json:"permissions,omitempty"`
+
+	// Optional CORS rules of the bucket, applied through the S3 API of the instance
+	CORS []GarageS3CORSRule `json:"cors,omitempty"`
+
+	// Optional lifecycle configuration of the bucket, applied through the S3 API of the instance
+	Lifecycle *GarageS3BucketLifecycle `json:"lifecycle,omitempty"`
 }
 
 // GarageS3BucketQuota describes optional quota limits
@@ -117,13 +556,155 @@ type GarageS3BucketQuota struct {
 }
 
 // GarageS3WebsiteAccess describes website access configuration for a bucket
+// +kubebuilder:validation:XValidation:rule="!has(self.redirectAllRequestsTo) || !(has(self.indexDocument) || has(self.errorDocument) || has(self.routingRules))",message="redirectAllRequestsTo excludes the other website settings"
 type GarageS3WebsiteAccess struct {
 	// Enabled controls whether the bucket is configured as a website
+	// +kubebuilder:default=false
 	Enabled bool `json:"enabled"`
 	// IndexDocument is the name of the index document (e.g., index.html)
 	IndexDocument string `json:"indexDocument,omitempty"`
 	// ErrorDocument is the name of the error document (e.g., error.html)
 	ErrorDocument string `json:"errorDocument,omitempty"`
+	// RedirectAllRequestsTo redirects every request of the website to another host,
+	// applied through the S3 API of the instance
+	RedirectAllRequestsTo *GarageS3WebsiteRedirectAll `json:"redirectAllRequestsTo,omitempty"`
+	// RoutingRules redirect the requests matching their condition, applied through the
+	// S3 API of the instance
+	RoutingRules []GarageS3WebsiteRoutingRule `json:"routingRules,omitempty"`
+	// Route exposes the domain-like additional aliases of the bucket through an Ingress or
+	// an HTTPRoute each, targeting the web Service of the instance
+	Route *GarageS3WebsiteRoute `json:"route,omitempty"`
+}
+
+// GarageS3WebsiteRoute describes the Ingresses or HTTPRoutes created for the domains of a website
+// +kubebuilder:validation:XValidation:rule="!has(self.kind) || self.kind != 'HTTPRoute' || has(self.parentRef)",message="HTTPRoutes need a parentRef"
+// +kubebuilder:validation:XValidation:rule="!has(self.clusterIssuer) || !has(self.issuer)",message="clusterIssuer and issuer are mutually exclusive"
+type GarageS3WebsiteRoute struct {
+	// Kind of the created objects
+	// +kubebuilder:validation:Enum=Ingress;HTTPRoute
+	// +kubebuilder:default=Ingress
+	// +optional
+	Kind string `json:"kind,omitempty"`
+	// IngressClassName of the Ingresses, the default class of the cluster when empty
+	IngressClassName string `json:"ingressClassName,omitempty"`
+	// ParentRef is the Gateway the HTTPRoutes attach to
+	ParentRef *GarageS3GatewayRef `json:"parentRef,omitempty"`
+	// ClusterIssuer is the cert-manager ClusterIssuer of the Ingress certificates. HTTPRoutes
+	// get theirs from the listeners of the Gateway.
+	ClusterIssuer string `json:"clusterIssuer,omitempty"`
+	// Issuer is the cert-manager Issuer of the Ingress certificates, in the namespace of the bucket
+	Issuer string `json:"issuer,omitempty"`
+	// Annotations added to the created objects, restricted to the routeAnnotations of the
+	// instance web Service
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+// GarageS3GatewayRef references the Gateway listener HTTPRoutes attach to
+type GarageS3GatewayRef struct {
+	Name string `json:"name"`
+	// Namespace of the Gateway, the one of the bucket when empty
+	Namespace string `json:"namespace,omitempty"`
+	// SectionName is the listener of the Gateway, all of them when empty
+	SectionName string `json:"sectionName,omitempty"`
+}
+
+// GarageS3WebsiteRedirectAll describes the host every request of a website is redirected to
+type GarageS3WebsiteRedirectAll struct {
+	// Host name requests are redirected to
+	HostName string `json:"hostName"`
+	// Protocol of the redirection, the one of the request when empty
+	// +kubebuilder:validation:Enum=http;https
+	Protocol string `json:"protocol,omitempty"`
+}
+
+// GarageS3WebsiteRoutingRule describes a redirection of the website requests matching a condition
+type GarageS3WebsiteRoutingRule struct {
+	// Condition of the redirection, every request matching when unset
+	Condition *GarageS3WebsiteRoutingCondition `json:"condition,omitempty"`
+	// Redirection applied to the matching requests
+	Redirect GarageS3WebsiteRoutingRedirect `json:"redirect"`
+}
+
+// GarageS3WebsiteRoutingCondition describes the requests a routing rule applies to
+type GarageS3WebsiteRoutingCondition struct {
+	// Prefix of the object keys of the requests
+	KeyPrefixEquals string `json:"keyPrefixEquals,omitempty"`
+	// HTTP error code the request would otherwise be answered with
+	// +kubebuilder:validation:Minimum=400
+	// +kubebuilder:validation:Maximum=599
+	HttpErrorCodeReturnedEquals *int32 `json:"httpErrorCodeReturnedEquals,omitempty"`
+}
+
+// GarageS3WebsiteRoutingRedirect describes the redirection of a routing rule
+// +kubebuilder:validation:XValidation:rule="!(has(self.replaceKeyPrefixWith) && has(self.replaceKeyWith))",message="replaceKeyPrefixWith and replaceKeyWith are mutually exclusive"
+type GarageS3WebsiteRoutingRedirect struct {
+	// Host name of the redirection, the one of the request when empty
+	HostName string `json:"hostName,omitempty"`
+	// Protocol of the redirection, the one of the request when empty
+	// +kubebuilder:validation:Enum=http;https
+	Protocol string `json:"protocol,omitempty"`
+	// HTTP status code of the redirection (default: 301)
+	// +kubebuilder:validation:Minimum=300
+	// +kubebuilder:validation:Maximum=399
+	HttpRedirectCode *int32 `json:"httpRedirectCode,omitempty"`
+	// Prefix replacing the keyPrefixEquals of the condition in the object key
+	ReplaceKeyPrefixWith string `json:"replaceKeyPrefixWith,omitempty"`
+	// Object key replacing the one of the request
+	ReplaceKeyWith string `json:"replaceKeyWith,omitempty"`
+}
+
+// GarageS3CORSRule describes a CORS rule of a bucket
+type GarageS3CORSRule struct {
+	// Optional identifier of the rule
+	ID string `json:"id,omitempty"`
+
+	// Origins allowed to send cross-origin requests, * for any
+	// +kubebuilder:validation:MinItems=1
+	AllowedOrigins []string `json:"allowedOrigins"`
+
+	// HTTP methods allowed in cross-origin requests
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Enum=GET;PUT;POST;DELETE;HEAD
+	AllowedMethods []string `json:"allowedMethods"`
+
+	// Headers allowed in preflight requests, * for any
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+
+	// Response headers exposed to the browser
+	ExposeHeaders []string `json:"exposeHeaders,omitempty"`
+
+	// Time in seconds browsers may cache the preflight response
+	// +kubebuilder:validation:Minimum=0
+	MaxAgeSeconds *int32 `json:"maxAgeSeconds,omitempty"`
+}
+
+// GarageS3BucketLifecycle describes the lifecycle configuration of a bucket
+type GarageS3BucketLifecycle struct {
+	// Lifecycle rules, applied to the objects matching their prefix
+	Rules []GarageS3LifecycleRule `json:"rules,omitempty"`
+}
+
+// GarageS3LifecycleRule describes a lifecycle rule of a bucket
+// +kubebuilder:validation:XValidation:rule="!(has(self.expirationDays) && has(self.expirationDate))",message="expirationDays and expirationDate are mutually exclusive"
+// +kubebuilder:validation:XValidation:rule="has(self.expirationDays) || has(self.expirationDate) || has(self.abortIncompleteMultipartUploadDays)",message="a rule needs an expiration or abortIncompleteMultipartUploadDays"
+type GarageS3LifecycleRule struct {
+	// Optional identifier of the rule
+	ID string `json:"id,omitempty"`
+
+	// Prefix of the object keys the rule applies to, all objects when empty
+	Prefix string `json:"prefix,omitempty"`
+
+	// Number of days after their creation objects are deleted
+	// +kubebuilder:validation:Minimum=1
+	ExpirationDays *int32 `json:"expirationDays,omitempty"`
+
+	// Date from which objects are deleted, as YYYY-MM-DD
+	// +kubebuilder:validation:Format=date
+	ExpirationDate string `json:"expirationDate,omitempty"`
+
+	// Number of days after their initiation incomplete multipart uploads are aborted
+	// +kubebuilder:validation:Minimum=1
+	AbortIncompleteMultipartUploadDays *int32 `json:"abortIncompleteMultipartUploadDays,omitempty"`
 }
 
 // GarageS3BucketPermission represents an ACL/permission to grant on the bucket
@@ -131,17 +712,393 @@ type GarageS3BucketPermission struct {
 	// Name of the GarageS3AccessKey to which to apply the permission
 	AccessKeyName string `json:"accessKeyName"`
 
+	// Namespace of the GarageS3AccessKey, defaults to the bucket namespace
+	AccessKeyNamespace string `json:"accessKeyNamespace,omitempty"`
+
 	// Grant read permission
+	// +kubebuilder:default=false
 	Read bool `json:"read,omitempty"`
 
 	// Grant write permission
+	// +kubebuilder:default=false
 	Write bool `json:"write,omitempty"`
 
 	// Grant owner permission
+	// +kubebuilder:default=false
 	Owner bool `json:"owner,omitempty"`
 }
 
 // GarageS3BucketStatus represents the observed state of the Bucket
 type GarageS3BucketStatus struct {
 	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ID of the Garage bucket provisioned for this Bucket, the only bucket deleted with it
+	BucketID string `json:"bucketId,omitempty"`
+
+	// Configuration applied to the bucket, after merging the instance bucket defaults
+	EffectiveConfig *GarageS3BucketEffectiveConfig `json:"effectiveConfig,omitempty"`
+
+	// Lifecycle rules of the bucket, read back from Garage through the S3 API once applied
+	Lifecycle *GarageS3BucketLifecycle `json:"lifecycle,omitempty"`
+
+	// Result of the Garage check of each domain alias of a website bucket
+	// +listType=map
+	// +listMapKey=domain
+	WebsiteDomains []GarageS3WebsiteDomainStatus `json:"websiteDomains,omitempty"`
+}
+
+// GarageS3WebsiteDomainStatus is the result of the check of a website domain by Garage.
+type GarageS3WebsiteDomainStatus struct {
+	Domain string `json:"domain"`
+
+	// Whether Garage serves the website of a bucket on the domain
+	Served bool `json:"served"`
+
+	// Answer of Garage when the domain is not served
+	Message string `json:"message,omitempty"`
+
+	// Time of the first check giving the current answer, kept while the answer doesn't change
+	LastCheckTime metav1.Time `json:"lastCheckTime"`
+}
+
+// Origins of an effective bucket setting.
+const (
+	BucketConfigSourceBucket        = "bucket"
+	BucketConfigSourceInstance      = "instance"
+	BucketConfigSourceTenancyPolicy = "tenancyPolicy"
+)
+
+// GarageS3BucketEffectiveConfig describes the configuration applied to a bucket.
+type GarageS3BucketEffectiveConfig struct {
+	Quota         *GarageS3BucketQuota       `json:"quota,omitempty"`
+	WebsiteAccess *GarageS3WebsiteAccess     `json:"websiteAccess,omitempty"`
+	Aliases       []string                   `json:"aliases,omitempty"`
+	Permissions   []GarageS3BucketPermission `json:"permissions,omitempty"`
+	CORS          []GarageS3CORSRule         `json:"cors,omitempty"`
+
+	// Origin of each setting (bucket, instance or tenancyPolicy), keyed by setting path
+	// such as quota.maxBytes, aliases.<alias> or permissions.<namespace>/<key>
+	Sources map[string]string `json:"sources,omitempty"`
+}
+
+/* ***************************************
+   GarageS3AdminToken API Schema and types
+   ***************************************/
+
+// GarageS3AdminToken is the Schema for a scoped Garage admin API token.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3at
+// +kubebuilder:storageversion
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secret`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expiresAt`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3AdminToken struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3AdminTokenSpec   `json:"spec"`
+	Status GarageS3AdminTokenStatus `json:"status,omitempty"`
+}
+
+// GarageS3AdminTokenList contains a list of GarageS3AdminToken
+// +kubebuilder:object:root=true
+type GarageS3AdminTokenList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3AdminToken `json:"items"`
+}
+
+// Annotation requesting the rotation of an admin token, rotated each time its value changes.
+const AdminTokenRotateAnnotation = "garage-s3-operator.abucquet.com/rotate"
+
+// GarageS3AdminTokenSpec describes the desired state of an admin API token.
+// +kubebuilder:validation:XValidation:rule="!(has(self.expiration) && has(self.validity))",message="expiration and validity are mutually exclusive"
+type GarageS3AdminTokenSpec struct {
+	// Reference to the instance the token is created on. The token must be in the
+	// namespace of the instance admin token Secret.
+	InstanceRef GarageS3InstanceRef `json:"instanceRef"`
+
+	// Admin API endpoints the token may call (e.g. Metrics, ListBuckets), * for all
+	// +kubebuilder:validation:MinItems=1
+	Scope []string `json:"scope"`
+
+	// Expiration date of the token in RFC3339 format
+	Expiration string `json:"expiration,omitempty"`
+
+	// Validity of the token, which is rotated once 80% of it has elapsed (e.g. 720h)
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Name of the Secret the token is written to, defaults to <name>-gs3at
+	SecretName string `json:"secretName,omitempty"`
+}
+
+// GarageS3AdminTokenStatus represents the observed state of the GarageS3AdminToken.
+type GarageS3AdminTokenStatus struct {
+	// Secret the token is written to
+	Secret string `json:"secret,omitempty"`
+
+	// Garage identifier of the current token
+	TokenID string `json:"tokenId,omitempty"`
+
+	// Creation time of the current token
+	CreatedAt *metav1.Time `json:"createdAt,omitempty"`
+
+	// Expiration time of the current token, unset when it never expires
+	ExpiresAt *metav1.Time `json:"expiresAt,omitempty"`
+
+	// Value of the rotate annotation handled by the last rotation
+	RotationRequest string `json:"rotationRequest,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+/* *********************************
+   GarageS3Node API Schema and types
+   *********************************/
+
+// GarageS3Node is the Schema for a node of a Garage cluster, used to drain it.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3n
+// +kubebuilder:printcolumn:name="Node",type=string,JSONPath=`.status.nodeId`,priority=1
+// +kubebuilder:printcolumn:name="Zone",type=string,JSONPath=`.status.zone`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3Node struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3NodeSpec   `json:"spec"`
+	Status GarageS3NodeStatus `json:"status,omitempty"`
+}
+
+// GarageS3NodeList contains a list of GarageS3Node
+// +kubebuilder:object:root=true
+type GarageS3NodeList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3Node `json:"items"`
+}
+
+// GarageS3NodeSpec describes the desired state of a Garage node.
+type GarageS3NodeSpec struct {
+	// Reference to the instance of the cluster the node belongs to
+	InstanceRef GarageS3InstanceRef `json:"instanceRef"`
+
+	// Garage node ID, or a unique prefix of it
+	// +kubebuilder:validation:MinLength=1
+	NodeID string `json:"nodeId"`
+
+	// Whether the node role is removed from the layout and its data moved to other nodes
+	// +kubebuilder:default=false
+	Drain bool `json:"drain,omitempty"`
+}
+
+// Phases of a Garage node.
+const (
+	NodePhaseActive   = "Active"
+	NodePhaseDraining = "Draining"
+	NodePhaseDrained  = "Drained"
+)
+
+// GarageS3NodeStatus represents the observed state of the GarageS3Node.
+type GarageS3NodeStatus struct {
+	// Full Garage node ID
+	NodeID string `json:"nodeId,omitempty"`
+
+	// Active, Draining or Drained
+	Phase string `json:"phase,omitempty"`
+
+	// Zone of the node in the current layout
+	Zone string `json:"zone,omitempty"`
+
+	// Capacity of the node in the current layout in bytes, unset for gateway nodes
+	Capacity *int64 `json:"capacity,omitempty"`
+
+	// Layout version removing the node role
+	DrainLayoutVersion int64 `json:"drainLayoutVersion,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+/* *****************************************************
+   GarageS3MetadataSnapshotSchedule API Schema and types
+   *****************************************************/
+
+// GarageS3MetadataSnapshotSchedule is the Schema for scheduled metadata snapshots of Garage nodes.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3mss
+// +kubebuilder:printcolumn:name="Schedule",type=string,JSONPath=`.spec.schedule`
+// +kubebuilder:printcolumn:name="Suspend",type=boolean,JSONPath=`.spec.suspend`
+// +kubebuilder:printcolumn:name="Last Success",type=date,JSONPath=`.status.lastSuccessfulTime`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3MetadataSnapshotSchedule struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3MetadataSnapshotScheduleSpec   `json:"spec"`
+	Status GarageS3MetadataSnapshotScheduleStatus `json:"status,omitempty"`
+}
+
+// GarageS3MetadataSnapshotScheduleList contains a list of GarageS3MetadataSnapshotSchedule
+// +kubebuilder:object:root=true
+type GarageS3MetadataSnapshotScheduleList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3MetadataSnapshotSchedule `json:"items"`
+}
+
+// GarageS3MetadataSnapshotScheduleSpec describes when and where metadata snapshots are taken.
+type GarageS3MetadataSnapshotScheduleSpec struct {
+	// Reference to the instance of the cluster to snapshot
+	InstanceRef GarageS3InstanceRef `json:"instanceRef"`
+
+	// Cron schedule of the snapshots (e.g. 0 3 * * *)
+	Schedule string `json:"schedule"`
+
+	// IDs or unique ID prefixes of the nodes to snapshot, all nodes when empty
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Whether scheduling is suspended
+	// +kubebuilder:default=false
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// GarageS3MetadataSnapshotScheduleStatus represents the observed state of the GarageS3MetadataSnapshotSchedule.
+type GarageS3MetadataSnapshotScheduleStatus struct {
+	// Time of the last snapshot
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// Time of the last snapshot successful on all nodes
+	LastSuccessfulTime *metav1.Time `json:"lastSuccessfulTime,omitempty"`
+
+	// Result of the last snapshot on each node
+	Nodes []GarageS3NodeOperationStatus `json:"nodes,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// GarageS3NodeOperationStatus is the result of an operation on a Garage node.
+type GarageS3NodeOperationStatus struct {
+	NodeID string `json:"nodeId"`
+
+	Success bool `json:"success"`
+
+	// Error returned by the node
+	Error string `json:"error,omitempty"`
+}
+
+/* **************************************
+   GarageS3RepairJob API Schema and types
+   **************************************/
+
+// GarageS3RepairJob is the Schema for a repair operation launched on Garage nodes.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3rj
+// +kubebuilder:printcolumn:name="Repair",type=string,JSONPath=`.spec.repair`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3RepairJob struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3RepairJobSpec   `json:"spec"`
+	Status GarageS3RepairJobStatus `json:"status,omitempty"`
+}
+
+// GarageS3RepairJobList contains a list of GarageS3RepairJob
+// +kubebuilder:object:root=true
+type GarageS3RepairJobList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3RepairJob `json:"items"`
+}
+
+// GarageS3RepairJobSpec describes the repair operation to launch.
+// +kubebuilder:validation:XValidation:rule="self.repair != 'scrub' || has(self.scrubCommand)",message="scrubCommand is required when repair is scrub"
+type GarageS3RepairJobSpec struct {
+	// Reference to the instance of the cluster to repair
+	InstanceRef GarageS3InstanceRef `json:"instanceRef"`
+
+	// Repair to launch: tables, blocks, versions, multipartUploads, blockRefs, blockRc,
+	// rebalance, aliases, clearResyncQueue or scrub
+	// +kubebuilder:validation:Enum=tables;blocks;versions;multipartUploads;blockRefs;blockRc;rebalance;aliases;clearResyncQueue;scrub
+	Repair string `json:"repair"`
+
+	// Command sent to the scrub worker when repair is scrub: start, pause, resume or cancel
+	// +kubebuilder:validation:Enum=start;pause;resume;cancel
+	ScrubCommand string `json:"scrubCommand,omitempty"`
+
+	// IDs or unique ID prefixes of the nodes to repair, all nodes when empty
+	Nodes []string `json:"nodes,omitempty"`
+
+	// Optional cron schedule relaunching the repair, the job runs once when unset
+	Schedule string `json:"schedule,omitempty"`
+}
+
+// Phases of a repair job run.
+const (
+	RepairJobPhaseRunning   = "Running"
+	RepairJobPhaseSucceeded = "Succeeded"
+	RepairJobPhaseFailed    = "Failed"
+)
+
+// GarageS3RepairJobStatus represents the observed state of the GarageS3RepairJob.
+type GarageS3RepairJobStatus struct {
+	// Running, Succeeded or Failed
+	// +kubebuilder:validation:Enum=Running;Succeeded;Failed
+	Phase string `json:"phase,omitempty"`
+
+	// Launch time of the last run
+	StartTime *metav1.Time `json:"startTime,omitempty"`
+
+	// Time the workers of the last run completed
+	CompletionTime *metav1.Time `json:"completionTime,omitempty"`
+
+	// Result of the launch on each node
+	Nodes []GarageS3NodeOperationStatus `json:"nodes,omitempty"`
+
+	// Workers started by the last run
+	Workers []GarageS3WorkerStatus `json:"workers,omitempty"`
+
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+}
+
+// GarageS3WorkerStatus is the state of a background worker of a Garage node.
+type GarageS3WorkerStatus struct {
+	NodeID string `json:"nodeId"`
+
+	WorkerID int64 `json:"workerId"`
+
+	Name string `json:"name"`
+
+	// busy, throttled, idle or done
+	State string `json:"state"`
+
+	Progress string `json:"progress,omitempty"`
+
+	// Number of errors encountered by the worker
+	Errors int64 `json:"errors,omitempty"`
+
+	// Number of errors since the last success
+	ConsecutiveErrors int64 `json:"consecutiveErrors,omitempty"`
+
+	// Number of items the worker failed to process, such as blocks for the resync and scrub workers
+	PersistentErrors int64 `json:"persistentErrors,omitempty"`
+
+	// Number of items waiting to be processed
+	QueueLength int64 `json:"queueLength,omitempty"`
+
+	LastError string `json:"lastError,omitempty"`
 }
@@ -0,0 +1,1738 @@
+//go:build !ignore_autogenerated
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AccessKey) DeepCopyInto(out *GarageS3AccessKey) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AccessKey.
+func (in *GarageS3AccessKey) DeepCopy() *GarageS3AccessKey {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AccessKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3AccessKey) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AccessKeyList) DeepCopyInto(out *GarageS3AccessKeyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3AccessKey, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AccessKeyList.
+func (in *GarageS3AccessKeyList) DeepCopy() *GarageS3AccessKeyList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AccessKeyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3AccessKeyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AccessKeyRef) DeepCopyInto(out *GarageS3AccessKeyRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AccessKeyRef.
+func (in *GarageS3AccessKeyRef) DeepCopy() *GarageS3AccessKeyRef {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AccessKeyRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AccessKeySpec) DeepCopyInto(out *GarageS3AccessKeySpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AccessKeySpec.
+func (in *GarageS3AccessKeySpec) DeepCopy() *GarageS3AccessKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AccessKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AccessKeyStatus) DeepCopyInto(out *GarageS3AccessKeyStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AccessKeyStatus.
+func (in *GarageS3AccessKeyStatus) DeepCopy() *GarageS3AccessKeyStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AccessKeyStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AdminToken) DeepCopyInto(out *GarageS3AdminToken) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AdminToken.
+func (in *GarageS3AdminToken) DeepCopy() *GarageS3AdminToken {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AdminToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3AdminToken) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AdminTokenList) DeepCopyInto(out *GarageS3AdminTokenList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3AdminToken, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AdminTokenList.
+func (in *GarageS3AdminTokenList) DeepCopy() *GarageS3AdminTokenList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AdminTokenList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3AdminTokenList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AdminTokenSpec) DeepCopyInto(out *GarageS3AdminTokenSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.Scope != nil {
+		in, out := &in.Scope, &out.Scope
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Validity != nil {
+		in, out := &in.Validity, &out.Validity
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AdminTokenSpec.
+func (in *GarageS3AdminTokenSpec) DeepCopy() *GarageS3AdminTokenSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AdminTokenSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AdminTokenStatus) DeepCopyInto(out *GarageS3AdminTokenStatus) {
+	*out = *in
+	if in.CreatedAt != nil {
+		in, out := &in.CreatedAt, &out.CreatedAt
+		*out = (*in).DeepCopy()
+	}
+	if in.ExpiresAt != nil {
+		in, out := &in.ExpiresAt, &out.ExpiresAt
+		*out = (*in).DeepCopy()
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AdminTokenStatus.
+func (in *GarageS3AdminTokenStatus) DeepCopy() *GarageS3AdminTokenStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AdminTokenStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BlockErrorPolicy) DeepCopyInto(out *GarageS3BlockErrorPolicy) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BlockErrorPolicy.
+func (in *GarageS3BlockErrorPolicy) DeepCopy() *GarageS3BlockErrorPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BlockErrorPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BootstrapPolicy) DeepCopyInto(out *GarageS3BootstrapPolicy) {
+	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		x := (*in).DeepCopy()
+		*out = &x
+	}
+	if in.ExpectedNodes != nil {
+		in, out := &in.ExpectedNodes, &out.ExpectedNodes
+		*out = new(int)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BootstrapPolicy.
+func (in *GarageS3BootstrapPolicy) DeepCopy() *GarageS3BootstrapPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BootstrapPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3Bucket) DeepCopyInto(out *GarageS3Bucket) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3Bucket.
+func (in *GarageS3Bucket) DeepCopy() *GarageS3Bucket {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3Bucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3Bucket) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketDefaults) DeepCopyInto(out *GarageS3BucketDefaults) {
+	*out = *in
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(GarageS3BucketQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WebsiteAccess != nil {
+		in, out := &in.WebsiteAccess, &out.WebsiteAccess
+		*out = new(GarageS3WebsiteAccess)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.ReadOnlyAccessKey != nil {
+		in, out := &in.ReadOnlyAccessKey, &out.ReadOnlyAccessKey
+		*out = new(GarageS3AccessKeyRef)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketDefaults.
+func (in *GarageS3BucketDefaults) DeepCopy() *GarageS3BucketDefaults {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketDefaults)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketEffectiveConfig) DeepCopyInto(out *GarageS3BucketEffectiveConfig) {
+	*out = *in
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(GarageS3BucketQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WebsiteAccess != nil {
+		in, out := &in.WebsiteAccess, &out.WebsiteAccess
+		*out = new(GarageS3WebsiteAccess)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Aliases != nil {
+		in, out := &in.Aliases, &out.Aliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]GarageS3BucketPermission, len(*in))
+		copy(*out, *in)
+	}
+	if in.CORS != nil {
+		in, out := &in.CORS, &out.CORS
+		*out = make([]GarageS3CORSRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketEffectiveConfig.
+func (in *GarageS3BucketEffectiveConfig) DeepCopy() *GarageS3BucketEffectiveConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketEffectiveConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketLifecycle) DeepCopyInto(out *GarageS3BucketLifecycle) {
+	*out = *in
+	if in.Rules != nil {
+		in, out := &in.Rules, &out.Rules
+		*out = make([]GarageS3LifecycleRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketLifecycle.
+func (in *GarageS3BucketLifecycle) DeepCopy() *GarageS3BucketLifecycle {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketLifecycle)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketList) DeepCopyInto(out *GarageS3BucketList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3Bucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketList.
+func (in *GarageS3BucketList) DeepCopy() *GarageS3BucketList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3BucketList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketPermission) DeepCopyInto(out *GarageS3BucketPermission) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketPermission.
+func (in *GarageS3BucketPermission) DeepCopy() *GarageS3BucketPermission {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketPermission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketQuota) DeepCopyInto(out *GarageS3BucketQuota) {
+	*out = *in
+	if in.MaxObjects != nil {
+		in, out := &in.MaxObjects, &out.MaxObjects
+		*out = new(int64)
+		**out = **in
+	}
+	if in.MaxBytes != nil {
+		in, out := &in.MaxBytes, &out.MaxBytes
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketQuota.
+func (in *GarageS3BucketQuota) DeepCopy() *GarageS3BucketQuota {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketQuota)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketSpec) DeepCopyInto(out *GarageS3BucketSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.WebsiteAccess != nil {
+		in, out := &in.WebsiteAccess, &out.WebsiteAccess
+		*out = new(GarageS3WebsiteAccess)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(GarageS3BucketQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdditionalAliases != nil {
+		in, out := &in.AdditionalAliases, &out.AdditionalAliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]GarageS3BucketPermission, len(*in))
+		copy(*out, *in)
+	}
+	if in.CORS != nil {
+		in, out := &in.CORS, &out.CORS
+		*out = make([]GarageS3CORSRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(GarageS3BucketLifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketSpec.
+func (in *GarageS3BucketSpec) DeepCopy() *GarageS3BucketSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketStatus) DeepCopyInto(out *GarageS3BucketStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EffectiveConfig != nil {
+		in, out := &in.EffectiveConfig, &out.EffectiveConfig
+		*out = new(GarageS3BucketEffectiveConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(GarageS3BucketLifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WebsiteDomains != nil {
+		in, out := &in.WebsiteDomains, &out.WebsiteDomains
+		*out = make([]GarageS3WebsiteDomainStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketStatus.
+func (in *GarageS3BucketStatus) DeepCopy() *GarageS3BucketStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3CORSRule) DeepCopyInto(out *GarageS3CORSRule) {
+	*out = *in
+	if in.AllowedOrigins != nil {
+		in, out := &in.AllowedOrigins, &out.AllowedOrigins
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedMethods != nil {
+		in, out := &in.AllowedMethods, &out.AllowedMethods
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.AllowedHeaders != nil {
+		in, out := &in.AllowedHeaders, &out.AllowedHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.ExposeHeaders != nil {
+		in, out := &in.ExposeHeaders, &out.ExposeHeaders
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.MaxAgeSeconds != nil {
+		in, out := &in.MaxAgeSeconds, &out.MaxAgeSeconds
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3CORSRule.
+func (in *GarageS3CORSRule) DeepCopy() *GarageS3CORSRule {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3CORSRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3ClusterInstance) DeepCopyInto(out *GarageS3ClusterInstance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3ClusterInstance.
+func (in *GarageS3ClusterInstance) DeepCopy() *GarageS3ClusterInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3ClusterInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3ClusterInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3ClusterInstanceList) DeepCopyInto(out *GarageS3ClusterInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3ClusterInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3ClusterInstanceList.
+func (in *GarageS3ClusterInstanceList) DeepCopy() *GarageS3ClusterInstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3ClusterInstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3ClusterInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3ClusterInstanceSpec) DeepCopyInto(out *GarageS3ClusterInstanceSpec) {
+	*out = *in
+	in.GarageS3InstanceSpec.DeepCopyInto(&out.GarageS3InstanceSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3ClusterInstanceSpec.
+func (in *GarageS3ClusterInstanceSpec) DeepCopy() *GarageS3ClusterInstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3ClusterInstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3EndpointStatus) DeepCopyInto(out *GarageS3EndpointStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3EndpointStatus.
+func (in *GarageS3EndpointStatus) DeepCopy() *GarageS3EndpointStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3EndpointStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3GatewayRef) DeepCopyInto(out *GarageS3GatewayRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3GatewayRef.
+func (in *GarageS3GatewayRef) DeepCopy() *GarageS3GatewayRef {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3GatewayRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3Instance) DeepCopyInto(out *GarageS3Instance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3Instance.
+func (in *GarageS3Instance) DeepCopy() *GarageS3Instance {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3Instance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3Instance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3InstanceList) DeepCopyInto(out *GarageS3InstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3Instance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3InstanceList.
+func (in *GarageS3InstanceList) DeepCopy() *GarageS3InstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3InstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3InstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3InstanceRef) DeepCopyInto(out *GarageS3InstanceRef) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3InstanceRef.
+func (in *GarageS3InstanceRef) DeepCopy() *GarageS3InstanceRef {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3InstanceRef)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3InstanceSpec) DeepCopyInto(out *GarageS3InstanceSpec) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(GarageS3S3API)
+		**out = **in
+	}
+	if in.Web != nil {
+		in, out := &in.Web, &out.Web
+		*out = new(GarageS3WebService)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tenancy != nil {
+		in, out := &in.Tenancy, &out.Tenancy
+		*out = new(GarageS3TenancyPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BucketDefaults != nil {
+		in, out := &in.BucketDefaults, &out.BucketDefaults
+		*out = new(GarageS3BucketDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(GarageS3BootstrapPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PeerService != nil {
+		in, out := &in.PeerService, &out.PeerService
+		*out = new(GarageS3PeerService)
+		**out = **in
+	}
+	if in.BlockErrors != nil {
+		in, out := &in.BlockErrors, &out.BlockErrors
+		*out = new(GarageS3BlockErrorPolicy)
+		**out = **in
+	}
+	if in.WorkerVariables != nil {
+		in, out := &in.WorkerVariables, &out.WorkerVariables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeWorkerVariables != nil {
+		in, out := &in.NodeWorkerVariables, &out.NodeWorkerVariables
+		*out = make([]GarageS3NodeWorkerVariables, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3InstanceSpec.
+func (in *GarageS3InstanceSpec) DeepCopy() *GarageS3InstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3InstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3InstanceStatus) DeepCopyInto(out *GarageS3InstanceStatus) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]GarageS3EndpointStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]GarageS3PeerStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.PendingLayout != nil {
+		in, out := &in.PendingLayout, &out.PendingLayout
+		*out = new(GarageS3PendingLayout)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BlockErrors != nil {
+		in, out := &in.BlockErrors, &out.BlockErrors
+		*out = make([]GarageS3NodeBlockErrors, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.WorkerVariables != nil {
+		in, out := &in.WorkerVariables, &out.WorkerVariables
+		*out = make([]GarageS3NodeWorkerVariablesStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Workers != nil {
+		in, out := &in.Workers, &out.Workers
+		*out = make([]GarageS3NodeWorkers, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3InstanceStatus.
+func (in *GarageS3InstanceStatus) DeepCopy() *GarageS3InstanceStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3InstanceStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3LayoutRole) DeepCopyInto(out *GarageS3LayoutRole) {
+	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(int64)
+		**out = **in
+	}
+	if in.UsableCapacity != nil {
+		in, out := &in.UsableCapacity, &out.UsableCapacity
+		*out = new(int64)
+		**out = **in
+	}
+	if in.StoredPartitions != nil {
+		in, out := &in.StoredPartitions, &out.StoredPartitions
+		*out = new(int64)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3LayoutRole.
+func (in *GarageS3LayoutRole) DeepCopy() *GarageS3LayoutRole {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3LayoutRole)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3LifecycleRule) DeepCopyInto(out *GarageS3LifecycleRule) {
+	*out = *in
+	if in.ExpirationDays != nil {
+		in, out := &in.ExpirationDays, &out.ExpirationDays
+		*out = new(int32)
+		**out = **in
+	}
+	if in.AbortIncompleteMultipartUploadDays != nil {
+		in, out := &in.AbortIncompleteMultipartUploadDays, &out.AbortIncompleteMultipartUploadDays
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3LifecycleRule.
+func (in *GarageS3LifecycleRule) DeepCopy() *GarageS3LifecycleRule {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3LifecycleRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3MetadataSnapshotSchedule) DeepCopyInto(out *GarageS3MetadataSnapshotSchedule) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3MetadataSnapshotSchedule.
+func (in *GarageS3MetadataSnapshotSchedule) DeepCopy() *GarageS3MetadataSnapshotSchedule {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3MetadataSnapshotSchedule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3MetadataSnapshotSchedule) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3MetadataSnapshotScheduleList) DeepCopyInto(out *GarageS3MetadataSnapshotScheduleList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3MetadataSnapshotSchedule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3MetadataSnapshotScheduleList.
+func (in *GarageS3MetadataSnapshotScheduleList) DeepCopy() *GarageS3MetadataSnapshotScheduleList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3MetadataSnapshotScheduleList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3MetadataSnapshotScheduleList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3MetadataSnapshotScheduleSpec) DeepCopyInto(out *GarageS3MetadataSnapshotScheduleSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3MetadataSnapshotScheduleSpec.
+func (in *GarageS3MetadataSnapshotScheduleSpec) DeepCopy() *GarageS3MetadataSnapshotScheduleSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3MetadataSnapshotScheduleSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3MetadataSnapshotScheduleStatus) DeepCopyInto(out *GarageS3MetadataSnapshotScheduleStatus) {
+	*out = *in
+	if in.LastScheduleTime != nil {
+		in, out := &in.LastScheduleTime, &out.LastScheduleTime
+		*out = (*in).DeepCopy()
+	}
+	if in.LastSuccessfulTime != nil {
+		in, out := &in.LastSuccessfulTime, &out.LastSuccessfulTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]GarageS3NodeOperationStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3MetadataSnapshotScheduleStatus.
+func (in *GarageS3MetadataSnapshotScheduleStatus) DeepCopy() *GarageS3MetadataSnapshotScheduleStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3MetadataSnapshotScheduleStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3Node) DeepCopyInto(out *GarageS3Node) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	out.Spec = in.Spec
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3Node.
+func (in *GarageS3Node) DeepCopy() *GarageS3Node {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3Node)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3Node) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3NodeBlockErrors) DeepCopyInto(out *GarageS3NodeBlockErrors) {
+	*out = *in
+	if in.Unrecoverable != nil {
+		in, out := &in.Unrecoverable, &out.Unrecoverable
+		*out = make([]GarageS3UnrecoverableBlock, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3NodeBlockErrors.
+func (in *GarageS3NodeBlockErrors) DeepCopy() *GarageS3NodeBlockErrors {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3NodeBlockErrors)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3NodeList) DeepCopyInto(out *GarageS3NodeList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3Node, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3NodeList.
+func (in *GarageS3NodeList) DeepCopy() *GarageS3NodeList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3NodeList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3NodeList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3NodeOperationStatus) DeepCopyInto(out *GarageS3NodeOperationStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3NodeOperationStatus.
+func (in *GarageS3NodeOperationStatus) DeepCopy() *GarageS3NodeOperationStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3NodeOperationStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3NodeSpec) DeepCopyInto(out *GarageS3NodeSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3NodeSpec.
+func (in *GarageS3NodeSpec) DeepCopy() *GarageS3NodeSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3NodeSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3NodeStatus) DeepCopyInto(out *GarageS3NodeStatus) {
+	*out = *in
+	if in.Capacity != nil {
+		in, out := &in.Capacity, &out.Capacity
+		*out = new(int64)
+		**out = **in
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3NodeStatus.
+func (in *GarageS3NodeStatus) DeepCopy() *GarageS3NodeStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3NodeStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3NodeWorkerVariables) DeepCopyInto(out *GarageS3NodeWorkerVariables) {
+	*out = *in
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3NodeWorkerVariables.
+func (in *GarageS3NodeWorkerVariables) DeepCopy() *GarageS3NodeWorkerVariables {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3NodeWorkerVariables)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3NodeWorkerVariablesStatus) DeepCopyInto(out *GarageS3NodeWorkerVariablesStatus) {
+	*out = *in
+	if in.Variables != nil {
+		in, out := &in.Variables, &out.Variables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3NodeWorkerVariablesStatus.
+func (in *GarageS3NodeWorkerVariablesStatus) DeepCopy() *GarageS3NodeWorkerVariablesStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3NodeWorkerVariablesStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3NodeWorkers) DeepCopyInto(out *GarageS3NodeWorkers) {
+	*out = *in
+	if in.States != nil {
+		in, out := &in.States, &out.States
+		*out = make(map[string]int, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.Unhealthy != nil {
+		in, out := &in.Unhealthy, &out.Unhealthy
+		*out = make([]GarageS3WorkerStatus, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3NodeWorkers.
+func (in *GarageS3NodeWorkers) DeepCopy() *GarageS3NodeWorkers {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3NodeWorkers)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3PeerService) DeepCopyInto(out *GarageS3PeerService) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3PeerService.
+func (in *GarageS3PeerService) DeepCopy() *GarageS3PeerService {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3PeerService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3PeerStatus) DeepCopyInto(out *GarageS3PeerStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3PeerStatus.
+func (in *GarageS3PeerStatus) DeepCopy() *GarageS3PeerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3PeerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3PendingLayout) DeepCopyInto(out *GarageS3PendingLayout) {
+	*out = *in
+	if in.Message != nil {
+		in, out := &in.Message, &out.Message
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Roles != nil {
+		in, out := &in.Roles, &out.Roles
+		*out = make([]GarageS3LayoutRole, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3PendingLayout.
+func (in *GarageS3PendingLayout) DeepCopy() *GarageS3PendingLayout {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3PendingLayout)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3RepairJob) DeepCopyInto(out *GarageS3RepairJob) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3RepairJob.
+func (in *GarageS3RepairJob) DeepCopy() *GarageS3RepairJob {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3RepairJob)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3RepairJob) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3RepairJobList) DeepCopyInto(out *GarageS3RepairJobList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3RepairJob, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3RepairJobList.
+func (in *GarageS3RepairJobList) DeepCopy() *GarageS3RepairJobList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3RepairJobList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3RepairJobList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3RepairJobSpec) DeepCopyInto(out *GarageS3RepairJobSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3RepairJobSpec.
+func (in *GarageS3RepairJobSpec) DeepCopy() *GarageS3RepairJobSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3RepairJobSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3RepairJobStatus) DeepCopyInto(out *GarageS3RepairJobStatus) {
+	*out = *in
+	if in.StartTime != nil {
+		in, out := &in.StartTime, &out.StartTime
+		*out = (*in).DeepCopy()
+	}
+	if in.CompletionTime != nil {
+		in, out := &in.CompletionTime, &out.CompletionTime
+		*out = (*in).DeepCopy()
+	}
+	if in.Nodes != nil {
+		in, out := &in.Nodes, &out.Nodes
+		*out = make([]GarageS3NodeOperationStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Workers != nil {
+		in, out := &in.Workers, &out.Workers
+		*out = make([]GarageS3WorkerStatus, len(*in))
+		copy(*out, *in)
+	}
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3RepairJobStatus.
+func (in *GarageS3RepairJobStatus) DeepCopy() *GarageS3RepairJobStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3RepairJobStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3S3API) DeepCopyInto(out *GarageS3S3API) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3S3API.
+func (in *GarageS3S3API) DeepCopy() *GarageS3S3API {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3S3API)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3TenancyPolicy) DeepCopyInto(out *GarageS3TenancyPolicy) {
+	*out = *in
+	if in.NamespaceSelector != nil {
+		in, out := &in.NamespaceSelector, &out.NamespaceSelector
+		*out = new(metav1.LabelSelector)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxBucketsPerNamespace != nil {
+		in, out := &in.MaxBucketsPerNamespace, &out.MaxBucketsPerNamespace
+		*out = new(int)
+		**out = **in
+	}
+	if in.MaxAccessKeysPerNamespace != nil {
+		in, out := &in.MaxAccessKeysPerNamespace, &out.MaxAccessKeysPerNamespace
+		*out = new(int)
+		**out = **in
+	}
+	if in.DefaultBucketQuota != nil {
+		in, out := &in.DefaultBucketQuota, &out.DefaultBucketQuota
+		*out = new(GarageS3BucketQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.MaxBucketQuota != nil {
+		in, out := &in.MaxBucketQuota, &out.MaxBucketQuota
+		*out = new(GarageS3BucketQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WebsiteDomains != nil {
+		in, out := &in.WebsiteDomains, &out.WebsiteDomains
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3TenancyPolicy.
+func (in *GarageS3TenancyPolicy) DeepCopy() *GarageS3TenancyPolicy {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3TenancyPolicy)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3UnrecoverableBlock) DeepCopyInto(out *GarageS3UnrecoverableBlock) {
+	*out = *in
+	if in.Objects != nil {
+		in, out := &in.Objects, &out.Objects
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3UnrecoverableBlock.
+func (in *GarageS3UnrecoverableBlock) DeepCopy() *GarageS3UnrecoverableBlock {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3UnrecoverableBlock)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WebService) DeepCopyInto(out *GarageS3WebService) {
+	*out = *in
+	if in.RouteAnnotations != nil {
+		in, out := &in.RouteAnnotations, &out.RouteAnnotations
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WebService.
+func (in *GarageS3WebService) DeepCopy() *GarageS3WebService {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WebService)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WebsiteAccess) DeepCopyInto(out *GarageS3WebsiteAccess) {
+	*out = *in
+	if in.RedirectAllRequestsTo != nil {
+		in, out := &in.RedirectAllRequestsTo, &out.RedirectAllRequestsTo
+		*out = new(GarageS3WebsiteRedirectAll)
+		**out = **in
+	}
+	if in.RoutingRules != nil {
+		in, out := &in.RoutingRules, &out.RoutingRules
+		*out = make([]GarageS3WebsiteRoutingRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Route != nil {
+		in, out := &in.Route, &out.Route
+		*out = new(GarageS3WebsiteRoute)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WebsiteAccess.
+func (in *GarageS3WebsiteAccess) DeepCopy() *GarageS3WebsiteAccess {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WebsiteAccess)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WebsiteDomainStatus) DeepCopyInto(out *GarageS3WebsiteDomainStatus) {
+	*out = *in
+	in.LastCheckTime.DeepCopyInto(&out.LastCheckTime)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WebsiteDomainStatus.
+func (in *GarageS3WebsiteDomainStatus) DeepCopy() *GarageS3WebsiteDomainStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WebsiteDomainStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WebsiteRedirectAll) DeepCopyInto(out *GarageS3WebsiteRedirectAll) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WebsiteRedirectAll.
+func (in *GarageS3WebsiteRedirectAll) DeepCopy() *GarageS3WebsiteRedirectAll {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WebsiteRedirectAll)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WebsiteRoute) DeepCopyInto(out *GarageS3WebsiteRoute) {
+	*out = *in
+	if in.ParentRef != nil {
+		in, out := &in.ParentRef, &out.ParentRef
+		*out = new(GarageS3GatewayRef)
+		**out = **in
+	}
+	if in.Annotations != nil {
+		in, out := &in.Annotations, &out.Annotations
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WebsiteRoute.
+func (in *GarageS3WebsiteRoute) DeepCopy() *GarageS3WebsiteRoute {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WebsiteRoute)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WebsiteRoutingCondition) DeepCopyInto(out *GarageS3WebsiteRoutingCondition) {
+	*out = *in
+	if in.HttpErrorCodeReturnedEquals != nil {
+		in, out := &in.HttpErrorCodeReturnedEquals, &out.HttpErrorCodeReturnedEquals
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WebsiteRoutingCondition.
+func (in *GarageS3WebsiteRoutingCondition) DeepCopy() *GarageS3WebsiteRoutingCondition {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WebsiteRoutingCondition)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WebsiteRoutingRedirect) DeepCopyInto(out *GarageS3WebsiteRoutingRedirect) {
+	*out = *in
+	if in.HttpRedirectCode != nil {
+		in, out := &in.HttpRedirectCode, &out.HttpRedirectCode
+		*out = new(int32)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WebsiteRoutingRedirect.
+func (in *GarageS3WebsiteRoutingRedirect) DeepCopy() *GarageS3WebsiteRoutingRedirect {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WebsiteRoutingRedirect)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WebsiteRoutingRule) DeepCopyInto(out *GarageS3WebsiteRoutingRule) {
+	*out = *in
+	if in.Condition != nil {
+		in, out := &in.Condition, &out.Condition
+		*out = new(GarageS3WebsiteRoutingCondition)
+		(*in).DeepCopyInto(*out)
+	}
+	in.Redirect.DeepCopyInto(&out.Redirect)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WebsiteRoutingRule.
+func (in *GarageS3WebsiteRoutingRule) DeepCopy() *GarageS3WebsiteRoutingRule {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WebsiteRoutingRule)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3WorkerStatus) DeepCopyInto(out *GarageS3WorkerStatus) {
+	*out = *in
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3WorkerStatus.
+func (in *GarageS3WorkerStatus) DeepCopy() *GarageS3WorkerStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3WorkerStatus)
+	in.DeepCopyInto(out)
+	return out
+}
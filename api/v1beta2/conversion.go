@@ -0,0 +1,477 @@
+package v1beta2
+
+import (
+	"encoding/json"
+	"net"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// Annotations keeping the fields a version can't represent, so that converting an object
+// to the other version and back returns it unchanged.
+const (
+	// Set on v1beta2 objects, holding the v1 fields lost by the conversion
+	V1FieldsAnnotation = GroupName + "/v1-fields"
+	// Set on v1 objects, holding the v1beta2 fields lost by the conversion
+	V1beta2FieldsAnnotation = GroupName + "/v1beta2-fields"
+)
+
+// Port of the admin API endpoints which don't give one
+const defaultAdminAPIPort = 3903
+
+// convertFields converts the fields of src which differ between versions. The fields saved in
+// the savedAnnotation of meta by the opposite conversion are restored when they still convert
+// to src, and src is saved in the saveAnnotation of meta when converting back doesn't return it.
+// meta is the metadata of the converted object, copied from the source object.
+func convertFields[S, D any](src S, meta *metav1.ObjectMeta, savedAnnotation, saveAnnotation string, convert func(S) D, revert func(D) S) (D, error) {
+	converted := convert(src)
+	if data, found := meta.Annotations[savedAnnotation]; found {
+		var saved D
+		if err := json.Unmarshal([]byte(data), &saved); err == nil && equality.Semantic.DeepEqual(revert(saved), src) {
+			converted = saved
+		}
+		delete(meta.Annotations, savedAnnotation)
+	}
+	if !equality.Semantic.DeepEqual(revert(converted), src) {
+		data, err := json.Marshal(src)
+		if err != nil {
+			return converted, err
+		}
+		if meta.Annotations == nil {
+			meta.Annotations = map[string]string{}
+		}
+		meta.Annotations[saveAnnotation] = string(data)
+	}
+	if len(meta.Annotations) == 0 {
+		meta.Annotations = nil
+	}
+	return converted, nil
+}
+
+// toV1Fields converts v1beta2 fields to v1, meta being the metadata of the v1 object.
+func toV1Fields[S, D any](src S, meta *metav1.ObjectMeta, convert func(S) D, revert func(D) S) (D, error) {
+	return convertFields(src, meta, V1FieldsAnnotation, V1beta2FieldsAnnotation, convert, revert)
+}
+
+// fromV1Fields converts v1 fields to v1beta2, meta being the metadata of the v1beta2 object.
+func fromV1Fields[S, D any](src S, meta *metav1.ObjectMeta, convert func(S) D, revert func(D) S) (D, error) {
+	return convertFields(src, meta, V1beta2FieldsAnnotation, V1FieldsAnnotation, convert, revert)
+}
+
+/* ********************************************
+   GarageS3Instance and GarageS3ClusterInstance
+   ********************************************/
+
+// v1Endpoints are the v1 fields replaced by the endpoint URLs.
+type v1Endpoints struct {
+	Url       string   `json:"url,omitempty"`
+	Port      int      `json:"port,omitempty"`
+	Endpoints []string `json:"endpoints,omitempty"`
+}
+
+// endpointsFromV1 returns the URLs of the endpoints, or of the url when there is none,
+// the port being used by the hosts which don't give one.
+func endpointsFromV1(src v1Endpoints) []string {
+	hosts := src.Endpoints
+	if len(hosts) == 0 {
+		hosts = []string{src.Url}
+	}
+	urls := make([]string, 0, len(hosts))
+	for _, host := range hosts {
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(host, strconv.Itoa(src.Port))
+		}
+		urls = append(urls, "http://"+host)
+	}
+	return urls
+}
+
+// endpointsToV1 returns the url and port of the first endpoint, and the endpoints as host:port
+// when there are several.
+func endpointsToV1(urls []string) v1Endpoints {
+	hosts := make([]string, 0, len(urls))
+	for _, url := range urls {
+		host := strings.TrimSuffix(strings.TrimPrefix(url, "http://"), "/")
+		if _, _, err := net.SplitHostPort(host); err != nil {
+			host = net.JoinHostPort(strings.Trim(host, "[]"), strconv.Itoa(defaultAdminAPIPort))
+		}
+		hosts = append(hosts, host)
+	}
+	dst := v1Endpoints{}
+	if len(hosts) == 0 {
+		return dst
+	}
+	host, port, _ := net.SplitHostPort(hosts[0])
+	dst.Url = host
+	dst.Port, _ = strconv.Atoi(port)
+	if len(hosts) > 1 {
+		dst.Endpoints = hosts
+	}
+	return dst
+}
+
+func convertInstanceSpecTo(src *GarageS3InstanceSpec, dst *v1.GarageS3InstanceSpec, meta *metav1.ObjectMeta) error {
+	endpoints, err := toV1Fields(src.Endpoints, meta, endpointsToV1, endpointsFromV1)
+	if err != nil {
+		return err
+	}
+	in := src.DeepCopy()
+	dst.Url = endpoints.Url
+	dst.Port = endpoints.Port
+	dst.Endpoints = endpoints.Endpoints
+	dst.AdminTokenSecret = in.AdminTokenSecret
+	dst.S3 = in.S3
+	dst.Web = in.Web
+	dst.Tenancy = in.Tenancy
+	dst.BucketDefaults = in.BucketDefaults
+	dst.Bootstrap = in.Bootstrap
+	dst.Peers = in.Peers
+	dst.PeerService = in.PeerService
+	dst.RequireLayoutApproval = in.RequireLayoutApproval
+	dst.BlockErrors = in.BlockErrors
+	dst.WorkerVariables = in.WorkerVariables
+	dst.NodeWorkerVariables = in.NodeWorkerVariables
+	return nil
+}
+
+func convertInstanceSpecFrom(src *v1.GarageS3InstanceSpec, dst *GarageS3InstanceSpec, meta *metav1.ObjectMeta) error {
+	endpoints, err := fromV1Fields(v1Endpoints{Url: src.Url, Port: src.Port, Endpoints: src.Endpoints}, meta, endpointsFromV1, endpointsToV1)
+	if err != nil {
+		return err
+	}
+	in := src.DeepCopy()
+	dst.Endpoints = endpoints
+	dst.AdminTokenSecret = in.AdminTokenSecret
+	dst.S3 = in.S3
+	dst.Web = in.Web
+	dst.Tenancy = in.Tenancy
+	dst.BucketDefaults = in.BucketDefaults
+	dst.Bootstrap = in.Bootstrap
+	dst.Peers = in.Peers
+	dst.PeerService = in.PeerService
+	dst.RequireLayoutApproval = in.RequireLayoutApproval
+	dst.BlockErrors = in.BlockErrors
+	dst.WorkerVariables = in.WorkerVariables
+	dst.NodeWorkerVariables = in.NodeWorkerVariables
+	return nil
+}
+
+// ConvertTo converts the GarageS3Instance to v1.
+func (in *GarageS3Instance) ConvertTo(hub conversion.Hub) error {
+	dst := hub.(*v1.GarageS3Instance)
+	in.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	if err := convertInstanceSpecTo(&in.Spec, &dst.Spec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	in.Status.DeepCopyInto(&dst.Status)
+	return nil
+}
+
+// ConvertFrom converts the GarageS3Instance from v1.
+func (in *GarageS3Instance) ConvertFrom(hub conversion.Hub) error {
+	src := hub.(*v1.GarageS3Instance)
+	src.ObjectMeta.DeepCopyInto(&in.ObjectMeta)
+	if err := convertInstanceSpecFrom(&src.Spec, &in.Spec, &in.ObjectMeta); err != nil {
+		return err
+	}
+	src.Status.DeepCopyInto(&in.Status)
+	return nil
+}
+
+// ConvertTo converts the GarageS3ClusterInstance to v1.
+func (in *GarageS3ClusterInstance) ConvertTo(hub conversion.Hub) error {
+	dst := hub.(*v1.GarageS3ClusterInstance)
+	in.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	if err := convertInstanceSpecTo(&in.Spec.GarageS3InstanceSpec, &dst.Spec.GarageS3InstanceSpec, &dst.ObjectMeta); err != nil {
+		return err
+	}
+	dst.Spec.AdminTokenSecretNamespace = in.Spec.AdminTokenSecretNamespace
+	in.Status.DeepCopyInto(&dst.Status)
+	return nil
+}
+
+// ConvertFrom converts the GarageS3ClusterInstance from v1.
+func (in *GarageS3ClusterInstance) ConvertFrom(hub conversion.Hub) error {
+	src := hub.(*v1.GarageS3ClusterInstance)
+	src.ObjectMeta.DeepCopyInto(&in.ObjectMeta)
+	if err := convertInstanceSpecFrom(&src.Spec.GarageS3InstanceSpec, &in.Spec.GarageS3InstanceSpec, &in.ObjectMeta); err != nil {
+		return err
+	}
+	in.Spec.AdminTokenSecretNamespace = src.Spec.AdminTokenSecretNamespace
+	src.Status.DeepCopyInto(&in.Status)
+	return nil
+}
+
+/* ****************************************
+   GarageS3AccessKey and GarageS3AdminToken
+   ****************************************/
+
+// expirationFromV1 parses an RFC3339 expiration, unset when it is empty or invalid.
+func expirationFromV1(src string) *metav1.Time {
+	parsed, err := time.Parse(time.RFC3339, src)
+	if err != nil {
+		return nil
+	}
+	expiration := metav1.NewTime(parsed)
+	return &expiration
+}
+
+// expirationToV1 formats an expiration in RFC3339, empty when it is unset.
+func expirationToV1(src *metav1.Time) string {
+	if src == nil {
+		return ""
+	}
+	return src.UTC().Format(time.RFC3339)
+}
+
+// v1KeyExpiration are the v1 fields replaced by the expiration time of an access key.
+type v1KeyExpiration struct {
+	Expiration   string `json:"expiration,omitempty"`
+	NeverExpires bool   `json:"neverExpires,omitempty"`
+}
+
+// keyExpirationFromV1 returns the expiration time of the key, unset when it never expires.
+func keyExpirationFromV1(src v1KeyExpiration) *metav1.Time {
+	if src.NeverExpires {
+		return nil
+	}
+	return expirationFromV1(src.Expiration)
+}
+
+// keyExpirationToV1 returns the expiration of the key, which never expires when it isn't set.
+func keyExpirationToV1(src *metav1.Time) v1KeyExpiration {
+	if src == nil {
+		return v1KeyExpiration{NeverExpires: true}
+	}
+	return v1KeyExpiration{Expiration: expirationToV1(src)}
+}
+
+// ConvertTo converts the GarageS3AccessKey to v1.
+func (in *GarageS3AccessKey) ConvertTo(hub conversion.Hub) error {
+	dst := hub.(*v1.GarageS3AccessKey)
+	in.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	expiration, err := toV1Fields(in.Spec.Expiration, &dst.ObjectMeta, keyExpirationToV1, keyExpirationFromV1)
+	if err != nil {
+		return err
+	}
+	dst.Spec = v1.GarageS3AccessKeySpec{
+		InstanceRef:     in.Spec.InstanceRef,
+		CanCreateBucket: in.Spec.CanCreateBucket,
+		Expiration:      expiration.Expiration,
+		NeverExpires:    expiration.NeverExpires,
+	}
+	in.Status.DeepCopyInto(&dst.Status)
+	return nil
+}
+
+// ConvertFrom converts the GarageS3AccessKey from v1.
+func (in *GarageS3AccessKey) ConvertFrom(hub conversion.Hub) error {
+	src := hub.(*v1.GarageS3AccessKey)
+	src.ObjectMeta.DeepCopyInto(&in.ObjectMeta)
+	expiration, err := fromV1Fields(v1KeyExpiration{Expiration: src.Spec.Expiration, NeverExpires: src.Spec.NeverExpires}, &in.ObjectMeta, keyExpirationFromV1, keyExpirationToV1)
+	if err != nil {
+		return err
+	}
+	in.Spec = GarageS3AccessKeySpec{
+		InstanceRef:     src.Spec.InstanceRef,
+		CanCreateBucket: src.Spec.CanCreateBucket,
+		Expiration:      expiration,
+	}
+	src.Status.DeepCopyInto(&in.Status)
+	return nil
+}
+
+// ConvertTo converts the GarageS3AdminToken to v1.
+func (in *GarageS3AdminToken) ConvertTo(hub conversion.Hub) error {
+	dst := hub.(*v1.GarageS3AdminToken)
+	in.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	expiration, err := toV1Fields(in.Spec.Expiration, &dst.ObjectMeta, expirationToV1, expirationFromV1)
+	if err != nil {
+		return err
+	}
+	spec := in.Spec.DeepCopy()
+	dst.Spec = v1.GarageS3AdminTokenSpec{
+		InstanceRef: spec.InstanceRef,
+		Scope:       spec.Scope,
+		Expiration:  expiration,
+		Validity:    spec.Validity,
+		SecretName:  spec.SecretName,
+	}
+	in.Status.DeepCopyInto(&dst.Status)
+	return nil
+}
+
+// ConvertFrom converts the GarageS3AdminToken from v1.
+func (in *GarageS3AdminToken) ConvertFrom(hub conversion.Hub) error {
+	src := hub.(*v1.GarageS3AdminToken)
+	src.ObjectMeta.DeepCopyInto(&in.ObjectMeta)
+	expiration, err := fromV1Fields(src.Spec.Expiration, &in.ObjectMeta, expirationFromV1, expirationToV1)
+	if err != nil {
+		return err
+	}
+	spec := src.Spec.DeepCopy()
+	in.Spec = GarageS3AdminTokenSpec{
+		InstanceRef: spec.InstanceRef,
+		Scope:       spec.Scope,
+		Expiration:  expiration,
+		Validity:    spec.Validity,
+		SecretName:  spec.SecretName,
+	}
+	src.Status.DeepCopyInto(&in.Status)
+	return nil
+}
+
+/* **************
+   GarageS3Bucket
+   **************/
+
+// bucketPermissions are the permissions of a bucket and of its effective configuration.
+type bucketPermissions struct {
+	Spec   []GarageS3BucketPermission `json:"spec,omitempty"`
+	Status []GarageS3BucketPermission `json:"status,omitempty"`
+}
+
+// v1BucketPermissions are the v1 permissions of a bucket and of its effective configuration.
+type v1BucketPermissions struct {
+	Spec   []v1.GarageS3BucketPermission `json:"spec,omitempty"`
+	Status []v1.GarageS3BucketPermission `json:"status,omitempty"`
+}
+
+// permissionsFromV1 lists the permissions granted by the read, write and owner flags, in this order.
+func permissionsFromV1(src []v1.GarageS3BucketPermission) []GarageS3BucketPermission {
+	if src == nil {
+		return nil
+	}
+	dst := make([]GarageS3BucketPermission, 0, len(src))
+	for _, permission := range src {
+		converted := GarageS3BucketPermission{
+			AccessKeyName:      permission.AccessKeyName,
+			AccessKeyNamespace: permission.AccessKeyNamespace,
+		}
+		if permission.Read {
+			converted.Allow = append(converted.Allow, PermissionRead)
+		}
+		if permission.Write {
+			converted.Allow = append(converted.Allow, PermissionWrite)
+		}
+		if permission.Owner {
+			converted.Allow = append(converted.Allow, PermissionOwner)
+		}
+		dst = append(dst, converted)
+	}
+	return dst
+}
+
+// permissionsToV1 sets the read, write and owner flags of the granted permissions.
+func permissionsToV1(src []GarageS3BucketPermission) []v1.GarageS3BucketPermission {
+	if src == nil {
+		return nil
+	}
+	dst := make([]v1.GarageS3BucketPermission, 0, len(src))
+	for _, permission := range src {
+		dst = append(dst, v1.GarageS3BucketPermission{
+			AccessKeyName:      permission.AccessKeyName,
+			AccessKeyNamespace: permission.AccessKeyNamespace,
+			Read:               slices.Contains(permission.Allow, PermissionRead),
+			Write:              slices.Contains(permission.Allow, PermissionWrite),
+			Owner:              slices.Contains(permission.Allow, PermissionOwner),
+		})
+	}
+	return dst
+}
+
+func bucketPermissionsFromV1(src v1BucketPermissions) bucketPermissions {
+	return bucketPermissions{Spec: permissionsFromV1(src.Spec), Status: permissionsFromV1(src.Status)}
+}
+
+func bucketPermissionsToV1(src bucketPermissions) v1BucketPermissions {
+	return v1BucketPermissions{Spec: permissionsToV1(src.Spec), Status: permissionsToV1(src.Status)}
+}
+
+// ConvertTo converts the GarageS3Bucket to v1.
+func (in *GarageS3Bucket) ConvertTo(hub conversion.Hub) error {
+	dst := hub.(*v1.GarageS3Bucket)
+	in.ObjectMeta.DeepCopyInto(&dst.ObjectMeta)
+	src := in.DeepCopy()
+	permissions := bucketPermissions{Spec: src.Spec.Permissions}
+	if src.Status.EffectiveConfig != nil {
+		permissions.Status = src.Status.EffectiveConfig.Permissions
+	}
+	converted, err := toV1Fields(permissions, &dst.ObjectMeta, bucketPermissionsToV1, bucketPermissionsFromV1)
+	if err != nil {
+		return err
+	}
+	dst.Spec = v1.GarageS3BucketSpec{
+		InstanceRef:       src.Spec.InstanceRef,
+		WebsiteAccess:     src.Spec.WebsiteAccess,
+		Quota:             src.Spec.Quota,
+		AdditionalAliases: src.Spec.AdditionalAliases,
+		Permissions:       converted.Spec,
+		CORS:              src.Spec.CORS,
+		Lifecycle:         src.Spec.Lifecycle,
+	}
+	dst.Status = v1.GarageS3BucketStatus{
+		Conditions:     src.Status.Conditions,
+		BucketID:       src.Status.BucketID,
+		Lifecycle:      src.Status.Lifecycle,
+		WebsiteDomains: src.Status.WebsiteDomains,
+	}
+	if config := src.Status.EffectiveConfig; config != nil {
+		dst.Status.EffectiveConfig = &v1.GarageS3BucketEffectiveConfig{
+			Quota:         config.Quota,
+			WebsiteAccess: config.WebsiteAccess,
+			Aliases:       config.Aliases,
+			Permissions:   converted.Status,
+			CORS:          config.CORS,
+			Sources:       config.Sources,
+		}
+	}
+	return nil
+}
+
+// ConvertFrom converts the GarageS3Bucket from v1.
+func (in *GarageS3Bucket) ConvertFrom(hub conversion.Hub) error {
+	src := hub.(*v1.GarageS3Bucket).DeepCopy()
+	src.ObjectMeta.DeepCopyInto(&in.ObjectMeta)
+	permissions := v1BucketPermissions{Spec: src.Spec.Permissions}
+	if src.Status.EffectiveConfig != nil {
+		permissions.Status = src.Status.EffectiveConfig.Permissions
+	}
+	converted, err := fromV1Fields(permissions, &in.ObjectMeta, bucketPermissionsFromV1, bucketPermissionsToV1)
+	if err != nil {
+		return err
+	}
+	in.Spec = GarageS3BucketSpec{
+		InstanceRef:       src.Spec.InstanceRef,
+		WebsiteAccess:     src.Spec.WebsiteAccess,
+		Quota:             src.Spec.Quota,
+		AdditionalAliases: src.Spec.AdditionalAliases,
+		Permissions:       converted.Spec,
+		CORS:              src.Spec.CORS,
+		Lifecycle:         src.Spec.Lifecycle,
+	}
+	in.Status = GarageS3BucketStatus{
+		Conditions:     src.Status.Conditions,
+		BucketID:       src.Status.BucketID,
+		Lifecycle:      src.Status.Lifecycle,
+		WebsiteDomains: src.Status.WebsiteDomains,
+	}
+	if config := src.Status.EffectiveConfig; config != nil {
+		in.Status.EffectiveConfig = &GarageS3BucketEffectiveConfig{
+			Quota:         config.Quota,
+			WebsiteAccess: config.WebsiteAccess,
+			Aliases:       config.Aliases,
+			Permissions:   converted.Status,
+			CORS:          config.CORS,
+			Sources:       config.Sources,
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package v1beta2
+
+import (
+	"math/rand"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/apitesting/fuzzer"
+	"k8s.io/apimachinery/pkg/api/equality"
+	metafuzzer "k8s.io/apimachinery/pkg/apis/meta/fuzzer"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/serializer"
+	"k8s.io/apimachinery/pkg/util/diff"
+	"sigs.k8s.io/controller-runtime/pkg/conversion"
+)
+
+// Objects converted by each round-trip test
+const fuzzIterations = 1000
+
+// convertible is a v1beta2 object with its v1 hub.
+type convertible struct {
+	spoke func() conversion.Convertible
+	hub   func() conversion.Hub
+}
+
+var convertibles = map[string]convertible{
+	"GarageS3Instance": {
+		spoke: func() conversion.Convertible { return &GarageS3Instance{} },
+		hub:   func() conversion.Hub { return &v1.GarageS3Instance{} },
+	},
+	"GarageS3ClusterInstance": {
+		spoke: func() conversion.Convertible { return &GarageS3ClusterInstance{} },
+		hub:   func() conversion.Hub { return &v1.GarageS3ClusterInstance{} },
+	},
+	"GarageS3AccessKey": {
+		spoke: func() conversion.Convertible { return &GarageS3AccessKey{} },
+		hub:   func() conversion.Hub { return &v1.GarageS3AccessKey{} },
+	},
+	"GarageS3Bucket": {
+		spoke: func() conversion.Convertible { return &GarageS3Bucket{} },
+		hub:   func() conversion.Hub { return &v1.GarageS3Bucket{} },
+	},
+	"GarageS3AdminToken": {
+		spoke: func() conversion.Convertible { return &GarageS3AdminToken{} },
+		hub:   func() conversion.Hub { return &v1.GarageS3AdminToken{} },
+	},
+}
+
+// newFuzzer returns a filler of valid API objects, with times and quantities which survive serialization.
+func newFuzzer(t *testing.T) interface{ Fill(any) } {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := v1.AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	if err := AddToScheme(scheme); err != nil {
+		t.Fatalf("failed to build scheme: %v", err)
+	}
+	seed := time.Now().UnixNano()
+	t.Logf("fuzzer seed %d", seed)
+	return fuzzer.FuzzerFor(metafuzzer.Funcs, rand.NewSource(seed), serializer.NewCodecFactory(scheme))
+}
+
+func TestConversion_HubRoundTrip(t *testing.T) {
+	for kind, c := range convertibles {
+		t.Run(kind, func(t *testing.T) {
+			f := newFuzzer(t)
+			for range fuzzIterations {
+				hub := c.hub()
+				f.Fill(hub)
+				spoke := c.spoke()
+				if err := spoke.ConvertFrom(hub); err != nil {
+					t.Fatalf("failed to convert from v1: %v", err)
+				}
+				got := c.hub()
+				if err := spoke.ConvertTo(got); err != nil {
+					t.Fatalf("failed to convert to v1: %v", err)
+				}
+				if !equality.Semantic.DeepEqual(hub, got) {
+					t.Fatalf("v1 object changed by the round trip:\n%s", diff.Diff(hub, got))
+				}
+			}
+		})
+	}
+}
+
+func TestConversion_SpokeRoundTrip(t *testing.T) {
+	for kind, c := range convertibles {
+		t.Run(kind, func(t *testing.T) {
+			f := newFuzzer(t)
+			for range fuzzIterations {
+				spoke := c.spoke()
+				f.Fill(spoke)
+				hub := c.hub()
+				if err := spoke.ConvertTo(hub); err != nil {
+					t.Fatalf("failed to convert to v1: %v", err)
+				}
+				got := c.spoke()
+				if err := got.ConvertFrom(hub); err != nil {
+					t.Fatalf("failed to convert from v1: %v", err)
+				}
+				if !equality.Semantic.DeepEqual(spoke, got) {
+					t.Fatalf("v1beta2 object changed by the round trip:\n%s", diff.Diff(spoke, got))
+				}
+			}
+		})
+	}
+}
+
+func TestConversion_Fields(t *testing.T) {
+	expiration := metav1.Date(2030, time.January, 2, 3, 4, 5, 0, time.UTC)
+
+	t.Run("instance endpoints", func(t *testing.T) {
+		spoke := &GarageS3Instance{Spec: GarageS3InstanceSpec{Endpoints: []string{"http://garage-0", "http://garage-1:3913/"}}}
+		hub := &v1.GarageS3Instance{}
+		if err := spoke.ConvertTo(hub); err != nil {
+			t.Fatalf("failed to convert to v1: %v", err)
+		}
+		if hub.Spec.Url != "garage-0" || hub.Spec.Port != 3903 {
+			t.Errorf("expected url garage-0 and port 3903, got %s and %d", hub.Spec.Url, hub.Spec.Port)
+		}
+		if want := []string{"garage-0:3903", "garage-1:3913"}; !equality.Semantic.DeepEqual(hub.Spec.Endpoints, want) {
+			t.Errorf("expected endpoints %v, got %v", want, hub.Spec.Endpoints)
+		}
+
+		hub = &v1.GarageS3Instance{Spec: v1.GarageS3InstanceSpec{Url: "garage", Port: 3903}}
+		if err := spoke.ConvertFrom(hub); err != nil {
+			t.Fatalf("failed to convert from v1: %v", err)
+		}
+		if want := []string{"http://garage:3903"}; !equality.Semantic.DeepEqual(spoke.Spec.Endpoints, want) {
+			t.Errorf("expected endpoints %v, got %v", want, spoke.Spec.Endpoints)
+		}
+		if _, found := spoke.Annotations[V1FieldsAnnotation]; found {
+			t.Error("expected no v1 fields annotation on a lossless conversion")
+		}
+	})
+
+	t.Run("bucket permissions", func(t *testing.T) {
+		hub := &v1.GarageS3Bucket{Spec: v1.GarageS3BucketSpec{Permissions: []v1.GarageS3BucketPermission{
+			{AccessKeyName: "reader", Read: true},
+			{AccessKeyName: "admin", Read: true, Write: true, Owner: true},
+		}}}
+		spoke := &GarageS3Bucket{}
+		if err := spoke.ConvertFrom(hub); err != nil {
+			t.Fatalf("failed to convert from v1: %v", err)
+		}
+		want := []GarageS3BucketPermission{
+			{AccessKeyName: "reader", Allow: []GarageS3Permission{PermissionRead}},
+			{AccessKeyName: "admin", Allow: []GarageS3Permission{PermissionRead, PermissionWrite, PermissionOwner}},
+		}
+		if !equality.Semantic.DeepEqual(spoke.Spec.Permissions, want) {
+			t.Errorf("expected permissions %+v, got %+v", want, spoke.Spec.Permissions)
+		}
+	})
+
+	t.Run("access key expiration", func(t *testing.T) {
+		tests := []struct {
+			name     string
+			hub      v1.GarageS3AccessKeySpec
+			expected *metav1.Time
+		}{
+			{name: "never expires", hub: v1.GarageS3AccessKeySpec{NeverExpires: true}},
+			{name: "expires", hub: v1.GarageS3AccessKeySpec{Expiration: "2030-01-02T03:04:05Z"}, expected: &expiration},
+			{name: "never expires wins", hub: v1.GarageS3AccessKeySpec{Expiration: "2030-01-02T03:04:05Z", NeverExpires: true}},
+		}
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				spoke := &GarageS3AccessKey{}
+				if err := spoke.ConvertFrom(&v1.GarageS3AccessKey{Spec: tt.hub}); err != nil {
+					t.Fatalf("failed to convert from v1: %v", err)
+				}
+				if !equality.Semantic.DeepEqual(spoke.Spec.Expiration, tt.expected) {
+					t.Errorf("expected expiration %v, got %v", tt.expected, spoke.Spec.Expiration)
+				}
+			})
+		}
+	})
+
+	t.Run("edited v1beta2 object", func(t *testing.T) {
+		// The saved v1 fields no longer apply once the v1beta2 fields changed
+		hub := &v1.GarageS3AdminToken{Spec: v1.GarageS3AdminTokenSpec{Expiration: "next week"}}
+		spoke := &GarageS3AdminToken{}
+		if err := spoke.ConvertFrom(hub); err != nil {
+			t.Fatalf("failed to convert from v1: %v", err)
+		}
+		if _, found := spoke.Annotations[V1FieldsAnnotation]; !found {
+			t.Fatal("expected the invalid v1 expiration to be saved")
+		}
+		spoke.Spec.Expiration = &expiration
+		if err := spoke.ConvertTo(hub); err != nil {
+			t.Fatalf("failed to convert to v1: %v", err)
+		}
+		if hub.Spec.Expiration != "2030-01-02T03:04:05Z" {
+			t.Errorf("expected the edited expiration, got %q", hub.Spec.Expiration)
+		}
+		if len(hub.Annotations) != 0 {
+			t.Errorf("expected no annotation, got %v", hub.Annotations)
+		}
+	})
+}
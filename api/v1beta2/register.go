@@ -0,0 +1,41 @@
+// Package v1beta2 contains the v1beta2 API types of the operator, served next to v1 and
+// converted to and from it by the conversion webhook. The nested types which didn't change
+// are shared with v1.
+// +kubebuilder:object:generate=true
+// +groupName=garage-s3-operator.abucquet.com
+package v1beta2
+
+import (
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const GroupName = v1.GroupName
+const GroupVersion = "v1beta2"
+
+var SchemeGroupVersion = schema.GroupVersion{Group: GroupName, Version: GroupVersion}
+
+var (
+	SchemeBuilder = runtime.NewSchemeBuilder(addKnownTypes)
+	AddToScheme   = SchemeBuilder.AddToScheme
+)
+
+func addKnownTypes(scheme *runtime.Scheme) error {
+	scheme.AddKnownTypes(SchemeGroupVersion,
+		&GarageS3Instance{},
+		&GarageS3InstanceList{},
+		&GarageS3ClusterInstance{},
+		&GarageS3ClusterInstanceList{},
+		&GarageS3AccessKey{},
+		&GarageS3AccessKeyList{},
+		&GarageS3Bucket{},
+		&GarageS3BucketList{},
+		&GarageS3AdminToken{},
+		&GarageS3AdminTokenList{},
+	)
+
+	metav1.AddToGroupVersion(scheme, SchemeGroupVersion)
+	return nil
+}
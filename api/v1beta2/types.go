@@ -0,0 +1,321 @@
+package v1beta2
+
+import (
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+/* *************************************
+   GarageS3Instance API Schema and types
+   *************************************/
+
+// GarageS3Instance is the Schema for a Garage S3 instance.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3i
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.activeEndpoint`
+// +kubebuilder:printcolumn:name="Layout",type=integer,JSONPath=`.status.layoutVersion`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3Instance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3InstanceSpec      `json:"spec"`
+	Status v1.GarageS3InstanceStatus `json:"status,omitempty"`
+}
+
+// GarageS3InstanceList contains a list of GarageS3Instance
+// +kubebuilder:object:root=true
+type GarageS3InstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3Instance `json:"items"`
+}
+
+// GarageS3InstanceSpec describes how to reach a Garage cluster and how the operator manages it.
+type GarageS3InstanceSpec struct {
+	// Admin API endpoints as http://<host>[:<port>] URLs, the port defaulting to 3903.
+	// The operator fails over to the next one when an endpoint is down.
+	// +kubebuilder:default={"http://127.0.0.1:3903"}
+	// +kubebuilder:validation:MinItems=1
+	// +kubebuilder:validation:items:Pattern=`^http://(\[[0-9a-fA-F:.]+\]|[^/?#@:\[\]]+)(:[0-9]{1,5})?/?$`
+	Endpoints []string `json:"endpoints,omitempty"`
+
+	// Secret holding the Garage admin API token in its token field
+	AdminTokenSecret string `json:"adminTokenSecret"`
+
+	// Optional S3 API of the instance, used to apply the bucket settings only exposed there (e.g. CORS)
+	S3 *v1.GarageS3S3API `json:"s3,omitempty"`
+
+	// Optional Service of the web endpoint, targeted by the Ingresses and HTTPRoutes of website buckets
+	Web *v1.GarageS3WebService `json:"web,omitempty"`
+
+	// Optional policy restricting which namespaces may use this instance and how much
+	Tenancy *v1.GarageS3TenancyPolicy `json:"tenancy,omitempty"`
+
+	// Optional settings inherited by the buckets of this instance
+	BucketDefaults *v1.GarageS3BucketDefaults `json:"bucketDefaults,omitempty"`
+
+	// Optional policy used to assign the first layout of a fresh cluster
+	Bootstrap *v1.GarageS3BootstrapPolicy `json:"bootstrap,omitempty"`
+
+	// Optional static peers the cluster nodes are connected to, as <node id>@<address>:<rpc port>
+	Peers []string `json:"peers,omitempty"`
+
+	// Optional headless Service whose endpoints are the Garage pods to connect
+	PeerService *v1.GarageS3PeerService `json:"peerService,omitempty"`
+
+	// Whether staged layout changes wait for the approve-layout annotation, instead of being
+	// applied by the instance reconciler
+	RequireLayoutApproval bool `json:"requireLayoutApproval,omitempty"`
+
+	// Optional policy handling the blocks which failed to resync
+	BlockErrors *v1.GarageS3BlockErrorPolicy `json:"blockErrors,omitempty"`
+
+	// Optional background worker variables set on every node (e.g. resync-tranquility)
+	WorkerVariables map[string]string `json:"workerVariables,omitempty"`
+
+	// Optional worker variables of specific nodes, overriding workerVariables
+	NodeWorkerVariables []v1.GarageS3NodeWorkerVariables `json:"nodeWorkerVariables,omitempty"`
+}
+
+/* ********************************************
+   GarageS3ClusterInstance API Schema and types
+   ********************************************/
+
+// GarageS3ClusterInstance is the Schema for a cluster-scoped Garage S3 instance,
+// usable by buckets and access keys of any namespace.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:scope=Cluster,shortName=gs3ci
+// +kubebuilder:printcolumn:name="Endpoint",type=string,JSONPath=`.status.activeEndpoint`
+// +kubebuilder:printcolumn:name="Layout",type=integer,JSONPath=`.status.layoutVersion`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3ClusterInstance struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3ClusterInstanceSpec `json:"spec"`
+	Status v1.GarageS3InstanceStatus   `json:"status,omitempty"`
+}
+
+// GarageS3ClusterInstanceList contains a list of GarageS3ClusterInstance
+// +kubebuilder:object:root=true
+type GarageS3ClusterInstanceList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3ClusterInstance `json:"items"`
+}
+
+// GarageS3ClusterInstanceSpec describes a cluster-scoped Garage S3 instance.
+type GarageS3ClusterInstanceSpec struct {
+	GarageS3InstanceSpec `json:",inline"`
+
+	// Namespace of the Secret referenced by AdminTokenSecret
+	AdminTokenSecretNamespace string `json:"adminTokenSecretNamespace"`
+}
+
+/* **************************************
+   GarageS3AccessKey API Schema and types
+   **************************************/
+
+// GarageS3AccessKey is the Schema for a Garage S3 access key.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3ak
+// +kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.spec.instanceRef.name`
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secret`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.spec.expiration`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3AccessKey struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3AccessKeySpec      `json:"spec"`
+	Status v1.GarageS3AccessKeyStatus `json:"status,omitempty"`
+}
+
+// GarageS3AccessKeyList contains a list of GarageS3AccessKey
+// +kubebuilder:object:root=true
+type GarageS3AccessKeyList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3AccessKey `json:"items"`
+}
+
+// GarageS3AccessKeySpec describes the desired state of an Access Key.
+type GarageS3AccessKeySpec struct {
+	// Reference to the instance this Access Key belongs to
+	InstanceRef v1.GarageS3InstanceRef `json:"instanceRef"`
+
+	// Whether this Access Key can create buckets
+	// +kubebuilder:default=false
+	CanCreateBucket bool `json:"canCreateBucket,omitempty"`
+
+	// Expiration time of the Access Key, which never expires when unset
+	Expiration *metav1.Time `json:"expiration,omitempty"`
+}
+
+/* **************************************
+   GarageS3Bucket API Schema and types
+ **************************************/
+
+// GarageS3Bucket is the Schema for an S3 Bucket related to a Garage S3 Instance.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3b
+// +kubebuilder:printcolumn:name="Instance",type=string,JSONPath=`.spec.instanceRef.name`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3Bucket struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3BucketSpec   `json:"spec"`
+	Status GarageS3BucketStatus `json:"status,omitempty"`
+}
+
+// GarageS3BucketList contains a list of GarageS3Bucket
+// +kubebuilder:object:root=true
+type GarageS3BucketList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3Bucket `json:"items"`
+}
+
+// GarageS3BucketSpec describes the desired state of a Bucket.
+type GarageS3BucketSpec struct {
+	// Reference to the GarageS3Instance (name + namespace) this Bucket belongs to.
+	InstanceRef v1.GarageS3InstanceRef `json:"instanceRef"`
+
+	// Website access configuration for the bucket
+	WebsiteAccess *v1.GarageS3WebsiteAccess `json:"websiteAccess,omitempty"`
+
+	// Optional quota in buckets or bytes to apply to this bucket
+	Quota *v1.GarageS3BucketQuota `json:"quota,omitempty"`
+
+	// List of additional aliases associated with this bucket
+	AdditionalAliases []string `json:"additionalAliases,omitempty"`
+
+	// List of permissions to apply to this bucket
+	Permissions []GarageS3BucketPermission `json:"permissions,omitempty"`
+
+	// Optional CORS rules of the bucket, applied through the S3 API of the instance
+	CORS []v1.GarageS3CORSRule `json:"cors,omitempty"`
+
+	// Optional lifecycle configuration of the bucket, applied through the S3 API of the instance
+	Lifecycle *v1.GarageS3BucketLifecycle `json:"lifecycle,omitempty"`
+}
+
+// GarageS3Permission is a permission an access key can be granted on a bucket.
+// +kubebuilder:validation:Enum=read;write;owner
+type GarageS3Permission string
+
+// Permissions an access key can be granted on a bucket.
+const (
+	PermissionRead  GarageS3Permission = "read"
+	PermissionWrite GarageS3Permission = "write"
+	PermissionOwner GarageS3Permission = "owner"
+)
+
+// GarageS3BucketPermission represents the permissions granted to an access key on the bucket
+type GarageS3BucketPermission struct {
+	// Name of the GarageS3AccessKey to which to apply the permission
+	AccessKeyName string `json:"accessKeyName"`
+
+	// Namespace of the GarageS3AccessKey, defaults to the bucket namespace
+	AccessKeyNamespace string `json:"accessKeyNamespace,omitempty"`
+
+	// Permissions granted to the access key, the others being denied
+	// +listType=set
+	Allow []GarageS3Permission `json:"allow,omitempty"`
+}
+
+// GarageS3BucketStatus represents the observed state of the Bucket
+type GarageS3BucketStatus struct {
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// ID of the Garage bucket provisioned for this Bucket, the only bucket deleted with it
+	BucketID string `json:"bucketId,omitempty"`
+
+	// Configuration applied to the bucket, after merging the instance bucket defaults
+	EffectiveConfig *GarageS3BucketEffectiveConfig `json:"effectiveConfig,omitempty"`
+
+	// Lifecycle rules of the bucket, read back from Garage through the S3 API once applied
+	Lifecycle *v1.GarageS3BucketLifecycle `json:"lifecycle,omitempty"`
+
+	// Result of the Garage check of each domain alias of a website bucket
+	// +listType=map
+	// +listMapKey=domain
+	WebsiteDomains []v1.GarageS3WebsiteDomainStatus `json:"websiteDomains,omitempty"`
+}
+
+// GarageS3BucketEffectiveConfig describes the configuration applied to a bucket.
+type GarageS3BucketEffectiveConfig struct {
+	Quota         *v1.GarageS3BucketQuota    `json:"quota,omitempty"`
+	WebsiteAccess *v1.GarageS3WebsiteAccess  `json:"websiteAccess,omitempty"`
+	Aliases       []string                   `json:"aliases,omitempty"`
+	Permissions   []GarageS3BucketPermission `json:"permissions,omitempty"`
+	CORS          []v1.GarageS3CORSRule      `json:"cors,omitempty"`
+
+	// Origin of each setting (bucket, instance or tenancyPolicy), keyed by setting path
+	// such as quota.maxBytes, aliases.<alias> or permissions.<namespace>/<key>
+	Sources map[string]string `json:"sources,omitempty"`
+}
+
+/* ***************************************
+   GarageS3AdminToken API Schema and types
+   ***************************************/
+
+// GarageS3AdminToken is the Schema for a scoped Garage admin API token.
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:resource:shortName=gs3at
+// +kubebuilder:printcolumn:name="Secret",type=string,JSONPath=`.status.secret`
+// +kubebuilder:printcolumn:name="Expires",type=date,JSONPath=`.status.expiresAt`
+// +kubebuilder:printcolumn:name="Ready",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].status`
+// +kubebuilder:printcolumn:name="Reason",type=string,JSONPath=`.status.conditions[?(@.type=="Ready")].reason`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+type GarageS3AdminToken struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   GarageS3AdminTokenSpec      `json:"spec"`
+	Status v1.GarageS3AdminTokenStatus `json:"status,omitempty"`
+}
+
+// GarageS3AdminTokenList contains a list of GarageS3AdminToken
+// +kubebuilder:object:root=true
+type GarageS3AdminTokenList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []GarageS3AdminToken `json:"items"`
+}
+
+// GarageS3AdminTokenSpec describes the desired state of an admin API token.
+// +kubebuilder:validation:XValidation:rule="!(has(self.expiration) && has(self.validity))",message="expiration and validity are mutually exclusive"
+type GarageS3AdminTokenSpec struct {
+	// Reference to the instance the token is created on. The token must be in the
+	// namespace of the instance admin token Secret.
+	InstanceRef v1.GarageS3InstanceRef `json:"instanceRef"`
+
+	// Admin API endpoints the token may call (e.g. Metrics, ListBuckets), * for all
+	// +kubebuilder:validation:MinItems=1
+	Scope []string `json:"scope"`
+
+	// Expiration time of the token
+	Expiration *metav1.Time `json:"expiration,omitempty"`
+
+	// Validity of the token, which is rotated once 80% of it has elapsed (e.g. 720h)
+	Validity *metav1.Duration `json:"validity,omitempty"`
+
+	// Name of the Secret the token is written to, defaults to <name>-gs3at
+	SecretName string `json:"secretName,omitempty"`
+}
@@ -0,0 +1,606 @@
+//go:build !ignore_autogenerated
+
+// Code generated by controller-gen. DO NOT EDIT.
+
+package v1beta2
+
+import (
+	"abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AccessKey) DeepCopyInto(out *GarageS3AccessKey) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AccessKey.
+func (in *GarageS3AccessKey) DeepCopy() *GarageS3AccessKey {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AccessKey)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3AccessKey) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AccessKeyList) DeepCopyInto(out *GarageS3AccessKeyList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3AccessKey, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AccessKeyList.
+func (in *GarageS3AccessKeyList) DeepCopy() *GarageS3AccessKeyList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AccessKeyList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3AccessKeyList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AccessKeySpec) DeepCopyInto(out *GarageS3AccessKeySpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.Expiration != nil {
+		in, out := &in.Expiration, &out.Expiration
+		*out = (*in).DeepCopy()
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AccessKeySpec.
+func (in *GarageS3AccessKeySpec) DeepCopy() *GarageS3AccessKeySpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AccessKeySpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AdminToken) DeepCopyInto(out *GarageS3AdminToken) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AdminToken.
+func (in *GarageS3AdminToken) DeepCopy() *GarageS3AdminToken {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AdminToken)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3AdminToken) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AdminTokenList) DeepCopyInto(out *GarageS3AdminTokenList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3AdminToken, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AdminTokenList.
+func (in *GarageS3AdminTokenList) DeepCopy() *GarageS3AdminTokenList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AdminTokenList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3AdminTokenList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3AdminTokenSpec) DeepCopyInto(out *GarageS3AdminTokenSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.Scope != nil {
+		in, out := &in.Scope, &out.Scope
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Expiration != nil {
+		in, out := &in.Expiration, &out.Expiration
+		*out = (*in).DeepCopy()
+	}
+	if in.Validity != nil {
+		in, out := &in.Validity, &out.Validity
+		*out = new(metav1.Duration)
+		**out = **in
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3AdminTokenSpec.
+func (in *GarageS3AdminTokenSpec) DeepCopy() *GarageS3AdminTokenSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3AdminTokenSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3Bucket) DeepCopyInto(out *GarageS3Bucket) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3Bucket.
+func (in *GarageS3Bucket) DeepCopy() *GarageS3Bucket {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3Bucket)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3Bucket) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketEffectiveConfig) DeepCopyInto(out *GarageS3BucketEffectiveConfig) {
+	*out = *in
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(v1.GarageS3BucketQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WebsiteAccess != nil {
+		in, out := &in.WebsiteAccess, &out.WebsiteAccess
+		*out = new(v1.GarageS3WebsiteAccess)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Aliases != nil {
+		in, out := &in.Aliases, &out.Aliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]GarageS3BucketPermission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CORS != nil {
+		in, out := &in.CORS, &out.CORS
+		*out = make([]v1.GarageS3CORSRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Sources != nil {
+		in, out := &in.Sources, &out.Sources
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketEffectiveConfig.
+func (in *GarageS3BucketEffectiveConfig) DeepCopy() *GarageS3BucketEffectiveConfig {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketEffectiveConfig)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketList) DeepCopyInto(out *GarageS3BucketList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3Bucket, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketList.
+func (in *GarageS3BucketList) DeepCopy() *GarageS3BucketList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3BucketList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketPermission) DeepCopyInto(out *GarageS3BucketPermission) {
+	*out = *in
+	if in.Allow != nil {
+		in, out := &in.Allow, &out.Allow
+		*out = make([]GarageS3Permission, len(*in))
+		copy(*out, *in)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketPermission.
+func (in *GarageS3BucketPermission) DeepCopy() *GarageS3BucketPermission {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketPermission)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketSpec) DeepCopyInto(out *GarageS3BucketSpec) {
+	*out = *in
+	out.InstanceRef = in.InstanceRef
+	if in.WebsiteAccess != nil {
+		in, out := &in.WebsiteAccess, &out.WebsiteAccess
+		*out = new(v1.GarageS3WebsiteAccess)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Quota != nil {
+		in, out := &in.Quota, &out.Quota
+		*out = new(v1.GarageS3BucketQuota)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.AdditionalAliases != nil {
+		in, out := &in.AdditionalAliases, &out.AdditionalAliases
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.Permissions != nil {
+		in, out := &in.Permissions, &out.Permissions
+		*out = make([]GarageS3BucketPermission, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.CORS != nil {
+		in, out := &in.CORS, &out.CORS
+		*out = make([]v1.GarageS3CORSRule, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(v1.GarageS3BucketLifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketSpec.
+func (in *GarageS3BucketSpec) DeepCopy() *GarageS3BucketSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3BucketStatus) DeepCopyInto(out *GarageS3BucketStatus) {
+	*out = *in
+	if in.Conditions != nil {
+		in, out := &in.Conditions, &out.Conditions
+		*out = make([]metav1.Condition, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+	if in.EffectiveConfig != nil {
+		in, out := &in.EffectiveConfig, &out.EffectiveConfig
+		*out = new(GarageS3BucketEffectiveConfig)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Lifecycle != nil {
+		in, out := &in.Lifecycle, &out.Lifecycle
+		*out = new(v1.GarageS3BucketLifecycle)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.WebsiteDomains != nil {
+		in, out := &in.WebsiteDomains, &out.WebsiteDomains
+		*out = make([]v1.GarageS3WebsiteDomainStatus, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3BucketStatus.
+func (in *GarageS3BucketStatus) DeepCopy() *GarageS3BucketStatus {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3BucketStatus)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3ClusterInstance) DeepCopyInto(out *GarageS3ClusterInstance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3ClusterInstance.
+func (in *GarageS3ClusterInstance) DeepCopy() *GarageS3ClusterInstance {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3ClusterInstance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3ClusterInstance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3ClusterInstanceList) DeepCopyInto(out *GarageS3ClusterInstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3ClusterInstance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3ClusterInstanceList.
+func (in *GarageS3ClusterInstanceList) DeepCopy() *GarageS3ClusterInstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3ClusterInstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3ClusterInstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3ClusterInstanceSpec) DeepCopyInto(out *GarageS3ClusterInstanceSpec) {
+	*out = *in
+	in.GarageS3InstanceSpec.DeepCopyInto(&out.GarageS3InstanceSpec)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3ClusterInstanceSpec.
+func (in *GarageS3ClusterInstanceSpec) DeepCopy() *GarageS3ClusterInstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3ClusterInstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3Instance) DeepCopyInto(out *GarageS3Instance) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ObjectMeta.DeepCopyInto(&out.ObjectMeta)
+	in.Spec.DeepCopyInto(&out.Spec)
+	in.Status.DeepCopyInto(&out.Status)
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3Instance.
+func (in *GarageS3Instance) DeepCopy() *GarageS3Instance {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3Instance)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3Instance) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3InstanceList) DeepCopyInto(out *GarageS3InstanceList) {
+	*out = *in
+	out.TypeMeta = in.TypeMeta
+	in.ListMeta.DeepCopyInto(&out.ListMeta)
+	if in.Items != nil {
+		in, out := &in.Items, &out.Items
+		*out = make([]GarageS3Instance, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3InstanceList.
+func (in *GarageS3InstanceList) DeepCopy() *GarageS3InstanceList {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3InstanceList)
+	in.DeepCopyInto(out)
+	return out
+}
+
+// DeepCopyObject is an autogenerated deepcopy function, copying the receiver, creating a new runtime.Object.
+func (in *GarageS3InstanceList) DeepCopyObject() runtime.Object {
+	if c := in.DeepCopy(); c != nil {
+		return c
+	}
+	return nil
+}
+
+// DeepCopyInto is an autogenerated deepcopy function, copying the receiver, writing into out. in must be non-nil.
+func (in *GarageS3InstanceSpec) DeepCopyInto(out *GarageS3InstanceSpec) {
+	*out = *in
+	if in.Endpoints != nil {
+		in, out := &in.Endpoints, &out.Endpoints
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.S3 != nil {
+		in, out := &in.S3, &out.S3
+		*out = new(v1.GarageS3S3API)
+		**out = **in
+	}
+	if in.Web != nil {
+		in, out := &in.Web, &out.Web
+		*out = new(v1.GarageS3WebService)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Tenancy != nil {
+		in, out := &in.Tenancy, &out.Tenancy
+		*out = new(v1.GarageS3TenancyPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.BucketDefaults != nil {
+		in, out := &in.BucketDefaults, &out.BucketDefaults
+		*out = new(v1.GarageS3BucketDefaults)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Bootstrap != nil {
+		in, out := &in.Bootstrap, &out.Bootstrap
+		*out = new(v1.GarageS3BootstrapPolicy)
+		(*in).DeepCopyInto(*out)
+	}
+	if in.Peers != nil {
+		in, out := &in.Peers, &out.Peers
+		*out = make([]string, len(*in))
+		copy(*out, *in)
+	}
+	if in.PeerService != nil {
+		in, out := &in.PeerService, &out.PeerService
+		*out = new(v1.GarageS3PeerService)
+		**out = **in
+	}
+	if in.BlockErrors != nil {
+		in, out := &in.BlockErrors, &out.BlockErrors
+		*out = new(v1.GarageS3BlockErrorPolicy)
+		**out = **in
+	}
+	if in.WorkerVariables != nil {
+		in, out := &in.WorkerVariables, &out.WorkerVariables
+		*out = make(map[string]string, len(*in))
+		for key, val := range *in {
+			(*out)[key] = val
+		}
+	}
+	if in.NodeWorkerVariables != nil {
+		in, out := &in.NodeWorkerVariables, &out.NodeWorkerVariables
+		*out = make([]v1.GarageS3NodeWorkerVariables, len(*in))
+		for i := range *in {
+			(*in)[i].DeepCopyInto(&(*out)[i])
+		}
+	}
+}
+
+// DeepCopy is an autogenerated deepcopy function, copying the receiver, creating a new GarageS3InstanceSpec.
+func (in *GarageS3InstanceSpec) DeepCopy() *GarageS3InstanceSpec {
+	if in == nil {
+		return nil
+	}
+	out := new(GarageS3InstanceSpec)
+	in.DeepCopyInto(out)
+	return out
+}
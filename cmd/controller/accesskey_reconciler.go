@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	v1 "abucquet.com/garage-s3-operator/api/v1"
@@ -9,7 +10,6 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -24,8 +24,8 @@ const (
 // accessKeyReconciler is a reconciler for GarageS3AccessKey resources.
 type accessKeyReconciler struct {
 	client.Client
-	scheme     *runtime.Scheme
-	kubeClient *kubernetes.Clientset
+	scheme        *runtime.Scheme
+	garageClients GarageClientFactory
 }
 
 // AccessKeyExists checks if an access key with the given name exists in Garage S3.
@@ -43,14 +43,29 @@ func (r *accessKeyReconciler) AccessKeyExists(apiCtx context.Context, garageClie
 	return "", nil
 }
 
+// AccessKeyClaimedBy returns the namespace/name of the other GarageS3AccessKey which provisioned
+// the Garage access key, or an empty string, so that a key is never taken over by name.
+func (r *accessKeyReconciler) AccessKeyClaimedBy(ctx context.Context, ak *v1.GarageS3AccessKey, accessKeyID string) (string, error) {
+	keys := &v1.GarageS3AccessKeyList{}
+	if err := r.List(ctx, keys); err != nil {
+		return "", err
+	}
+	for _, other := range keys.Items {
+		if other.Namespace == ak.Namespace && other.Name == ak.Name {
+			continue
+		}
+		if other.Status.KeyID == accessKeyID {
+			return other.Namespace + "/" + other.Name, nil
+		}
+	}
+	return "", nil
+}
+
 // Return Secret Access Key
 func (r *accessKeyReconciler) GetSecretAccessKey(ctx context.Context, namespace string, secretName string) bool {
 
-	_, err := r.kubeClient.CoreV1().Secrets(namespace).Get(ctx, secretName, metav1.GetOptions{})
-	if err != nil {
-		return false
-	}
-	return true
+	err := r.Get(ctx, client.ObjectKey{Name: secretName, Namespace: namespace}, &corev1.Secret{})
+	return err == nil
 }
 
 func (r *accessKeyReconciler) GenerateCreateKeyBody(ak v1.GarageS3AccessKey, keyname string) (garage.UpdateKeyRequestBody, error) {
@@ -82,7 +97,8 @@ func (r *accessKeyReconciler) GenerateCreateKeyBody(ak v1.GarageS3AccessKey, key
 	return keyReq, nil
 }
 
-func (r *accessKeyReconciler) UpdateStatus(ctx context.Context, secretName string, status metav1.ConditionStatus, reason string, message string, instance *v1.GarageS3AccessKey) {
+// UpdateStatus sets the Secret and Ready condition and writes the status, returning the error of the write.
+func (r *accessKeyReconciler) UpdateStatus(ctx context.Context, secretName string, status metav1.ConditionStatus, reason string, message string, instance *v1.GarageS3AccessKey) error {
 	instance.Status.Secret = secretName
 	cond := metav1.Condition{
 		Type:    "Ready",
@@ -112,7 +128,9 @@ func (r *accessKeyReconciler) UpdateStatus(ctx context.Context, secretName strin
 
 	if err := r.Status().Update(ctx, instance); err != nil {
 		log.Log.Error(err, "Failed to update GarageS3AccessKey status")
+		return err
 	}
+	return nil
 }
 
 // Reconcile performs reconciliation for GarageS3AccessKey.
@@ -136,23 +154,23 @@ func (r *accessKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	// If the object is being deleted, handle finalizer cleanup
 	if !ak.ObjectMeta.DeletionTimestamp.IsZero() {
 		if controllerutil.ContainsFinalizer(ak, finalizerName) {
-			// Perform external cleanup: delete the access key in Garage S3 if present
+			// Perform external cleanup: delete the access key provisioned for this resource, never
+			// one found by name, so that nothing is deleted for keys refused by the tenancy policy
 			instanceRef := ak.Spec.InstanceRef
-			if instanceRef.Name != "" && instanceRef.Namespace != "" {
-				instance := &v1.GarageS3Instance{}
-				if err := r.Get(ctx, client.ObjectKey{Name: instanceRef.Name, Namespace: instanceRef.Namespace}, instance); err != nil {
+			if instanceRef.Name != "" && ak.Status.KeyID != "" {
+				instance, err := GetInstanceForRef(ctx, r, instanceRef)
+				if err != nil {
 					// If instance not found, ignore — nothing to cleanup remotely
 				} else {
-					garageClient, apiCtx, err := CreateGarageClient(r.kubeClient, instance)
+					garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
 					if err == nil {
-						accessKeyID, err := r.AccessKeyExists(apiCtx, garageClient, ak.Name)
-						if err == nil && accessKeyID != "" {
-							if _, err := garageClient.AccessKeyAPI.DeleteKey(apiCtx).Id(accessKeyID).Execute(); err != nil {
-								log.Error(err, "Failed to delete external access key during finalizer cleanup", "accessKeyId", accessKeyID)
-								return ctrl.Result{}, err
-							}
-							log.Info("Deleted external access key during finalizer cleanup", "accessKeyId", accessKeyID)
+						accessKeyID := ak.Status.KeyID
+						resp, err := garageClient.AccessKeyAPI.DeleteKey(apiCtx).Id(accessKeyID).Execute()
+						if err != nil && (resp == nil || resp.StatusCode != 404) {
+							log.Error(err, "Failed to delete external access key during finalizer cleanup", "accessKeyId", accessKeyID)
+							return ctrl.Result{}, err
 						}
+						log.Info("Deleted external access key during finalizer cleanup", "accessKeyId", accessKeyID)
 					} else {
 						log.Error(err, "Failed to create Garage client for finalizer cleanup", "InstanceRef", instanceRef)
 						return ctrl.Result{}, err
@@ -184,16 +202,26 @@ func (r *accessKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 	// Fetch the associated GarageS3Instance and create Garage Client
 	instanceRef := ak.Spec.InstanceRef
-	instance := &v1.GarageS3Instance{}
-	if err := r.Get(ctx, client.ObjectKey{
-		Name:      instanceRef.Name,
-		Namespace: instanceRef.Namespace,
-	}, instance); err != nil {
+	instance, err := GetInstanceForRef(ctx, r, instanceRef)
+	if err != nil {
 		log.Error(err, "Failed to get associated GarageS3Instance", "InstanceRef", instanceRef)
 		r.UpdateStatus(ctx, "", metav1.ConditionFalse, "InstanceNotFound", "Associated GarageS3Instance not found", ak)
 		return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
 	}
-	garageClient, apiCtx, err := CreateGarageClient(r.kubeClient, instance)
+
+	// Enforce the tenancy policy of the instance
+	if err := CheckAccessKeyPolicy(ctx, r, instance, ak); err != nil {
+		var violation *policyViolation
+		if errors.As(err, &violation) {
+			log.Info("Access Key refused by instance tenancy policy", "Reason", err.Error())
+			r.UpdateStatus(ctx, "", metav1.ConditionFalse, "PolicyViolation", err.Error(), ak)
+			return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, nil
+		}
+		log.Error(err, "Failed to check instance tenancy policy", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, "", metav1.ConditionFalse, "KubernetesError", "Failed to check instance tenancy policy", ak)
+		return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
+	}
+	garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
 	if err != nil {
 		log.Error(err, "Failed to create Garage S3 client for associated instance", "InstanceRef", instanceRef)
 		r.UpdateStatus(ctx, "", metav1.ConditionFalse, "GarageClientError", "Could not create Garage S3 client", ak)
@@ -208,6 +236,20 @@ func (r *accessKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		r.UpdateStatus(ctx, "", metav1.ConditionUnknown, "UnknownGarageState", "Failed to check if Access Key exists", ak)
 		return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
 	}
+	// Refuse to take over a Garage access key provisioned for another resource
+	if accessKey != "" && accessKey != ak.Status.KeyID {
+		owner, err := r.AccessKeyClaimedBy(ctx, ak, accessKey)
+		if err != nil {
+			log.Error(err, "Failed to check the owner of the Garage access key", "KeyName", keyName)
+			r.UpdateStatus(ctx, "", metav1.ConditionFalse, "KubernetesError", "Failed to check the owner of the Garage access key", ak)
+			return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
+		}
+		if owner != "" {
+			log.Info("Garage access key already provisioned for another GarageS3AccessKey", "KeyName", keyName, "Owner", owner)
+			r.UpdateStatus(ctx, "", metav1.ConditionFalse, "KeyConflict", "Garage access key already provisioned for GarageS3AccessKey "+owner, ak)
+			return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, nil
+		}
+	}
 	if accessKey == "" {
 		// Create Access Key in Garage S3
 		req, err := r.GenerateCreateKeyBody(*ak, keyName)
@@ -225,7 +267,6 @@ func (r *accessKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 
 		accessKey = keyInfo.AccessKeyId
 		secretKey = keyInfo.GetSecretAccessKey()
-
 		log.Info("Created Access Key in Garage S3", "KeyName", keyName, "AccessKey", keyInfo)
 	} else {
 		// Update Access Key in Garage S3
@@ -264,9 +305,19 @@ func (r *accessKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		log.Info("Updated Access Key in Garage S3", "KeyName", keyName, "AccessKey", keyInfo)
 	}
 
+	// Record the key before anything else, deletion and claim checks rely on it
+	if ak.Status.KeyID != accessKey {
+		ak.Status.KeyID = accessKey
+		if err := r.Status().Update(ctx, ak); err != nil {
+			log.Error(err, "Failed to record the Garage access key in status", "KeyName", keyName, "AccessKey", accessKey)
+			return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
+		}
+	}
+
 	// Check if the corresponding Kubernetes Secret exists
 	secretName := ak.Name + "-gs3ak"
-	secret, err := r.kubeClient.CoreV1().Secrets(ak.Namespace).Get(ctx, secretName, metav1.GetOptions{})
+	secret := &corev1.Secret{}
+	err = r.Get(ctx, client.ObjectKey{Name: secretName, Namespace: ak.Namespace}, secret)
 	if err != nil {
 		// Create Kubernetes Secret with Access Key and Secret Key
 		secretData := map[string][]byte{
@@ -291,8 +342,7 @@ func (r *accessKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
 		}
 
-		_, err := r.kubeClient.CoreV1().Secrets(ak.Namespace).Create(ctx, sec, metav1.CreateOptions{})
-		if err != nil {
+		if err := r.Create(ctx, sec); err != nil {
 			log.Error(err, "Failed to create Kubernetes Secret for Access Key", "SecretName", secretName)
 			r.UpdateStatus(ctx, "", metav1.ConditionFalse, "KubernetesError", "Failed to create Kubernetes Secret for Access Key", ak)
 			return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
@@ -319,8 +369,7 @@ func (r *accessKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			updated = true
 		}
 		if updated {
-			_, err := r.kubeClient.CoreV1().Secrets(ak.Namespace).Update(ctx, secret, metav1.UpdateOptions{})
-			if err != nil {
+			if err := r.Update(ctx, secret); err != nil {
 				log.Error(err, "Failed to update Kubernetes Secret for Access Key", "SecretName", secretName)
 				r.UpdateStatus(ctx, "", metav1.ConditionFalse, "KubernetesError", "Failed to update Kubernetes Secret for Access Key", ak)
 				return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
@@ -329,7 +378,9 @@ func (r *accessKeyReconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 		}
 	}
 
-	r.UpdateStatus(ctx, secretName, metav1.ConditionTrue, "Ready", "Access Key is ready", ak)
+	if err := r.UpdateStatus(ctx, secretName, metav1.ConditionTrue, "Ready", "Access Key is ready", ak); err != nil {
+		return ctrl.Result{RequeueAfter: accessKeyErrorRequeueInterval}, err
+	}
 	return ctrl.Result{RequeueAfter: accessKeyRequeueInterval}, nil
 }
 
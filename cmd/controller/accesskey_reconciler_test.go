@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const accessKeyFinalizer = "garage-s3-operator.abucquet.com/finalizer"
+
+func newTestAccessKey(env *garageTestEnv, spec v1.GarageS3AccessKeySpec) *v1.GarageS3AccessKey {
+	spec.InstanceRef = env.instanceRef()
+	return &v1.GarageS3AccessKey{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-key", Namespace: "default"},
+		Spec:       spec,
+	}
+}
+
+func TestAccessKeyReconciler_FakeGarage(t *testing.T) {
+	tests := []struct {
+		name            string
+		spec            v1.GarageS3AccessKeySpec
+		setup           func(env *garageTestEnv)
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  string
+		expectedRequeue time.Duration
+		expectError     bool
+		// Expected calls of the fake per operation
+		expectedCalls map[string]int
+	}{
+		{
+			name:            "creates the key",
+			spec:            v1.GarageS3AccessKeySpec{CanCreateBucket: true},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: accessKeyRequeueInterval,
+			expectedCalls:   map[string]int{"CreateKey": 1, "UpdateKey": 0},
+		},
+		{
+			name:            "updates an existing key",
+			spec:            v1.GarageS3AccessKeySpec{CanCreateBucket: true, NeverExpires: true},
+			setup:           func(env *garageTestEnv) { env.garage.AddKey("test-key") },
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: accessKeyRequeueInterval,
+			expectedCalls:   map[string]int{"CreateKey": 0, "UpdateKey": 1, "GetKeyInfo": 1},
+		},
+		{
+			name:            "invalid expiration",
+			spec:            v1.GarageS3AccessKeySpec{Expiration: "tomorrow"},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "SyntaxError",
+			expectError:     true,
+			expectedRequeue: 0,
+			expectedCalls:   map[string]int{"CreateKey": 0},
+		},
+		{
+			name:            "listing failure",
+			setup:           func(env *garageTestEnv) { env.garage.InjectFault("ListKeys", http.StatusInternalServerError, 1) },
+			expectedStatus:  metav1.ConditionUnknown,
+			expectedReason:  "UnknownGarageState",
+			expectedRequeue: accessKeyErrorRequeueInterval,
+			expectError:     true,
+			expectedCalls:   map[string]int{"CreateKey": 0},
+		},
+		{
+			name:            "creation failure",
+			setup:           func(env *garageTestEnv) { env.garage.InjectFault("CreateKey", http.StatusServiceUnavailable, 1) },
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "GarageClientError",
+			expectedRequeue: accessKeyErrorRequeueInterval,
+			expectError:     true,
+			expectedCalls:   map[string]int{"CreateKey": 1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			if tt.setup != nil {
+				tt.setup(env)
+			}
+			ak := newTestAccessKey(env, tt.spec)
+			c := env.client(ak)
+			r := &accessKeyReconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ak)})
+			if tt.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if result.RequeueAfter != tt.expectedRequeue {
+				t.Errorf("expected RequeueAfter=%v, got %v", tt.expectedRequeue, result.RequeueAfter)
+			}
+			for operation, calls := range tt.expectedCalls {
+				if got := env.garage.Calls(operation); got != calls {
+					t.Errorf("expected %d %s calls, got %d", calls, operation, got)
+				}
+			}
+
+			got := &v1.GarageS3AccessKey{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(ak), got); err != nil {
+				t.Fatalf("failed to get access key: %v", err)
+			}
+			cond := readyCondition(t, got.Status.Conditions)
+			if cond.Status != tt.expectedStatus || cond.Reason != tt.expectedReason {
+				t.Errorf("expected Ready %s/%s, got %s/%s: %s", tt.expectedStatus, tt.expectedReason, cond.Status, cond.Reason, cond.Message)
+			}
+			if tt.expectedStatus != metav1.ConditionTrue {
+				return
+			}
+
+			// The key exists in Garage and its credentials are in the Secret
+			key, found := env.garage.KeyByName("test-key")
+			if !found {
+				t.Fatal("expected the key to exist in Garage")
+			}
+			if key.CreateBucket != tt.spec.CanCreateBucket {
+				t.Errorf("expected createBucket %v, got %v", tt.spec.CanCreateBucket, key.CreateBucket)
+			}
+			if got.Status.KeyID != key.ID {
+				t.Errorf("expected key %s in status, got %q", key.ID, got.Status.KeyID)
+			}
+			if got.Status.Secret != "test-key-gs3ak" {
+				t.Errorf("expected secret test-key-gs3ak in status, got %q", got.Status.Secret)
+			}
+			secret := &corev1.Secret{}
+			if err := c.Get(context.Background(), client.ObjectKey{Name: "test-key-gs3ak", Namespace: "default"}, secret); err != nil {
+				t.Fatalf("failed to get the key Secret: %v", err)
+			}
+			if string(secret.Data["AWS_ACCESS_KEY"]) != key.ID || string(secret.Data["AWS_SECRET_KEY"]) != key.Secret {
+				t.Errorf("expected the Secret to hold the credentials of key %s", key.ID)
+			}
+		})
+	}
+}
+
+func TestAccessKeyReconciler_FakeGarageDeletion(t *testing.T) {
+	tests := []struct {
+		name string
+		// The key was never provisioned for the resource, e.g. refused by the tenancy policy
+		unprovisioned bool
+		expectRemoval bool
+	}{
+		{name: "deletes the provisioned key", expectRemoval: true},
+		{name: "key never provisioned", unprovisioned: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			key := env.garage.AddKey("test-key")
+			env.garage.AddKey("other-key")
+			ak := newTestAccessKey(env, v1.GarageS3AccessKeySpec{})
+			ak.Finalizers = []string{accessKeyFinalizer}
+			ak.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			if !tt.unprovisioned {
+				ak.Status.KeyID = key.ID
+			}
+			c := env.client(ak)
+			r := &accessKeyReconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+
+			if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ak)}); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if _, found := env.garage.KeyByName("test-key"); found == tt.expectRemoval {
+				t.Errorf("expected key removal %v, got %d keys", tt.expectRemoval, env.garage.KeyCount())
+			}
+			if _, found := env.garage.KeyByName("other-key"); !found {
+				t.Error("expected the other key to be kept")
+			}
+		})
+	}
+}
+
+func TestAccessKeyReconciler_KeyConflict(t *testing.T) {
+	env := newGarageTestEnv(t)
+	key := env.garage.AddKey("test-key")
+	owner := &v1.GarageS3AccessKey{
+		ObjectMeta: metav1.ObjectMeta{Name: "owner-key", Namespace: "other"},
+		Status:     v1.GarageS3AccessKeyStatus{KeyID: key.ID},
+	}
+	ak := newTestAccessKey(env, v1.GarageS3AccessKeySpec{})
+	c := env.client(ak, owner)
+	r := &accessKeyReconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+
+	result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(ak)})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if result.RequeueAfter != accessKeyErrorRequeueInterval {
+		t.Errorf("expected RequeueAfter=%v, got %v", accessKeyErrorRequeueInterval, result.RequeueAfter)
+	}
+	if calls := env.garage.Calls("UpdateKey"); calls != 0 {
+		t.Errorf("expected the key not to be updated, got %d calls", calls)
+	}
+	got := &v1.GarageS3AccessKey{}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(ak), got); err != nil {
+		t.Fatalf("failed to get access key: %v", err)
+	}
+	if cond := readyCondition(t, got.Status.Conditions); cond.Reason != "KeyConflict" {
+		t.Errorf("expected reason KeyConflict, got %s: %s", cond.Reason, cond.Message)
+	}
+	if got.Status.KeyID != "" || got.Status.Secret != "" {
+		t.Errorf("expected no key in status, got %+v", got.Status)
+	}
+}
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const adminTokenFinalizer = "garage-s3-operator.abucquet.com/admintoken-finalizer"
+
+// Annotation of the token Secret recording the ID of the Garage token it holds
+const adminTokenIDAnnotation = "garage-s3-operator.abucquet.com/token-id"
+
+// Label of the token Secrets holding the name of their GarageS3AdminToken, only the
+// labeled Secrets being cached for the controller to watch them
+const adminTokenSecretLabel = "garage-s3-operator.abucquet.com/admin-token"
+
+const (
+	adminTokenRequeueInterval      = 5 * time.Minute
+	adminTokenErrorRequeueInterval = 30 * time.Second
+)
+
+// adminTokenSecretCacheOptions restricts the cache of Secrets to the admin token Secrets, rather
+// than every Secret of the cluster. Other Secrets are read without the cache.
+func adminTokenSecretCacheOptions() (cache.ByObject, error) {
+	requirement, err := labels.NewRequirement(adminTokenSecretLabel, selection.Exists, nil)
+	if err != nil {
+		return cache.ByObject{}, err
+	}
+	return cache.ByObject{Label: labels.NewSelector().Add(*requirement)}, nil
+}
+
+// adminTokenReconciler is a reconciler for GarageS3AdminToken resources.
+type adminTokenReconciler struct {
+	client.Client
+	scheme        *runtime.Scheme
+	garageClients GarageClientFactory
+}
+
+// UpdateStatus sets the Ready condition and writes the status, returning the error of the write.
+func (r *adminTokenReconciler) UpdateStatus(ctx context.Context, status metav1.ConditionStatus, reason string, message string, token *v1.GarageS3AdminToken) error {
+	cond := metav1.Condition{
+		Type:    "Ready",
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	// Replace existing Ready condition if present, preserve LastTransitionTime when status unchanged
+	found := false
+	for i := range token.Status.Conditions {
+		if token.Status.Conditions[i].Type == "Ready" {
+			if token.Status.Conditions[i].Status == cond.Status {
+				cond.LastTransitionTime = token.Status.Conditions[i].LastTransitionTime
+			} else {
+				cond.LastTransitionTime = metav1.Now()
+			}
+			token.Status.Conditions[i] = cond
+			found = true
+			break
+		}
+	}
+	if !found {
+		cond.LastTransitionTime = metav1.Now()
+		token.Status.Conditions = append(token.Status.Conditions, cond)
+	}
+
+	if err := r.Status().Update(ctx, token); err != nil {
+		log.Log.Error(err, "Failed to update GarageS3AdminToken status")
+		return err
+	}
+	return nil
+}
+
+// SecretName returns the name of the Secret the token is written to.
+func (r *adminTokenReconciler) SecretName(token *v1.GarageS3AdminToken) string {
+	if token.Spec.SecretName != "" {
+		return token.Spec.SecretName
+	}
+	return token.Name + "-gs3at"
+}
+
+// GenerateTokenBody builds the create/update request of the token, and returns its expiration.
+func (r *adminTokenReconciler) GenerateTokenBody(token *v1.GarageS3AdminToken, now time.Time) (garage.UpdateAdminTokenRequestBody, *time.Time, error) {
+	if token.Spec.Expiration != "" && token.Spec.Validity != nil {
+		return garage.UpdateAdminTokenRequestBody{}, nil, fmt.Errorf("expiration and validity are mutually exclusive")
+	}
+
+	var expiration *time.Time
+	if token.Spec.Expiration != "" {
+		// Parse expiration time in RFC3339
+		parsed, err := time.Parse(time.RFC3339, token.Spec.Expiration)
+		if err != nil {
+			return garage.UpdateAdminTokenRequestBody{}, nil, err
+		}
+		expiration = &parsed
+	} else if token.Spec.Validity != nil {
+		expires := now.Add(token.Spec.Validity.Duration)
+		expiration = &expires
+	}
+
+	name := token.Namespace + "-" + token.Name
+	neverExpires := expiration == nil
+	body := garage.UpdateAdminTokenRequestBody{
+		Name:         *garage.NewNullableString(&name),
+		Expiration:   *garage.NewNullableTime(expiration),
+		NeverExpires: &neverExpires,
+		Scope:        token.Spec.Scope,
+	}
+	return body, expiration, nil
+}
+
+// NeedsRotation returns the reason why the current token must be replaced, or an empty string.
+func (r *adminTokenReconciler) NeedsRotation(token *v1.GarageS3AdminToken, info *garage.GetAdminTokenInfoResponse, secret *corev1.Secret, now time.Time) string {
+	if info == nil {
+		return "token not found in Garage"
+	}
+	if info.Expired {
+		return "token expired"
+	}
+	if secret == nil || len(secret.Data["token"]) == 0 {
+		return "token Secret missing"
+	}
+	if request := token.Annotations[v1.AdminTokenRotateAnnotation]; request != "" && request != token.Status.RotationRequest {
+		return "rotation requested"
+	}
+	if token.Spec.Validity != nil && token.Status.CreatedAt != nil {
+		rotateAt := token.Status.CreatedAt.Add(token.Spec.Validity.Duration * 4 / 5)
+		if now.After(rotateAt) {
+			return "token validity elapsed"
+		}
+	}
+	return ""
+}
+
+// WriteSecret creates or updates the Secret holding the token, annotated with the token ID.
+func (r *adminTokenReconciler) WriteSecret(ctx context.Context, token *v1.GarageS3AdminToken, secret *corev1.Secret, tokenID string, value string) error {
+	if secret == nil {
+		sec := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      r.SecretName(token),
+				Namespace: token.Namespace,
+				Labels:    map[string]string{adminTokenSecretLabel: token.Name},
+				Annotations: map[string]string{
+					"managed-by":           "garage-s3-operator",
+					adminTokenIDAnnotation: tokenID,
+				},
+			},
+			Data: map[string][]byte{
+				"token": []byte(value),
+			},
+		}
+		// set owner reference so Secret is garbage-collected with the GarageS3AdminToken
+		if err := controllerutil.SetControllerReference(token, sec, r.scheme); err != nil {
+			return err
+		}
+		return r.Create(ctx, sec)
+	}
+
+	if secret.ObjectMeta.Annotations == nil {
+		secret.ObjectMeta.Annotations = map[string]string{}
+	}
+	secret.ObjectMeta.Annotations["managed-by"] = "garage-s3-operator"
+	secret.ObjectMeta.Annotations[adminTokenIDAnnotation] = tokenID
+	if secret.ObjectMeta.Labels == nil {
+		secret.ObjectMeta.Labels = map[string]string{}
+	}
+	secret.ObjectMeta.Labels[adminTokenSecretLabel] = token.Name
+	if secret.Data == nil {
+		secret.Data = map[string][]byte{}
+	}
+	secret.Data["token"] = []byte(value)
+	return r.Update(ctx, secret)
+}
+
+// AdoptSecretToken records in the status the token held by the Secret when the status misses it,
+// which happens when the status update of a rotation failed after the Secret was written. It
+// returns whether the token was adopted, and the ID of the token the status recorded before.
+func (r *adminTokenReconciler) AdoptSecretToken(token *v1.GarageS3AdminToken, secret *corev1.Secret) (bool, string) {
+	if secret == nil {
+		return false, ""
+	}
+	tokenID := secret.Annotations[adminTokenIDAnnotation]
+	if tokenID == "" || tokenID == token.Status.TokenID {
+		return false, ""
+	}
+	previousTokenID := token.Status.TokenID
+	token.Status.TokenID = tokenID
+	token.Status.CreatedAt = nil
+	token.Status.ExpiresAt = nil
+	return true, previousTokenID
+}
+
+// DeleteGarageToken deletes the token in Garage, ignoring tokens already deleted.
+func (r *adminTokenReconciler) DeleteGarageToken(apiCtx context.Context, garageClient *garage.APIClient, tokenID string) error {
+	resp, err := garageClient.AdminAPITokenAPI.DeleteAdminToken(apiCtx).Id(tokenID).Execute()
+	if err != nil && (resp == nil || resp.StatusCode != 404) {
+		return err
+	}
+	return nil
+}
+
+// Reconcile performs reconciliation for GarageS3AdminToken.
+func (r *adminTokenReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithValues("GarageS3AdminToken", req.NamespacedName)
+
+	token := &v1.GarageS3AdminToken{}
+	if err := r.Get(ctx, req.NamespacedName, token); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// If the object is being deleted, delete the token in Garage then remove the finalizer
+	if !token.ObjectMeta.DeletionTimestamp.IsZero() {
+		if controllerutil.ContainsFinalizer(token, adminTokenFinalizer) {
+			if token.Status.TokenID != "" {
+				instance, err := GetInstanceForRef(ctx, r, token.Spec.InstanceRef)
+				if err == nil {
+					garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
+					if err != nil {
+						log.Error(err, "Failed to create Garage client for finalizer cleanup", "InstanceRef", token.Spec.InstanceRef)
+						return ctrl.Result{}, err
+					}
+					if err := r.DeleteGarageToken(apiCtx, garageClient, token.Status.TokenID); err != nil {
+						log.Error(err, "Failed to delete admin token during finalizer cleanup", "TokenID", token.Status.TokenID)
+						return ctrl.Result{}, err
+					}
+					log.Info("Deleted admin token during finalizer cleanup", "TokenID", token.Status.TokenID)
+				}
+				// If instance not found, ignore — nothing to cleanup remotely
+			}
+			controllerutil.RemoveFinalizer(token, adminTokenFinalizer)
+			if err := r.Update(ctx, token); err != nil {
+				log.Error(err, "Failed to remove finalizer from GarageS3AdminToken")
+				return ctrl.Result{}, err
+			}
+		}
+		return ctrl.Result{}, nil
+	}
+
+	// Ensure finalizer is present on non-deleted objects
+	if !controllerutil.ContainsFinalizer(token, adminTokenFinalizer) {
+		controllerutil.AddFinalizer(token, adminTokenFinalizer)
+		if err := r.Update(ctx, token); err != nil {
+			log.Error(err, "Failed to add finalizer to GarageS3AdminToken")
+			return ctrl.Result{}, err
+		}
+	}
+
+	// Fetch the associated instance
+	instanceRef := token.Spec.InstanceRef
+	instance, err := GetInstanceForRef(ctx, r, instanceRef)
+	if err != nil {
+		log.Error(err, "Failed to get associated GarageS3Instance", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "InstanceNotFound", "Associated GarageS3Instance not found", token)
+		return ctrl.Result{RequeueAfter: adminTokenErrorRequeueInterval}, err
+	}
+
+	// Tokens may only be minted where the full admin token of the instance already lives
+	if token.Namespace != instance.GetAdminTokenSecretNamespace() {
+		message := fmt.Sprintf("admin tokens of this instance must be in namespace %s", instance.GetAdminTokenSecretNamespace())
+		log.Info("Admin token refused", "Reason", message)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "NamespaceNotAllowed", message, token)
+		return ctrl.Result{}, nil // spec error, no point retrying until spec changes
+	}
+
+	now := time.Now()
+	body, expiration, err := r.GenerateTokenBody(token, now)
+	if err != nil {
+		log.Error(err, "Failed to generate admin token body")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "SyntaxError", err.Error(), token)
+		return ctrl.Result{}, nil // spec error, no point retrying until spec changes
+	}
+
+	garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
+	if err != nil {
+		log.Error(err, "Failed to create Garage S3 client for associated instance", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageClientError", "Could not create Garage S3 client", token)
+		return ctrl.Result{RequeueAfter: adminTokenErrorRequeueInterval}, err
+	}
+
+	// Fetch the current Secret, if any
+	secretName := r.SecretName(token)
+	secret := &corev1.Secret{}
+	if err := r.Get(ctx, client.ObjectKey{Name: secretName, Namespace: token.Namespace}, secret); apierrors.IsNotFound(err) {
+		secret = nil
+	} else if err != nil {
+		log.Error(err, "Failed to get admin token Secret", "SecretName", secretName)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "KubernetesError", "Failed to get admin token Secret", token)
+		return ctrl.Result{RequeueAfter: adminTokenErrorRequeueInterval}, err
+	}
+
+	// Tokens replaced in the status, deleted in Garage once the status is saved
+	var staleTokenIDs []string
+	adopted, previousTokenID := r.AdoptSecretToken(token, secret)
+	if adopted {
+		log.Info("Adopting admin token held by the Secret", "TokenID", token.Status.TokenID, "PreviousTokenID", previousTokenID)
+		if previousTokenID != "" {
+			staleTokenIDs = append(staleTokenIDs, previousTokenID)
+		}
+	}
+
+	// Fetch the current token in Garage, if any
+	var info *garage.GetAdminTokenInfoResponse
+	if token.Status.TokenID != "" {
+		res, resp, err := garageClient.AdminAPITokenAPI.GetAdminTokenInfo(apiCtx).Id(token.Status.TokenID).Execute()
+		if err != nil && (resp == nil || resp.StatusCode != 404) {
+			log.Error(err, "Failed to get admin token info", "TokenID", token.Status.TokenID)
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when retrieving admin token from Garage", token)
+			return ctrl.Result{RequeueAfter: adminTokenErrorRequeueInterval}, err
+		}
+		if err == nil {
+			info = res
+		}
+	}
+	if adopted && info != nil {
+		if created := info.Created.Get(); created != nil {
+			token.Status.CreatedAt = &metav1.Time{Time: *created}
+		}
+		if expires := info.Expiration.Get(); expires != nil {
+			token.Status.ExpiresAt = &metav1.Time{Time: *expires}
+		}
+	}
+
+	if reason := r.NeedsRotation(token, info, secret, now); reason != "" {
+		// Create the new token first, so the Secret is never left without a valid token
+		created, _, err := garageClient.AdminAPITokenAPI.CreateAdminToken(apiCtx).UpdateAdminTokenRequestBody(body).Execute()
+		if err != nil {
+			log.Error(err, "Failed to create admin token in Garage")
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when creating admin token in Garage", token)
+			return ctrl.Result{RequeueAfter: adminTokenErrorRequeueInterval}, err
+		}
+		if err := r.WriteSecret(ctx, token, secret, created.GetId(), created.SecretToken); err != nil {
+			log.Error(err, "Failed to write admin token Secret", "SecretName", secretName)
+			// Do not leak the token just created, as it can't be retrieved again
+			if err := r.DeleteGarageToken(apiCtx, garageClient, created.GetId()); err != nil {
+				log.Error(err, "Failed to delete unused admin token", "TokenID", created.GetId())
+			}
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "KubernetesError", "Failed to write admin token Secret", token)
+			return ctrl.Result{RequeueAfter: adminTokenErrorRequeueInterval}, err
+		}
+		if info != nil {
+			staleTokenIDs = append(staleTokenIDs, token.Status.TokenID)
+		}
+		log.Info("Created admin token in Garage", "TokenID", created.GetId(), "Reason", reason)
+
+		token.Status.TokenID = created.GetId()
+		token.Status.CreatedAt = &metav1.Time{Time: now}
+		token.Status.ExpiresAt = nil
+		if expiration != nil {
+			token.Status.ExpiresAt = &metav1.Time{Time: *expiration}
+		}
+		token.Status.RotationRequest = token.Annotations[v1.AdminTokenRotateAnnotation]
+	} else if !slices.Equal(info.Scope, token.Spec.Scope) || (token.Spec.Validity == nil && !expirationEqual(info.Expiration.Get(), expiration)) {
+		// Update scope and fixed expiration in place, validity based expirations only change on rotation
+		if token.Spec.Validity != nil {
+			body.Expiration = info.Expiration
+			body.NeverExpires = nil
+		}
+		if _, _, err := garageClient.AdminAPITokenAPI.UpdateAdminToken(apiCtx).Id(token.Status.TokenID).UpdateAdminTokenRequestBody(body).Execute(); err != nil {
+			log.Error(err, "Failed to update admin token in Garage", "TokenID", token.Status.TokenID)
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when updating admin token in Garage", token)
+			return ctrl.Result{RequeueAfter: adminTokenErrorRequeueInterval}, err
+		}
+		if token.Spec.Validity == nil {
+			token.Status.ExpiresAt = nil
+			if expiration != nil {
+				token.Status.ExpiresAt = &metav1.Time{Time: *expiration}
+			}
+		}
+		log.Info("Updated admin token in Garage", "TokenID", token.Status.TokenID)
+	}
+
+	token.Status.Secret = secretName
+	if err := r.UpdateStatus(ctx, metav1.ConditionTrue, "Ready", "Admin token is ready", token); err != nil {
+		// Keep the replaced tokens until the status is saved, the token held by the Secret
+		// being adopted on the next reconciliation
+		return ctrl.Result{RequeueAfter: adminTokenErrorRequeueInterval}, err
+	}
+	for _, tokenID := range staleTokenIDs {
+		if err := r.DeleteGarageToken(apiCtx, garageClient, tokenID); err != nil {
+			log.Error(err, "Failed to delete previous admin token", "TokenID", tokenID)
+		}
+	}
+
+	// Come back in time for the next rotation
+	requeueAfter := adminTokenRequeueInterval
+	if token.Spec.Validity != nil && token.Status.CreatedAt != nil {
+		untilRotation := time.Until(token.Status.CreatedAt.Add(token.Spec.Validity.Duration * 4 / 5))
+		if untilRotation > 0 && untilRotation < requeueAfter {
+			requeueAfter = untilRotation
+		}
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
+
+// expirationEqual compares two optional expiration times.
+func expirationEqual(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return a.Equal(*b)
+}
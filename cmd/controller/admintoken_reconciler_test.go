@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestAdminTokenNeedsRotation(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	secret := &corev1.Secret{Data: map[string][]byte{"token": []byte("secret")}}
+	validToken := func() *v1.GarageS3AdminToken {
+		return &v1.GarageS3AdminToken{
+			Spec: v1.GarageS3AdminTokenSpec{Validity: &metav1.Duration{Duration: 10 * 24 * time.Hour}},
+			Status: v1.GarageS3AdminTokenStatus{
+				TokenID:   "abc",
+				CreatedAt: &metav1.Time{Time: now.Add(-24 * time.Hour)},
+			},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		token   func() *v1.GarageS3AdminToken
+		info    *garage.GetAdminTokenInfoResponse
+		secret  *corev1.Secret
+		rotates bool
+	}{
+		{name: "up to date", token: validToken, info: &garage.GetAdminTokenInfoResponse{}, secret: secret},
+		{name: "missing in Garage", token: validToken, secret: secret, rotates: true},
+		{name: "expired", token: validToken, info: &garage.GetAdminTokenInfoResponse{Expired: true}, secret: secret, rotates: true},
+		{name: "missing Secret", token: validToken, info: &garage.GetAdminTokenInfoResponse{}, rotates: true},
+		{
+			name: "rotation requested",
+			token: func() *v1.GarageS3AdminToken {
+				token := validToken()
+				token.Annotations = map[string]string{v1.AdminTokenRotateAnnotation: "1"}
+				return token
+			},
+			info: &garage.GetAdminTokenInfoResponse{}, secret: secret, rotates: true,
+		},
+		{
+			name: "rotation already handled",
+			token: func() *v1.GarageS3AdminToken {
+				token := validToken()
+				token.Annotations = map[string]string{v1.AdminTokenRotateAnnotation: "1"}
+				token.Status.RotationRequest = "1"
+				return token
+			},
+			info: &garage.GetAdminTokenInfoResponse{}, secret: secret,
+		},
+		{
+			name: "validity mostly elapsed",
+			token: func() *v1.GarageS3AdminToken {
+				token := validToken()
+				token.Status.CreatedAt = &metav1.Time{Time: now.Add(-9 * 24 * time.Hour)}
+				return token
+			},
+			info: &garage.GetAdminTokenInfoResponse{}, secret: secret, rotates: true,
+		},
+	}
+
+	r := &adminTokenReconciler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reason := r.NeedsRotation(tt.token(), tt.info, tt.secret, now)
+			if (reason != "") != tt.rotates {
+				t.Errorf("expected rotation %v, got reason %q", tt.rotates, reason)
+			}
+		})
+	}
+}
+
+func TestGenerateAdminTokenBody(t *testing.T) {
+	now := time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC)
+	r := &adminTokenReconciler{}
+	token := &v1.GarageS3AdminToken{
+		ObjectMeta: metav1.ObjectMeta{Name: "metrics", Namespace: "garage"},
+		Spec: v1.GarageS3AdminTokenSpec{
+			Scope:    []string{"Metrics"},
+			Validity: &metav1.Duration{Duration: time.Hour},
+		},
+	}
+
+	body, expiration, err := r.GenerateTokenBody(token, now)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if *body.Name.Get() != "garage-metrics" || *body.NeverExpires || !expiration.Equal(now.Add(time.Hour)) {
+		t.Errorf("unexpected body: name=%s neverExpires=%v expiration=%v", *body.Name.Get(), *body.NeverExpires, expiration)
+	}
+
+	token.Spec.Validity = nil
+	body, expiration, err = r.GenerateTokenBody(token, now)
+	if err != nil || expiration != nil || !*body.NeverExpires {
+		t.Errorf("expected a never expiring token, got expiration=%v err=%v", expiration, err)
+	}
+
+	token.Spec.Expiration = "tomorrow"
+	if _, _, err := r.GenerateTokenBody(token, now); err == nil {
+		t.Errorf("expected an error for an invalid expiration")
+	}
+}
+
+func TestAdoptSecretToken(t *testing.T) {
+	secretFor := func(tokenID string) *corev1.Secret {
+		return &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Annotations: map[string]string{adminTokenIDAnnotation: tokenID}}}
+	}
+
+	tests := []struct {
+		name             string
+		statusTokenID    string
+		secret           *corev1.Secret
+		expectedAdopted  bool
+		expectedPrevious string
+		expectedTokenID  string
+	}{
+		{name: "recorded token", statusTokenID: "abc", secret: secretFor("abc"), expectedTokenID: "abc"},
+		{name: "missing Secret", statusTokenID: "abc", expectedTokenID: "abc"},
+		{name: "Secret without token ID", statusTokenID: "abc", secret: &corev1.Secret{}, expectedTokenID: "abc"},
+		{name: "rotated token missing in status", statusTokenID: "abc", secret: secretFor("def"), expectedAdopted: true, expectedPrevious: "abc", expectedTokenID: "def"},
+		{name: "created token missing in status", secret: secretFor("def"), expectedAdopted: true, expectedTokenID: "def"},
+	}
+
+	r := &adminTokenReconciler{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			token := &v1.GarageS3AdminToken{Status: v1.GarageS3AdminTokenStatus{TokenID: tt.statusTokenID}}
+			adopted, previous := r.AdoptSecretToken(token, tt.secret)
+			if adopted != tt.expectedAdopted || previous != tt.expectedPrevious {
+				t.Errorf("expected adopted %v replacing %q, got %v replacing %q", tt.expectedAdopted, tt.expectedPrevious, adopted, previous)
+			}
+			if token.Status.TokenID != tt.expectedTokenID {
+				t.Errorf("expected token %q in status, got %q", tt.expectedTokenID, token.Status.TokenID)
+			}
+		})
+	}
+}
+
+func TestAdminTokenReconciler_WriteSecret(t *testing.T) {
+	token := &v1.GarageS3AdminToken{ObjectMeta: metav1.ObjectMeta{Name: "operator", Namespace: "default"}}
+	existing := &corev1.Secret{ObjectMeta: metav1.ObjectMeta{Name: "existing", Namespace: "default"}}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(existing).Build()
+	r := &adminTokenReconciler{Client: c, scheme: scheme}
+
+	// Created and updated Secrets are labeled, to be in the cache of the controller
+	if err := r.WriteSecret(context.Background(), token, nil, "abc", "secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := r.WriteSecret(context.Background(), token, existing, "def", "secret"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for _, name := range []string{r.SecretName(token), "existing"} {
+		secret := &corev1.Secret{}
+		if err := c.Get(context.Background(), client.ObjectKey{Name: name, Namespace: "default"}, secret); err != nil {
+			t.Fatalf("failed to get Secret %s: %v", name, err)
+		}
+		if secret.Labels[adminTokenSecretLabel] != "operator" {
+			t.Errorf("expected Secret %s to be labeled with its token, got %v", name, secret.Labels)
+		}
+	}
+}
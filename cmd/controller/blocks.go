@@ -0,0 +1,185 @@
+package main
+
+import (
+	"context"
+	"sort"
+	"strings"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// Failed resyncs after which a block is reported unrecoverable, unless set in the policy
+	defaultUnrecoverableAfter = 10
+
+	// Unrecoverable blocks reported per node, to keep the instance status small
+	maxReportedBlocks = 20
+)
+
+// blockError is a block which failed to resync, in the wire format of the block API.
+type blockError struct {
+	BlockHash  string `json:"blockHash"`
+	Refcount   int64  `json:"refcount"`
+	ErrorCount int64  `json:"errorCount"`
+}
+
+// blockErrorList is the response of a block error listing on several nodes, in its wire format.
+type blockErrorList struct {
+	Success map[string][]blockError `json:"success"`
+	Error   map[string]string       `json:"error"`
+}
+
+// blockInfo lists the versions referencing a block, in the wire format of the block API.
+type blockInfo struct {
+	Versions []struct {
+		Backlink *struct {
+			Object *struct {
+				BucketID string `json:"bucketId"`
+				Key      string `json:"key"`
+			} `json:"object"`
+		} `json:"backlink"`
+	} `json:"versions"`
+}
+
+// UnrecoverableBlocks returns the blocks still referenced which reached the
+// unrecoverable error count, sorted by hash.
+func UnrecoverableBlocks(errors []blockError, unrecoverableAfter int64) []blockError {
+	blocks := []blockError{}
+	for _, block := range errors {
+		if block.Refcount > 0 && block.ErrorCount >= unrecoverableAfter {
+			blocks = append(blocks, block)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i].BlockHash < blocks[j].BlockHash })
+	return blocks
+}
+
+// RetryableBlocks returns the hashes of the blocks whose resync is worth retrying.
+func RetryableBlocks(errors []blockError, unrecoverableAfter int64) []string {
+	hashes := []string{}
+	for _, block := range errors {
+		if block.ErrorCount < unrecoverableAfter {
+			hashes = append(hashes, block.BlockHash)
+		}
+	}
+	return hashes
+}
+
+// BlocksToPurge returns, per node, the blocks of the purge annotation which are
+// reported unrecoverable. Other hashes are ignored.
+func BlocksToPurge(annotation string, reported []v1.GarageS3NodeBlockErrors) map[string][]string {
+	requested := map[string]bool{}
+	for _, hash := range strings.Split(annotation, ",") {
+		if hash = strings.TrimSpace(hash); hash != "" {
+			requested[hash] = true
+		}
+	}
+	purge := map[string][]string{}
+	for _, node := range reported {
+		for _, block := range node.Unrecoverable {
+			if requested[block.Hash] {
+				purge[node.NodeID] = append(purge[node.NodeID], block.Hash)
+			}
+		}
+	}
+	return purge
+}
+
+// BlockObjects returns the objects referencing a block, as <bucket id>/<key>.
+func BlockObjects(info blockInfo) []string {
+	objects := []string{}
+	for _, version := range info.Versions {
+		if version.Backlink != nil && version.Backlink.Object != nil {
+			objects = append(objects, version.Backlink.Object.BucketID+"/"+version.Backlink.Object.Key)
+		}
+	}
+	return objects
+}
+
+// ReconcileBlockErrors collects the block errors of every node into the instance status
+// and metrics, retries their resync when the policy asks for it, and purges the
+// unrecoverable blocks listed in the purge annotation.
+func (r *instance_reconciler) ReconcileBlockErrors(ctx context.Context, garageClient *garage.APIClient, apiCtx context.Context, instance garageInstance) error {
+	log := log.FromContext(ctx)
+	policy := instance.GetInstanceSpec().BlockErrors
+	unrecoverableAfter := int64(defaultUnrecoverableAfter)
+	if policy != nil && policy.UnrecoverableAfter > 0 {
+		unrecoverableAfter = policy.UnrecoverableAfter
+	}
+
+	res, _, err := garageClient.BlockAPI.ListBlockErrors(apiCtx).Node("*").Execute()
+	if err != nil {
+		return err
+	}
+	list := blockErrorList{}
+	if err := convertJSON(res, &list); err != nil {
+		return err
+	}
+
+	ResetInstanceMetrics(r.kind, instance, blockErrorsGauge, unrecoverableBlocksGauge)
+	reported := []v1.GarageS3NodeBlockErrors{}
+	for nodeID, errors := range list.Success {
+		node := v1.GarageS3NodeBlockErrors{NodeID: nodeID, Count: int64(len(errors))}
+		unrecoverable := UnrecoverableBlocks(errors, unrecoverableAfter)
+		for i, block := range unrecoverable {
+			if i >= maxReportedBlocks {
+				break
+			}
+			reportedBlock := v1.GarageS3UnrecoverableBlock{Hash: block.BlockHash, ErrorCount: block.ErrorCount}
+			info, _, err := garageClient.BlockAPI.GetBlockInfo(apiCtx).Node(nodeID).LocalGetBlockInfoRequest(garage.LocalGetBlockInfoRequest{BlockHash: block.BlockHash}).Execute()
+			if err == nil {
+				infos := map[string]blockInfo{}
+				if err := convertJSON(info.Success, &infos); err != nil {
+					return err
+				}
+				reportedBlock.Objects = BlockObjects(infos[nodeID])
+			}
+			node.Unrecoverable = append(node.Unrecoverable, reportedBlock)
+		}
+
+		if hashes := RetryableBlocks(errors, unrecoverableAfter); policy != nil && policy.AutoRetry && len(hashes) > 0 {
+			request := garage.LocalRetryBlockResyncRequest{}
+			if err := convertJSON(map[string][]string{"blockHashes": hashes}, &request); err != nil {
+				return err
+			}
+			if _, _, err := garageClient.BlockAPI.RetryBlockResync(apiCtx).Node(nodeID).LocalRetryBlockResyncRequest(request).Execute(); err != nil {
+				node.Error = "failed to retry block resync: " + err.Error()
+			} else {
+				log.Info("Retried block resync", "Node", nodeID, "Blocks", len(hashes))
+			}
+		}
+
+		labels := nodeLabels(r.kind, instance, nodeID)
+		blockErrorsGauge.With(labels).Set(float64(node.Count))
+		unrecoverableBlocksGauge.With(labels).Set(float64(len(unrecoverable)))
+		reported = append(reported, node)
+	}
+	for nodeID, message := range list.Error {
+		reported = append(reported, v1.GarageS3NodeBlockErrors{NodeID: nodeID, Error: message})
+	}
+	sort.Slice(reported, func(i, j int) bool { return reported[i].NodeID < reported[j].NodeID })
+	instance.GetInstanceStatus().BlockErrors = reported
+
+	annotation, found := instance.GetAnnotations()[v1.BlockPurgeAnnotation]
+	if !found {
+		return nil
+	}
+	for nodeID, hashes := range BlocksToPurge(annotation, reported) {
+		res, _, err := garageClient.BlockAPI.PurgeBlocks(apiCtx).Node(nodeID).RequestBody(hashes).Execute()
+		if err != nil {
+			return err
+		}
+		purged := multiNodeResponse{}
+		if err := convertJSON(res, &purged); err != nil {
+			return err
+		}
+		if message, failed := purged.Error[nodeID]; failed {
+			log.Info("Failed to purge unrecoverable blocks", "Node", nodeID, "Blocks", hashes, "Error", message)
+			continue
+		}
+		log.Info("Purged unrecoverable blocks", "Node", nodeID, "Blocks", hashes, "Result", string(purged.Success[nodeID]))
+	}
+	return r.RemoveAnnotations(ctx, instance, v1.BlockPurgeAnnotation)
+}
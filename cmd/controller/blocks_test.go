@@ -0,0 +1,58 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+)
+
+func TestUnrecoverableBlocks(t *testing.T) {
+	errors := []blockError{
+		{BlockHash: "c", Refcount: 1, ErrorCount: 12},
+		{BlockHash: "a", Refcount: 2, ErrorCount: 10},
+		{BlockHash: "b", Refcount: 1, ErrorCount: 3},
+		{BlockHash: "d", Refcount: 0, ErrorCount: 15},
+	}
+
+	blocks := UnrecoverableBlocks(errors, 10)
+	if len(blocks) != 2 || blocks[0].BlockHash != "a" || blocks[1].BlockHash != "c" {
+		t.Errorf("unexpected unrecoverable blocks %+v", blocks)
+	}
+	hashes := RetryableBlocks(errors, 10)
+	if len(hashes) != 1 || hashes[0] != "b" {
+		t.Errorf("unexpected retryable blocks %v", hashes)
+	}
+}
+
+func TestBlocksToPurge(t *testing.T) {
+	reported := []v1.GarageS3NodeBlockErrors{
+		{NodeID: "n1", Count: 3, Unrecoverable: []v1.GarageS3UnrecoverableBlock{{Hash: "a"}, {Hash: "b"}}},
+		{NodeID: "n2", Count: 1, Unrecoverable: []v1.GarageS3UnrecoverableBlock{{Hash: "a"}}},
+	}
+
+	purge := BlocksToPurge(" a, c,", reported)
+	if len(purge) != 2 || len(purge["n1"]) != 1 || purge["n1"][0] != "a" || len(purge["n2"]) != 1 {
+		t.Errorf("unexpected blocks to purge %v", purge)
+	}
+	if purge := BlocksToPurge("", reported); len(purge) != 0 {
+		t.Errorf("expected nothing to purge, got %v", purge)
+	}
+}
+
+func TestBlockObjects(t *testing.T) {
+	raw := `{"blockHash": "a", "refcount": 2, "versions": [
+		{"versionId": "v1", "backlink": {"object": {"bucketId": "b1", "key": "photos/cat.jpg"}}},
+		{"versionId": "v2", "backlink": {"upload": {"uploadId": "u1", "bucketId": "b1", "key": "big.iso"}}},
+		{"versionId": "v3"}
+	]}`
+	info := blockInfo{}
+	if err := json.Unmarshal([]byte(raw), &info); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	objects := BlockObjects(info)
+	if len(objects) != 1 || objects[0] != "b1/photos/cat.jpg" {
+		t.Errorf("unexpected objects %v", objects)
+	}
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Zone assigned to nodes when the bootstrap policy doesn't give one.
+const defaultBootstrapZone = "garage"
+
+// BootstrapRoles assigns a role to every node up following the bootstrap policy, and fails
+// when no node gets a capacity. Pods are indexed by name, which is the hostname reported by
+// Garage nodes.
+func BootstrapRoles(policy *v1.GarageS3BootstrapPolicy, nodes []garage.NodeResp, pods map[string]*corev1.Pod) ([]layoutRole, error) {
+	roles := []layoutRole{}
+	storageNodes := 0
+	for _, node := range nodes {
+		if !node.IsUp {
+			continue
+		}
+		hostname := ""
+		if node.Hostname.Get() != nil {
+			hostname = *node.Hostname.Get()
+		}
+		role := layoutRole{
+			ID:   node.Id,
+			Zone: policy.Zone,
+			Tags: []string{},
+		}
+		if hostname != "" {
+			role.Tags = append(role.Tags, hostname)
+		}
+		if role.Zone == "" {
+			role.Zone = defaultBootstrapZone
+		}
+		if policy.Capacity != nil {
+			capacity := policy.Capacity.Value()
+			role.Capacity = &capacity
+		}
+
+		if pod, found := pods[hostname]; found {
+			if zone := pod.Labels[policy.ZoneLabel]; policy.ZoneLabel != "" && zone != "" {
+				role.Zone = zone
+			}
+			if value := pod.Labels[policy.CapacityLabel]; policy.CapacityLabel != "" && value != "" {
+				quantity, err := resource.ParseQuantity(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid capacity %q on pod %s: %w", value, pod.Name, err)
+				}
+				capacity := quantity.Value()
+				role.Capacity = &capacity
+			}
+		}
+		if role.Capacity != nil {
+			storageNodes++
+		}
+		roles = append(roles, role)
+	}
+	if storageNodes == 0 {
+		return nil, fmt.Errorf("no node has a capacity, set the bootstrap capacity or label the Garage pods with the bootstrap capacityLabel")
+	}
+	return roles, nil
+}
+
+// BootstrapLayout stages the first layout of a cluster without layout, applied by
+// ReconcileStagedLayout. It returns a message when the bootstrap is waiting for nodes or approval.
+func (r *instance_reconciler) BootstrapLayout(ctx context.Context, garageClient *garage.APIClient, apiCtx context.Context, instance garageInstance, status *garage.GetClusterStatusResponse) (string, error) {
+	log := log.FromContext(ctx)
+	policy := instance.GetInstanceSpec().Bootstrap
+
+	// Wait for the expected nodes to be up, as a layout can't be changed back cheaply
+	up := 0
+	for _, node := range status.Nodes {
+		if node.IsUp {
+			up++
+		}
+	}
+	expected := len(status.Nodes)
+	if policy.ExpectedNodes != nil {
+		expected = *policy.ExpectedNodes
+	}
+	if up < expected || up == 0 {
+		return fmt.Sprintf("Waiting for nodes to be up before bootstrapping layout (%d/%d)", up, expected), nil
+	}
+
+	namespace := policy.PodNamespace
+	if namespace == "" {
+		namespace = instance.GetAdminTokenSecretNamespace()
+	}
+	pods := map[string]*corev1.Pod{}
+	for _, node := range status.Nodes {
+		if node.Hostname.Get() == nil {
+			continue
+		}
+		pod := &corev1.Pod{}
+		if err := r.Get(ctx, client.ObjectKey{Name: *node.Hostname.Get(), Namespace: namespace}, pod); err != nil {
+			log.Info("No pod found for Garage node, using bootstrap defaults", "Node", node.Id, "Hostname", *node.Hostname.Get())
+			continue
+		}
+		pods[pod.Name] = pod
+	}
+
+	roles, err := BootstrapRoles(policy, status.Nodes, pods)
+	if err != nil {
+		return "", err
+	}
+	changes, err := nodeRoleChanges(roles)
+	if err != nil {
+		return "", err
+	}
+
+	// Staging the same roles again is harmless, so an interrupted bootstrap is simply redone
+	if _, _, err := garageClient.ClusterLayoutAPI.UpdateClusterLayout(apiCtx).UpdateClusterLayoutRequest(garage.UpdateClusterLayoutRequest{Roles: changes}).Execute(); err != nil {
+		return "", fmt.Errorf("failed to stage bootstrap layout: %w", err)
+	}
+	log.Info("Staged bootstrap layout", "Nodes", len(roles))
+	if instance.GetInstanceSpec().RequireLayoutApproval {
+		return fmt.Sprintf("Waiting for approval of bootstrap layout version %d", status.LayoutVersion+1), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestBootstrapRoles(t *testing.T) {
+	capacity := resource.MustParse("1Gi")
+	policy := &v1.GarageS3BootstrapPolicy{
+		ZoneLabel:     "garage.abucquet.com/zone",
+		CapacityLabel: "garage.abucquet.com/capacity",
+		Capacity:      &capacity,
+	}
+	nodeWithHostname := func(id, hostname string) garage.NodeResp {
+		return garage.NodeResp{Id: id, IsUp: true, Hostname: *garage.NewNullableString(&hostname)}
+	}
+	nodes := []garage.NodeResp{
+		nodeWithHostname("a", "garage-0"),
+		nodeWithHostname("b", "garage-1"),
+		nodeWithHostname("c", "garage-2"),
+	}
+	pods := map[string]*corev1.Pod{
+		"garage-0": {ObjectMeta: metav1.ObjectMeta{Name: "garage-0", Labels: map[string]string{
+			"garage.abucquet.com/zone":     "dc1",
+			"garage.abucquet.com/capacity": "10Gi",
+		}}},
+		"garage-1": {ObjectMeta: metav1.ObjectMeta{Name: "garage-1", Labels: map[string]string{
+			"garage.abucquet.com/zone": "dc2",
+		}}},
+	}
+
+	roles, err := BootstrapRoles(policy, nodes, pods)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []struct {
+		zone     string
+		capacity int64
+	}{
+		{"dc1", 10 * 1024 * 1024 * 1024},
+		{"dc2", 1024 * 1024 * 1024},
+		{defaultBootstrapZone, 1024 * 1024 * 1024},
+	}
+	for i, e := range expected {
+		if roles[i].ID != nodes[i].Id || roles[i].Zone != e.zone || *roles[i].Capacity != e.capacity || roles[i].Tags[0] != *nodes[i].Hostname.Get() {
+			t.Errorf("unexpected role %d: %+v", i, roles[i])
+		}
+	}
+
+	// Without default capacity, nodes without capacity label are gateways
+	policy.Capacity = nil
+	roles, err = BootstrapRoles(policy, nodes, pods)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if roles[0].Capacity == nil || roles[1].Capacity != nil {
+		t.Errorf("expected only the first node to have a capacity, got %v and %v", roles[0].Capacity, roles[1].Capacity)
+	}
+
+	// Nodes down get no role, and nodes without hostname no tag
+	nodes[1].IsUp = false
+	nodes[2].Hostname = garage.NullableString{}
+	roles, err = BootstrapRoles(policy, nodes, pods)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(roles) != 2 || roles[1].ID != "c" || len(roles[1].Tags) != 0 {
+		t.Errorf("expected roles for the nodes up only, without empty tag, got %+v", roles)
+	}
+
+	// Without any capacity, the layout would only have gateways
+	delete(pods["garage-0"].Labels, "garage.abucquet.com/capacity")
+	if _, err := BootstrapRoles(policy, nodes, pods); err == nil {
+		t.Errorf("expected an error without storage node")
+	}
+
+	nodes[1].IsUp = true
+	pods["garage-1"].Labels["garage.abucquet.com/capacity"] = "lots"
+	if _, err := BootstrapRoles(policy, nodes, pods); err == nil {
+		t.Errorf("expected an error for an invalid capacity label")
+	}
+}
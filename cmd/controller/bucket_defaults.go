@@ -0,0 +1,145 @@
+package main
+
+import (
+	"strings"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+)
+
+// MergeBucketDefaults merges the instance bucket defaults under the bucket spec.
+// It returns the resulting spec and the origin of each setting.
+func MergeBucketDefaults(defaults *v1.GarageS3BucketDefaults, bucket *v1.GarageS3Bucket) (v1.GarageS3BucketSpec, map[string]string) {
+	spec := bucket.DeepCopy().Spec
+	sources := map[string]string{}
+
+	if defaults == nil {
+		defaults = &v1.GarageS3BucketDefaults{}
+	}
+
+	// Quota, value by value
+	if spec.Quota != nil {
+		if spec.Quota.MaxObjects != nil {
+			sources["quota.maxObjects"] = v1.BucketConfigSourceBucket
+		}
+		if spec.Quota.MaxBytes != nil {
+			sources["quota.maxBytes"] = v1.BucketConfigSourceBucket
+		}
+	}
+	if defaults.Quota != nil {
+		if spec.Quota == nil {
+			spec.Quota = &v1.GarageS3BucketQuota{}
+		}
+		defaultQuota := defaults.Quota.DeepCopy()
+		if spec.Quota.MaxObjects == nil && defaultQuota.MaxObjects != nil {
+			spec.Quota.MaxObjects = defaultQuota.MaxObjects
+			sources["quota.maxObjects"] = v1.BucketConfigSourceInstance
+		}
+		if spec.Quota.MaxBytes == nil && defaultQuota.MaxBytes != nil {
+			spec.Quota.MaxBytes = defaultQuota.MaxBytes
+			sources["quota.maxBytes"] = v1.BucketConfigSourceInstance
+		}
+	}
+
+	// Website access, with documents and route inherited when left empty in the bucket
+	if spec.WebsiteAccess == nil {
+		spec.WebsiteAccess = defaults.WebsiteAccess.DeepCopy()
+		if spec.WebsiteAccess != nil {
+			sources["websiteAccess"] = v1.BucketConfigSourceInstance
+		}
+	} else {
+		sources["websiteAccess"] = v1.BucketConfigSourceBucket
+		if defaults.WebsiteAccess != nil {
+			if spec.WebsiteAccess.IndexDocument == "" && defaults.WebsiteAccess.IndexDocument != "" {
+				spec.WebsiteAccess.IndexDocument = defaults.WebsiteAccess.IndexDocument
+				sources["websiteAccess.indexDocument"] = v1.BucketConfigSourceInstance
+			}
+			if spec.WebsiteAccess.ErrorDocument == "" && defaults.WebsiteAccess.ErrorDocument != "" {
+				spec.WebsiteAccess.ErrorDocument = defaults.WebsiteAccess.ErrorDocument
+				sources["websiteAccess.errorDocument"] = v1.BucketConfigSourceInstance
+			}
+			if spec.WebsiteAccess.Route == nil && defaults.WebsiteAccess.Route != nil {
+				spec.WebsiteAccess.Route = defaults.WebsiteAccess.Route.DeepCopy()
+				sources["websiteAccess.route"] = v1.BucketConfigSourceInstance
+			}
+		}
+	}
+
+	// Aliases, the alias pattern being added to the bucket ones
+	for _, alias := range spec.AdditionalAliases {
+		sources["aliases."+alias] = v1.BucketConfigSourceBucket
+	}
+	if alias := defaultAlias(defaults, bucket); alias != "" {
+		if _, exists := sources["aliases."+alias]; !exists && alias != bucket.Name {
+			spec.AdditionalAliases = append(spec.AdditionalAliases, alias)
+			sources["aliases."+alias] = v1.BucketConfigSourceInstance
+		}
+	}
+
+	// Permissions, the bucket own permission on the read-only key taking precedence
+	for _, p := range spec.Permissions {
+		sources["permissions."+permissionKey(p, bucket.Namespace)] = v1.BucketConfigSourceBucket
+	}
+	if ref := defaults.ReadOnlyAccessKey; ref != nil {
+		readOnly := v1.GarageS3BucketPermission{
+			AccessKeyName:      ref.Name,
+			AccessKeyNamespace: ref.Namespace,
+			Read:               true,
+		}
+		key := "permissions." + permissionKey(readOnly, bucket.Namespace)
+		if _, exists := sources[key]; !exists {
+			spec.Permissions = append(spec.Permissions, readOnly)
+			sources[key] = v1.BucketConfigSourceInstance
+		}
+	}
+
+	return spec, sources
+}
+
+// defaultAlias returns the alias the instance alias pattern gives to the bucket, if any.
+func defaultAlias(defaults *v1.GarageS3BucketDefaults, bucket *v1.GarageS3Bucket) string {
+	if defaults == nil || defaults.AliasPattern == "" {
+		return ""
+	}
+	return strings.NewReplacer("{name}", bucket.Name, "{namespace}", bucket.Namespace).Replace(defaults.AliasPattern)
+}
+
+// defaultPermission returns true when the permission is the one the instance bucket defaults
+// give on their read-only key.
+func defaultPermission(defaults *v1.GarageS3BucketDefaults, p v1.GarageS3BucketPermission, bucketNamespace string) bool {
+	if defaults == nil || defaults.ReadOnlyAccessKey == nil {
+		return false
+	}
+	ref := defaults.ReadOnlyAccessKey
+	readOnly := v1.GarageS3BucketPermission{AccessKeyName: ref.Name, AccessKeyNamespace: ref.Namespace, Read: true}
+	return p == readOnly
+}
+
+// permissionKey identifies the access key of a permission as namespace/name.
+func permissionKey(p v1.GarageS3BucketPermission, bucketNamespace string) string {
+	namespace := p.AccessKeyNamespace
+	if namespace == "" {
+		namespace = bucketNamespace
+	}
+	return namespace + "/" + p.AccessKeyName
+}
+
+// EffectiveBucketConfig builds the effective configuration reported in the bucket status.
+// Quota values set by the tenancy policy are recorded as such.
+func EffectiveBucketConfig(spec v1.GarageS3BucketSpec, quota *v1.GarageS3BucketQuota, sources map[string]string) *v1.GarageS3BucketEffectiveConfig {
+	if quota != nil {
+		if _, exists := sources["quota.maxObjects"]; !exists && quota.MaxObjects != nil {
+			sources["quota.maxObjects"] = v1.BucketConfigSourceTenancyPolicy
+		}
+		if _, exists := sources["quota.maxBytes"]; !exists && quota.MaxBytes != nil {
+			sources["quota.maxBytes"] = v1.BucketConfigSourceTenancyPolicy
+		}
+	}
+	return &v1.GarageS3BucketEffectiveConfig{
+		Quota:         quota.DeepCopy(),
+		WebsiteAccess: spec.WebsiteAccess.DeepCopy(),
+		Aliases:       append([]string{}, spec.AdditionalAliases...),
+		Permissions:   append([]v1.GarageS3BucketPermission{}, spec.Permissions...),
+		CORS:          spec.CORS,
+		Sources:       sources,
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestMergeBucketDefaults(t *testing.T) {
+	defaults := &v1.GarageS3BucketDefaults{
+		Quota: &v1.GarageS3BucketQuota{MaxObjects: int64Ptr(100), MaxBytes: int64Ptr(1000)},
+		WebsiteAccess: &v1.GarageS3WebsiteAccess{
+			Enabled:       true,
+			IndexDocument: "index.html",
+			ErrorDocument: "404.html",
+			Route:         &v1.GarageS3WebsiteRoute{ClusterIssuer: "letsencrypt"},
+		},
+		ReadOnlyAccessKey: &v1.GarageS3AccessKeyRef{Name: "backup", Namespace: "ops"},
+		AliasPattern:      "{namespace}-{name}",
+	}
+	bucket := &v1.GarageS3Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "assets", Namespace: "web"},
+		Spec: v1.GarageS3BucketSpec{
+			Quota:         &v1.GarageS3BucketQuota{MaxBytes: int64Ptr(50)},
+			WebsiteAccess: &v1.GarageS3WebsiteAccess{Enabled: true, IndexDocument: "home.html"},
+			Permissions:   []v1.GarageS3BucketPermission{{AccessKeyName: "app", Read: true, Write: true}},
+		},
+	}
+
+	spec, sources := MergeBucketDefaults(defaults, bucket)
+
+	if *spec.Quota.MaxBytes != 50 || *spec.Quota.MaxObjects != 100 {
+		t.Errorf("unexpected merged quota: objects=%d bytes=%d", *spec.Quota.MaxObjects, *spec.Quota.MaxBytes)
+	}
+	if spec.WebsiteAccess.IndexDocument != "home.html" || spec.WebsiteAccess.ErrorDocument != "404.html" || spec.WebsiteAccess.Route == nil {
+		t.Errorf("unexpected merged website access: %+v", spec.WebsiteAccess)
+	}
+	if len(spec.AdditionalAliases) != 1 || spec.AdditionalAliases[0] != "web-assets" {
+		t.Errorf("unexpected merged aliases: %v", spec.AdditionalAliases)
+	}
+	if len(spec.Permissions) != 2 || spec.Permissions[1].AccessKeyNamespace != "ops" || !spec.Permissions[1].Read || spec.Permissions[1].Write {
+		t.Errorf("unexpected merged permissions: %+v", spec.Permissions)
+	}
+
+	wantSources := map[string]string{
+		"quota.maxBytes":              v1.BucketConfigSourceBucket,
+		"quota.maxObjects":            v1.BucketConfigSourceInstance,
+		"websiteAccess":               v1.BucketConfigSourceBucket,
+		"websiteAccess.errorDocument": v1.BucketConfigSourceInstance,
+		"websiteAccess.route":         v1.BucketConfigSourceInstance,
+		"aliases.web-assets":          v1.BucketConfigSourceInstance,
+		"permissions.web/app":         v1.BucketConfigSourceBucket,
+		"permissions.ops/backup":      v1.BucketConfigSourceInstance,
+	}
+	for key, want := range wantSources {
+		if sources[key] != want {
+			t.Errorf("source of %s: expected %q, got %q", key, want, sources[key])
+		}
+	}
+
+	// The bucket itself must be left untouched
+	if bucket.Spec.Quota.MaxObjects != nil || len(bucket.Spec.Permissions) != 1 {
+		t.Errorf("bucket spec was modified by the merge: %+v", bucket.Spec)
+	}
+}
+
+func TestMergeBucketDefaults_BucketPermissionWins(t *testing.T) {
+	defaults := &v1.GarageS3BucketDefaults{
+		ReadOnlyAccessKey: &v1.GarageS3AccessKeyRef{Name: "backup", Namespace: "web"},
+	}
+	bucket := &v1.GarageS3Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "assets", Namespace: "web"},
+		Spec: v1.GarageS3BucketSpec{
+			Permissions: []v1.GarageS3BucketPermission{{AccessKeyName: "backup", Read: true, Write: true}},
+		},
+	}
+
+	spec, sources := MergeBucketDefaults(defaults, bucket)
+	if len(spec.Permissions) != 1 || !spec.Permissions[0].Write {
+		t.Errorf("expected the bucket permission to be kept as is, got: %+v", spec.Permissions)
+	}
+	if sources["permissions.web/backup"] != v1.BucketConfigSourceBucket {
+		t.Errorf("expected permission source to be the bucket, got %q", sources["permissions.web/backup"])
+	}
+}
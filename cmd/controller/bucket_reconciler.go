@@ -2,14 +2,15 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"time"
 
 	v1 "abucquet.com/garage-s3-operator/api/v1"
 	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
@@ -26,8 +27,8 @@ type AccessKeyPerm struct {
 
 type bucket_reconciler struct {
 	client.Client
-	scheme     *runtime.Scheme
-	kubeClient *kubernetes.Clientset
+	scheme        *runtime.Scheme
+	garageClients GarageClientFactory
 }
 
 const bucketFinalizer = "garage.abucquet.com/bucket-finalizer"
@@ -37,6 +38,8 @@ const (
 	bucketErrorRequeueInterval = 30 * time.Second
 )
 
+const defaultIndexDocument = "index.html"
+
 // Returns the bucket ID if the bucket exists, or empty string if not found
 func (r *bucket_reconciler) BucketExists(apiCtx context.Context, garageClient *garage.APIClient, bucketName string) (string, error) {
 	buckets, _, err := garageClient.BucketAPI.ListBuckets(apiCtx).Execute()
@@ -69,27 +72,47 @@ func (r *bucket_reconciler) CreateBucket(apiCtx context.Context, garageClient *g
 	return info, err
 }
 
-func (r *bucket_reconciler) GetBucketQuota(bucket *v1.GarageS3Bucket) garage.NullableApiBucketQuotas {
-	if bucket.Spec.Quota == nil {
+func (r *bucket_reconciler) GetBucketQuota(bucketQuota *v1.GarageS3BucketQuota) garage.NullableApiBucketQuotas {
+	if bucketQuota == nil {
 		return *garage.NewNullableApiBucketQuotas(nil)
 	}
 
 	quota := garage.ApiBucketQuotas{
-		MaxObjects: *garage.NewNullableInt64(bucket.Spec.Quota.MaxObjects),
-		MaxSize:    *garage.NewNullableInt64(bucket.Spec.Quota.MaxBytes),
+		MaxObjects: *garage.NewNullableInt64(bucketQuota.MaxObjects),
+		MaxSize:    *garage.NewNullableInt64(bucketQuota.MaxBytes),
 	}
 	return *garage.NewNullableApiBucketQuotas(&quota)
 }
 
+// GetBucketWebsiteAccess returns the website access update of the bucket. Website access is
+// disabled when unset, and left to the S3 API when the website has redirections.
 func (r *bucket_reconciler) GetBucketWebsiteAccess(bucket *v1.GarageS3Bucket) garage.NullableUpdateBucketWebsiteAccess {
-	if bucket.Spec.WebsiteAccess == nil {
+	website := bucket.Spec.WebsiteAccess
+	if website == nil || !website.Enabled {
+		wa := garage.UpdateBucketWebsiteAccess{
+			Enabled:       false,
+			IndexDocument: *garage.NewNullableString(nil),
+			ErrorDocument: *garage.NewNullableString(nil),
+		}
+		return *garage.NewNullableUpdateBucketWebsiteAccess(&wa)
+	}
+	if HasWebsiteS3Settings(website) {
 		return *garage.NewNullableUpdateBucketWebsiteAccess(nil)
 	}
 
+	// Index document defaults to index.html, no error document is set when empty
+	indexDocument := website.IndexDocument
+	if indexDocument == "" {
+		indexDocument = defaultIndexDocument
+	}
+	var errorDocument *string
+	if website.ErrorDocument != "" {
+		errorDocument = &website.ErrorDocument
+	}
 	wa := garage.UpdateBucketWebsiteAccess{
-		Enabled:       bucket.Spec.WebsiteAccess.Enabled,
-		IndexDocument: *garage.NewNullableString(&bucket.Spec.WebsiteAccess.IndexDocument),
-		ErrorDocument: *garage.NewNullableString(&bucket.Spec.WebsiteAccess.ErrorDocument),
+		Enabled:       true,
+		IndexDocument: *garage.NewNullableString(&indexDocument),
+		ErrorDocument: *garage.NewNullableString(errorDocument),
 	}
 	return *garage.NewNullableUpdateBucketWebsiteAccess(&wa)
 }
@@ -106,8 +129,8 @@ func (r *bucket_reconciler) GetAccessKeyForName(name string, namespace string) (
 	}
 	// Retrieve the AccessKeyID from the associated secret
 	secretName := accessKey.Status.Secret
-	secret, err := r.kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
-	if err != nil {
+	secret := &corev1.Secret{}
+	if err := r.Get(context.TODO(), client.ObjectKey{Name: secretName, Namespace: namespace}, secret); err != nil {
 		return "", err
 	}
 	// AccessKeyID is stored in "AWS_ACCESS_KEY" key in the secret data
@@ -119,7 +142,11 @@ func (r *bucket_reconciler) GetAllBucketPermInfo(bucket *v1.GarageS3Bucket) ([]A
 	var perms []AccessKeyPerm
 	oneNotFoundErr := false
 	for _, p := range bucket.Spec.Permissions {
-		accessKeyID, err := r.GetAccessKeyForName(p.AccessKeyName, bucket.Namespace)
+		namespace := p.AccessKeyNamespace
+		if namespace == "" {
+			namespace = bucket.Namespace
+		}
+		accessKeyID, err := r.GetAccessKeyForName(p.AccessKeyName, namespace)
 		if err != nil {
 			oneNotFoundErr = true
 			continue
@@ -240,8 +267,12 @@ func (r *bucket_reconciler) GetBucketPermissionChangeRequests(bucket *v1.GarageS
 		}
 	}
 
-	// Run through existing permissions to find any to remove
+	// Run through existing permissions to find any to remove, the operator key being
+	// managed with the S3 settings
 	for _, existingPerm := range bucketInfo.Keys {
+		if existingPerm.Name == operatorKeyName {
+			continue
+		}
 		found := false
 		for _, ak := range accessKeyInfos {
 			if ak.AccessKeyID == existingPerm.AccessKeyId {
@@ -263,7 +294,8 @@ func (r *bucket_reconciler) GetBucketPermissionChangeRequests(bucket *v1.GarageS
 	return allowRequests, denyRequests, err
 }
 
-func (r *bucket_reconciler) UpdateStatus(ctx context.Context, status metav1.ConditionStatus, reason string, message string, instance *v1.GarageS3Bucket) {
+// UpdateStatus sets the Ready condition and writes the status, returning the error of the write.
+func (r *bucket_reconciler) UpdateStatus(ctx context.Context, status metav1.ConditionStatus, reason string, message string, instance *v1.GarageS3Bucket) error {
 
 	cond := metav1.Condition{
 		Type:    "Ready",
@@ -293,44 +325,20 @@ func (r *bucket_reconciler) UpdateStatus(ctx context.Context, status metav1.Cond
 
 	if err := r.Status().Update(ctx, instance); err != nil {
 		log.Log.Error(err, "Failed to update GarageS3Bucket status")
+		return err
 	}
+	return nil
 }
 
 func (r *bucket_reconciler) BucketCleanup(ctx context.Context, bucket *v1.GarageS3Bucket) error {
 
 	// Being deleted: perform finalization then remove finalizer
 	if controllerutil.ContainsFinalizer(bucket, bucketFinalizer) {
-		// Try to delete the bucket on Garage S3
-		instanceRef := bucket.Spec.InstanceRef
-		instance := &v1.GarageS3Instance{}
-		if err := r.Get(ctx, client.ObjectKey{
-			Name:      instanceRef.Name,
-			Namespace: instanceRef.Namespace,
-		}, instance); err != nil {
-			// If associated instance can't be found, log and continue to remove finalizer
-			return fmt.Errorf("failed to get associated GarageS3Instance while finalizing; will remove finalizer to avoid blocking deletion: %w", err)
-		} else {
-			garageClient, apiCtx, err := CreateGarageClient(r.kubeClient, instance)
-			if err != nil {
-				return fmt.Errorf("failed to create Garage S3 client while finalizing; requeueing: %w", err)
-			}
-			// Check bucket presence and delete if exists
-			bucketID, err := r.BucketExists(apiCtx, garageClient, bucket.Name)
-			if err != nil {
-				return fmt.Errorf("failed to check bucket existence while finalizing; requeueing: %w", err)
-			}
-			if bucketID != "" {
-				resp, err := garageClient.BucketAPI.DeleteBucket(apiCtx).Id(bucketID).Execute()
-				if err != nil {
-					switch resp.StatusCode {
-					case 400:
-						return fmt.Errorf("failed to delete bucket in Garage S3 during finalization; requeueing: %w", err)
-					case 404:
-						// Ignore bucket not found
-					default:
-						return fmt.Errorf("failed to delete bucket in Garage S3 during finalization; requeueing: %w", err)
-					}
-				}
+		// Only the Garage bucket provisioned for this resource is deleted, never one found by
+		// name, so that nothing is deleted for buckets refused by the tenancy policy
+		if bucket.Status.BucketID != "" {
+			if err := r.DeleteGarageBucket(ctx, bucket); err != nil {
+				return err
 			}
 		}
 		// Remove finalizer so Kubernetes can delete the object
@@ -342,6 +350,42 @@ func (r *bucket_reconciler) BucketCleanup(ctx context.Context, bucket *v1.Garage
 	return nil
 }
 
+// DeleteGarageBucket deletes the Garage bucket recorded in the status of the bucket, ignoring
+// buckets already deleted.
+func (r *bucket_reconciler) DeleteGarageBucket(ctx context.Context, bucket *v1.GarageS3Bucket) error {
+	instance, err := GetInstanceForRef(ctx, r, bucket.Spec.InstanceRef)
+	if err != nil {
+		return fmt.Errorf("failed to get associated GarageS3Instance while finalizing: %w", err)
+	}
+	garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
+	if err != nil {
+		return fmt.Errorf("failed to create Garage S3 client while finalizing; requeueing: %w", err)
+	}
+	resp, err := garageClient.BucketAPI.DeleteBucket(apiCtx).Id(bucket.Status.BucketID).Execute()
+	if err != nil && (resp == nil || resp.StatusCode != 404) {
+		return fmt.Errorf("failed to delete bucket in Garage S3 during finalization; requeueing: %w", err)
+	}
+	return nil
+}
+
+// BucketClaimedBy returns the namespace/name of the other GarageS3Bucket which provisioned the
+// Garage bucket, or an empty string, so that a bucket is never taken over by name.
+func (r *bucket_reconciler) BucketClaimedBy(ctx context.Context, bucket *v1.GarageS3Bucket, bucketID string) (string, error) {
+	buckets := &v1.GarageS3BucketList{}
+	if err := r.List(ctx, buckets); err != nil {
+		return "", err
+	}
+	for _, other := range buckets.Items {
+		if other.Namespace == bucket.Namespace && other.Name == bucket.Name {
+			continue
+		}
+		if other.Status.BucketID == bucketID {
+			return other.Namespace + "/" + other.Name, nil
+		}
+	}
+	return "", nil
+}
+
 func (r *bucket_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	log := log.FromContext(ctx).WithValues("GarageS3Bucket", req.NamespacedName)
 
@@ -376,16 +420,33 @@ func (r *bucket_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	// Create client to Garage S3 instance
 	// Fetch the associated GarageS3Instance and create Garage Client
 	instanceRef := bucket.Spec.InstanceRef
-	instance := &v1.GarageS3Instance{}
-	if err := r.Get(ctx, client.ObjectKey{
-		Name:      instanceRef.Name,
-		Namespace: instanceRef.Namespace,
-	}, instance); err != nil {
+	instance, err := GetInstanceForRef(ctx, r, instanceRef)
+	if err != nil {
 		log.Error(err, "Failed to get associated GarageS3Instance", "InstanceRef", instanceRef)
 		r.UpdateStatus(ctx, metav1.ConditionFalse, "InstanceNotFound", "Associated GarageS3Instance not found", bucket)
 		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
 	}
-	garageClient, apiCtx, err := CreateGarageClient(r.kubeClient, instance)
+
+	// Merge the instance bucket defaults under the bucket spec
+	desired := bucket.DeepCopy()
+	var sources map[string]string
+	desired.Spec, sources = MergeBucketDefaults(instance.GetInstanceSpec().BucketDefaults, bucket)
+
+	// Enforce the tenancy policy of the instance, and get the quota to apply
+	quota, err := CheckBucketPolicy(ctx, r, instance, desired)
+	if err != nil {
+		var violation *policyViolation
+		if errors.As(err, &violation) {
+			log.Info("Bucket refused by instance tenancy policy", "Reason", err.Error())
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "PolicyViolation", err.Error(), bucket)
+			return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, nil
+		}
+		log.Error(err, "Failed to check instance tenancy policy", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "KubernetesError", "Failed to check instance tenancy policy", bucket)
+		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
+	}
+	bucket.Status.EffectiveConfig = EffectiveBucketConfig(desired.Spec, quota, sources)
+	garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
 	if err != nil {
 		log.Error(err, "Failed to create Garage S3 client for associated instance", "InstanceRef", instanceRef)
 		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageClientError", "Could not create Garage S3 client", bucket)
@@ -400,6 +461,21 @@ func (r *bucket_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
 	}
 
+	// Refuse to take over a Garage bucket provisioned for another resource
+	if bucketID != "" && bucketID != bucket.Status.BucketID {
+		owner, err := r.BucketClaimedBy(ctx, bucket, bucketID)
+		if err != nil {
+			log.Error(err, "Failed to check the owner of the Garage bucket", "BucketName", bucket.Name)
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "KubernetesError", "Failed to check the owner of the Garage bucket", bucket)
+			return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
+		}
+		if owner != "" {
+			log.Info("Garage bucket already provisioned for another GarageS3Bucket", "BucketName", bucket.Name, "Owner", owner)
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "BucketConflict", "Garage bucket already provisioned for GarageS3Bucket "+owner, bucket)
+			return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, nil
+		}
+	}
+
 	// Create bucket if not exists
 	var bucketInfo *garage.GetBucketInfoResponse = nil
 	if bucketID == "" {
@@ -419,16 +495,28 @@ func (r *bucket_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 			return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
 		}
 	}
+	// Record the bucket before anything else, deletion and claim checks rely on it
+	if bucket.Status.BucketID != bucketInfo.Id {
+		bucket.Status.BucketID = bucketInfo.Id
+		if err := r.Status().Update(ctx, bucket); err != nil {
+			log.Error(err, "Failed to record the Garage bucket in status", "BucketName", bucket.Name, "BucketID", bucketInfo.Id)
+			return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
+		}
+	}
 
 	// Update Bucket parameters
 	updateBucketReq := garage.UpdateBucketRequestBody{
-		Quotas:        r.GetBucketQuota(bucket),
-		WebsiteAccess: r.GetBucketWebsiteAccess(bucket),
+		Quotas:        r.GetBucketQuota(quota),
+		WebsiteAccess: r.GetBucketWebsiteAccess(desired),
+	}
+	if _, _, err := garageClient.BucketAPI.UpdateBucket(apiCtx).Id(bucketInfo.Id).UpdateBucketRequestBody(updateBucketReq).Execute(); err != nil {
+		log.Error(err, "Failed to update bucket in Garage S3", "BucketName", bucket.Name, "BucketID", bucketInfo.Id)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when updating bucket quota and website access in Garage S3", bucket)
+		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
 	}
-	garageClient.BucketAPI.UpdateBucket(apiCtx).Id(bucketInfo.Id).UpdateBucketRequestBody(updateBucketReq).Execute()
 
 	// Update Bucket aliases
-	aliases := bucket.Spec.AdditionalAliases
+	aliases := desired.Spec.AdditionalAliases
 	aliases = append(aliases, bucket.Name) // Ensure main alias is present
 	for _, alias := range bucketInfo.GlobalAliases {
 		// Remove aliases that are not in the spec
@@ -476,7 +564,7 @@ func (r *bucket_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 	}
 
 	// Handle permissions
-	allowReq, denyReq, err := r.GetBucketPermissionChangeRequests(bucket, bucketInfo)
+	allowReq, denyReq, err := r.GetBucketPermissionChangeRequests(desired, bucketInfo)
 	// In case of error, some AccessKeys were not found, but we can still process the others
 	// error means it is needed to requeue later
 	for _, req := range allowReq {
@@ -496,12 +584,59 @@ func (r *bucket_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ct
 		}
 	}
 
+	// Apply the settings only exposed by the S3 API
+	lifecycle, settingsErr := r.ReconcileS3Settings(ctx, apiCtx, garageClient, instance, desired, bucketInfo)
+	if settingsErr != nil {
+		if errors.Is(settingsErr, errNoS3API) {
+			log.Info("Bucket settings need the S3 API of the instance", "InstanceRef", instanceRef)
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "S3APIMissing", "CORS, lifecycle and website redirection rules need the s3 API of the instance to be set", bucket)
+			return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, nil
+		}
+		log.Error(settingsErr, "Failed to apply bucket settings through the S3 API", "BucketName", bucket.Name)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "S3APIError", "Error when applying bucket settings through the S3 API", bucket)
+		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, settingsErr
+	}
+	bucket.Status.Lifecycle = nil
+	if len(lifecycle) > 0 {
+		bucket.Status.Lifecycle = &v1.GarageS3BucketLifecycle{Rules: lifecycle}
+	}
+
+	// Expose the website domains through Ingresses or HTTPRoutes
+	if err := r.ReconcileWebsiteRoutes(ctx, instance, desired); err != nil {
+		if errors.Is(err, errNoWebService) {
+			log.Info("Website routes need the web Service of the instance", "InstanceRef", instanceRef)
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "WebServiceMissing", "Website routes need the web Service of the instance to be set", bucket)
+			return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, nil
+		}
+		var violation *policyViolation
+		if errors.As(err, &violation) {
+			log.Info("Website route refused by instance tenancy policy", "Reason", err.Error())
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "PolicyViolation", err.Error(), bucket)
+			return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, nil
+		}
+		log.Error(err, "Failed to apply the website routes", "BucketName", bucket.Name)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "KubernetesError", "Error when applying the website Ingresses or HTTPRoutes", bucket)
+		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
+	}
+
+	// Check that Garage serves the website on its domains
+	// (the permission error is kept for the end of the reconciliation)
+	websiteDomains, checkErr := r.CheckWebsiteDomains(apiCtx, garageClient, desired)
+	if checkErr != nil {
+		log.Error(checkErr, "Failed to check the website domains in Garage S3", "BucketName", bucket.Name)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when checking the website domains in Garage S3", bucket)
+		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, checkErr
+	}
+	bucket.Status.WebsiteDomains = websiteDomains
+
 	if err != nil {
 		log.Error(err, "One or more access keys not found for bucket permissions, will retry", "BucketName", bucket.Name)
 		r.UpdateStatus(ctx, metav1.ConditionFalse, "PermissionsIncomplete", "One or more access keys not found for bucket permissions", bucket)
 		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
 	}
 
-	r.UpdateStatus(ctx, metav1.ConditionTrue, "Ready", "Bucket is ready", bucket)
+	if err := r.UpdateStatus(ctx, metav1.ConditionTrue, "Ready", "Bucket is ready", bucket); err != nil {
+		return ctrl.Result{RequeueAfter: bucketErrorRequeueInterval}, err
+	}
 	return ctrl.Result{RequeueAfter: bucketRequeueInterval}, nil
 }
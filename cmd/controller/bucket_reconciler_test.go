@@ -2,13 +2,19 @@ package main
 
 import (
 	"context"
+	"net/http"
+	"slices"
 	"testing"
 	"time"
 
 	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"abucquet.com/garage-s3-operator/internal/garagefake"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/types"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/client/fake"
 )
 
@@ -65,9 +71,8 @@ func TestBucketReconciler_RequeuesAfterTransientError(t *testing.T) {
 func TestBucketReconciler_RequeuesOnSuccess(t *testing.T) {
 	// Even after a fully successful reconciliation the bucket reconciler should
 	// schedule a periodic requeue so it can recover from transient failures and
-	// detect drift. We cannot easily simulate a full successful reconciliation
-	// without a real Garage API, but we can verify the constant is defined with
-	// a sensible value.
+	// detect drift. TestBucketReconciler_FakeGarage checks the requeue of a
+	// successful reconciliation, here we verify the constant has a sensible value.
 
 	if bucketRequeueInterval <= 0 {
 		t.Fatal("bucketRequeueInterval must be positive for periodic requeue")
@@ -105,3 +110,536 @@ func TestBucketReconciler_NotFoundDoesNotRequeue(t *testing.T) {
 		t.Errorf("expected zero result for not-found bucket, got: %+v", result)
 	}
 }
+
+func newTestBucket(env *garageTestEnv, spec v1.GarageS3BucketSpec) *v1.GarageS3Bucket {
+	spec.InstanceRef = env.instanceRef()
+	return &v1.GarageS3Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "test-bucket", Namespace: "default"},
+		Spec:       spec,
+	}
+}
+
+// addTestAccessKey adds an access key to Garage, and returns its GarageS3AccessKey and Secret.
+func addTestAccessKey(env *garageTestEnv, name string) []client.Object {
+	key := env.garage.AddKey(name)
+	secretName := name + "-gs3ak"
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: secretName, Namespace: "default"},
+		Data:       map[string][]byte{"AWS_ACCESS_KEY": []byte(key.ID), "AWS_SECRET_KEY": []byte(key.Secret)},
+	}
+	ak := &v1.GarageS3AccessKey{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1.GarageS3AccessKeySpec{InstanceRef: env.instanceRef()},
+		Status:     v1.GarageS3AccessKeyStatus{Secret: secretName},
+	}
+	return []client.Object{ak, secret}
+}
+
+// equalCORSRules compares CORS rules of the fake, an empty list being equal to a missing one.
+func equalCORSRules(a garagefake.CORSRule, b garagefake.CORSRule) bool {
+	return equality.Semantic.DeepEqual(a, b)
+}
+
+func TestBucketReconciler_FakeGarage(t *testing.T) {
+	maxObjects := int64(100)
+	maxAge := int32(3600)
+	corsRule := v1.GarageS3CORSRule{
+		AllowedOrigins: []string{"https://app.example.com"},
+		AllowedMethods: []string{"GET", "HEAD"},
+		ExposeHeaders:  []string{"ETag"},
+		MaxAgeSeconds:  &maxAge,
+	}
+	fakeCORSRule := garagefake.CORSRule{
+		AllowedOrigins: corsRule.AllowedOrigins,
+		AllowedMethods: corsRule.AllowedMethods,
+		ExposeHeaders:  corsRule.ExposeHeaders,
+		MaxAgeSeconds:  &maxAge,
+	}
+	expirationDays, abortDays := int32(30), int32(7)
+	lifecycleRules := []v1.GarageS3LifecycleRule{
+		{ID: "logs", Prefix: "logs/", ExpirationDays: &expirationDays, AbortIncompleteMultipartUploadDays: &abortDays},
+		{ExpirationDate: "2030-01-01"},
+	}
+	redirectCode := int32(302)
+	routingRule := v1.GarageS3WebsiteRoutingRule{
+		Condition: &v1.GarageS3WebsiteRoutingCondition{KeyPrefixEquals: "docs/"},
+		Redirect:  v1.GarageS3WebsiteRoutingRedirect{HttpRedirectCode: &redirectCode, ReplaceKeyPrefixWith: "documentation/"},
+	}
+	fakeRoutingRule := garagefake.RoutingRule{
+		Condition: &garagefake.RoutingCondition{KeyPrefixEquals: "docs/"},
+		Redirect:  garagefake.RoutingRedirect{HttpRedirectCode: "302", ReplaceKeyPrefixWith: "documentation/"},
+	}
+	logsPrefix, noPrefix := "logs/", ""
+	fakeLifecycleRules := []garagefake.LifecycleRule{
+		{ID: "logs", Status: "Enabled", Prefix: &logsPrefix, ExpirationDays: &expirationDays, AbortIncompleteMultipartUploadDays: &abortDays},
+		{Status: "Enabled", Prefix: &noPrefix, ExpirationDate: "2030-01-01T00:00:00Z"},
+	}
+	tests := []struct {
+		name            string
+		spec            v1.GarageS3BucketSpec
+		setup           func(t *testing.T, env *garageTestEnv) []client.Object
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  string
+		expectedRequeue time.Duration
+		expectError     bool
+		check           func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket)
+		checkStatus     func(t *testing.T, status v1.GarageS3BucketStatus)
+	}{
+		{
+			name: "creates the bucket",
+			spec: v1.GarageS3BucketSpec{
+				Quota:         &v1.GarageS3BucketQuota{MaxObjects: &maxObjects},
+				WebsiteAccess: &v1.GarageS3WebsiteAccess{Enabled: true},
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if bucket.MaxObjects == nil || *bucket.MaxObjects != maxObjects {
+					t.Errorf("expected quota of %d objects, got %v", maxObjects, bucket.MaxObjects)
+				}
+				if !bucket.WebsiteAccess || bucket.IndexDocument != defaultIndexDocument {
+					t.Errorf("expected website access with %s, got %v/%q", defaultIndexDocument, bucket.WebsiteAccess, bucket.IndexDocument)
+				}
+			},
+		},
+		{
+			name: "syncs the aliases of an existing bucket",
+			spec: v1.GarageS3BucketSpec{AdditionalAliases: []string{"extra"}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				env.garage.AddBucket("test-bucket", "stale")
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			checkStatus: func(t *testing.T, status v1.GarageS3BucketStatus) {
+				if status.BucketID == "" {
+					t.Error("expected the ID of the adopted bucket in status")
+				}
+			},
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				aliases := slices.Sorted(slices.Values(bucket.GlobalAliases))
+				if !slices.Equal(aliases, []string{"extra", "test-bucket"}) {
+					t.Errorf("expected aliases [extra test-bucket], got %v", aliases)
+				}
+				if env.garage.Calls("CreateBucket") != 0 {
+					t.Error("expected the existing bucket to be reused")
+				}
+			},
+		},
+		{
+			name: "refuses a bucket provisioned for another resource",
+			spec: v1.GarageS3BucketSpec{AdditionalAliases: []string{"extra"}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				other := &v1.GarageS3Bucket{
+					ObjectMeta: metav1.ObjectMeta{Name: "test-bucket", Namespace: "other-tenant"},
+					Status:     v1.GarageS3BucketStatus{BucketID: bucket.ID},
+				}
+				return []client.Object{other}
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "BucketConflict",
+			expectedRequeue: bucketErrorRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if len(bucket.GlobalAliases) != 1 {
+					t.Errorf("expected the bucket of the other resource to be left untouched, got aliases %v", bucket.GlobalAliases)
+				}
+			},
+			checkStatus: func(t *testing.T, status v1.GarageS3BucketStatus) {
+				if status.BucketID != "" {
+					t.Errorf("expected no bucket ID, got %s", status.BucketID)
+				}
+			},
+		},
+		{
+			name: "grants and revokes permissions",
+			spec: v1.GarageS3BucketSpec{Permissions: []v1.GarageS3BucketPermission{{AccessKeyName: "app", Read: true, Write: true}}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				objs := addTestAccessKey(env, "app")
+				stale := env.garage.AddKey("stale")
+				env.garage.Allow(bucket.ID, stale.ID, garagefake.Permission{Read: true})
+				return objs
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				app, _ := env.garage.KeyByName("app")
+				if len(bucket.Permissions) != 1 || bucket.Permissions[app.ID] != (garagefake.Permission{Read: true, Write: true}) {
+					t.Errorf("expected read and write for the app key only, got %+v", bucket.Permissions)
+				}
+			},
+		},
+		{
+			name:            "applies CORS rules",
+			spec:            v1.GarageS3BucketSpec{CORS: []v1.GarageS3CORSRule{corsRule}},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if !slices.EqualFunc(bucket.CORSRules, []garagefake.CORSRule{fakeCORSRule}, equalCORSRules) {
+					t.Errorf("expected CORS rules %+v, got %+v", fakeCORSRule, bucket.CORSRules)
+				}
+				operator, found := env.garage.KeyByName(operatorKeyName)
+				if !found || !bucket.Permissions[operator.ID].Owner {
+					t.Errorf("expected the operator key to own the bucket, got %+v", bucket.Permissions)
+				}
+			},
+		},
+		{
+			name: "replaces drifted CORS rules",
+			spec: v1.GarageS3BucketSpec{CORS: []v1.GarageS3CORSRule{corsRule}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				operator := env.garage.AddKey(operatorKeyName)
+				env.garage.Allow(bucket.ID, operator.ID, garagefake.Permission{Owner: true})
+				env.garage.SetCORSRules(bucket.ID, garagefake.CORSRule{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"PUT"}})
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if !slices.EqualFunc(bucket.CORSRules, []garagefake.CORSRule{fakeCORSRule}, equalCORSRules) {
+					t.Errorf("expected CORS rules %+v, got %+v", fakeCORSRule, bucket.CORSRules)
+				}
+				if env.garage.KeyCount() != 1 {
+					t.Errorf("expected the operator key to be reused, got %d keys", env.garage.KeyCount())
+				}
+			},
+		},
+		{
+			name: "keeps CORS rules in sync",
+			spec: v1.GarageS3BucketSpec{CORS: []v1.GarageS3CORSRule{corsRule}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				operator := env.garage.AddKey(operatorKeyName)
+				env.garage.Allow(bucket.ID, operator.ID, garagefake.Permission{Owner: true})
+				env.garage.SetCORSRules(bucket.ID, fakeCORSRule)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if calls := env.garage.Calls("PutBucketCors"); calls != 0 {
+					t.Errorf("expected no PutBucketCors call, got %d", calls)
+				}
+			},
+		},
+		{
+			name: "removes CORS rules",
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				operator := env.garage.AddKey(operatorKeyName)
+				env.garage.Allow(bucket.ID, operator.ID, garagefake.Permission{Owner: true})
+				env.garage.SetCORSRules(bucket.ID, fakeCORSRule)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if len(bucket.CORSRules) != 0 {
+					t.Errorf("expected no CORS rule, got %+v", bucket.CORSRules)
+				}
+				if len(bucket.Permissions) != 0 {
+					t.Errorf("expected the operator key to be revoked, got %+v", bucket.Permissions)
+				}
+			},
+		},
+		{
+			name: "CORS rules without S3 API",
+			spec: v1.GarageS3BucketSpec{CORS: []v1.GarageS3CORSRule{corsRule}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				env.instance.Spec.S3 = nil
+				return nil
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "S3APIMissing",
+			expectedRequeue: bucketErrorRequeueInterval,
+		},
+		{
+			name: "CORS failure",
+			spec: v1.GarageS3BucketSpec{CORS: []v1.GarageS3CORSRule{corsRule}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				env.garage.InjectFault("PutBucketCors", http.StatusBadRequest, 1)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "S3APIError",
+			expectedRequeue: bucketErrorRequeueInterval,
+			expectError:     true,
+		},
+		{
+			name:            "applies lifecycle rules",
+			spec:            v1.GarageS3BucketSpec{Lifecycle: &v1.GarageS3BucketLifecycle{Rules: lifecycleRules}},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if !equality.Semantic.DeepEqual(bucket.LifecycleRules, fakeLifecycleRules) {
+					t.Errorf("expected lifecycle rules %+v, got %+v", fakeLifecycleRules, bucket.LifecycleRules)
+				}
+				// The status reports the rules read back from Garage after they were put
+				if calls := env.garage.Calls("GetBucketLifecycleConfiguration"); calls != 2 {
+					t.Errorf("expected the lifecycle rules to be read back, got %d reads", calls)
+				}
+			},
+			checkStatus: func(t *testing.T, status v1.GarageS3BucketStatus) {
+				if status.Lifecycle == nil || !equality.Semantic.DeepEqual(status.Lifecycle.Rules, lifecycleRules) {
+					t.Errorf("expected the applied lifecycle rules in status, got %+v", status.Lifecycle)
+				}
+			},
+		},
+		{
+			name: "enables disabled lifecycle rules",
+			spec: v1.GarageS3BucketSpec{Lifecycle: &v1.GarageS3BucketLifecycle{Rules: lifecycleRules}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				operator := env.garage.AddKey(operatorKeyName)
+				env.garage.Allow(bucket.ID, operator.ID, garagefake.Permission{Owner: true})
+				disabled := slices.Clone(fakeLifecycleRules)
+				disabled[0].Status = "Disabled"
+				env.garage.SetLifecycleRules(bucket.ID, disabled...)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if !equality.Semantic.DeepEqual(bucket.LifecycleRules, fakeLifecycleRules) {
+					t.Errorf("expected lifecycle rules %+v, got %+v", fakeLifecycleRules, bucket.LifecycleRules)
+				}
+			},
+		},
+		{
+			name: "removes lifecycle rules",
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				operator := env.garage.AddKey(operatorKeyName)
+				env.garage.Allow(bucket.ID, operator.ID, garagefake.Permission{Owner: true})
+				env.garage.SetLifecycleRules(bucket.ID, fakeLifecycleRules...)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if len(bucket.LifecycleRules) != 0 {
+					t.Errorf("expected no lifecycle rule, got %+v", bucket.LifecycleRules)
+				}
+			},
+			checkStatus: func(t *testing.T, status v1.GarageS3BucketStatus) {
+				if status.Lifecycle != nil {
+					t.Errorf("expected no lifecycle rule in status, got %+v", status.Lifecycle)
+				}
+			},
+		},
+		{
+			name: "disables a removed website",
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				env.garage.SetWebsiteAccess(bucket.ID, "index.html")
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if bucket.WebsiteAccess || bucket.IndexDocument != "" {
+					t.Errorf("expected website access to be disabled, got %v/%q", bucket.WebsiteAccess, bucket.IndexDocument)
+				}
+			},
+		},
+		{
+			name:            "applies website routing rules",
+			spec:            v1.GarageS3BucketSpec{WebsiteAccess: &v1.GarageS3WebsiteAccess{Enabled: true, RoutingRules: []v1.GarageS3WebsiteRoutingRule{routingRule}}},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if !bucket.WebsiteAccess || bucket.IndexDocument != defaultIndexDocument {
+					t.Errorf("expected website access with %s, got %v/%q", defaultIndexDocument, bucket.WebsiteAccess, bucket.IndexDocument)
+				}
+				if !equality.Semantic.DeepEqual(bucket.WebsiteRoutingRules, []garagefake.RoutingRule{fakeRoutingRule}) {
+					t.Errorf("expected routing rules %+v, got %+v", fakeRoutingRule, bucket.WebsiteRoutingRules)
+				}
+			},
+		},
+		{
+			name: "applies a website redirection",
+			spec: v1.GarageS3BucketSpec{WebsiteAccess: &v1.GarageS3WebsiteAccess{
+				Enabled:               true,
+				RedirectAllRequestsTo: &v1.GarageS3WebsiteRedirectAll{HostName: "www.example.com", Protocol: "https"},
+			}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				env.garage.SetWebsiteAccess(bucket.ID, "index.html")
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				expected := &garagefake.WebsiteRedirectAll{HostName: "www.example.com", Protocol: "https"}
+				if !bucket.WebsiteAccess || !equality.Semantic.DeepEqual(bucket.WebsiteRedirectAll, expected) || bucket.IndexDocument != "" {
+					t.Errorf("expected a redirection to %+v only, got %+v", expected, bucket)
+				}
+			},
+		},
+		{
+			name: "removes website routing rules",
+			spec: v1.GarageS3BucketSpec{WebsiteAccess: &v1.GarageS3WebsiteAccess{Enabled: true}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				bucket := env.garage.AddBucket("test-bucket")
+				operator := env.garage.AddKey(operatorKeyName)
+				env.garage.Allow(bucket.ID, operator.ID, garagefake.Permission{Owner: true})
+				env.garage.SetWebsiteAccess(bucket.ID, "index.html")
+				env.garage.SetWebsiteRoutingRules(bucket.ID, fakeRoutingRule)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, bucket garagefake.Bucket) {
+				if !bucket.WebsiteAccess || len(bucket.WebsiteRoutingRules) != 0 {
+					t.Errorf("expected website access without routing rule, got %v/%+v", bucket.WebsiteAccess, bucket.WebsiteRoutingRules)
+				}
+				if len(bucket.Permissions) != 0 {
+					t.Errorf("expected the operator key to be revoked, got %+v", bucket.Permissions)
+				}
+			},
+		},
+		{
+			name:            "missing access key",
+			spec:            v1.GarageS3BucketSpec{Permissions: []v1.GarageS3BucketPermission{{AccessKeyName: "missing", Read: true}}},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "PermissionsIncomplete",
+			expectedRequeue: bucketErrorRequeueInterval,
+			expectError:     true,
+		},
+		{
+			name: "listing failure",
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				env.garage.InjectFault("ListBuckets", http.StatusInternalServerError, 1)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "GarageAPIError",
+			expectedRequeue: bucketErrorRequeueInterval,
+			expectError:     true,
+		},
+		{
+			name: "creation failure",
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				env.garage.InjectFault("CreateBucket", http.StatusServiceUnavailable, 1)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "GarageAPIError",
+			expectedRequeue: bucketErrorRequeueInterval,
+			expectError:     true,
+		},
+		{
+			name: "alias failure",
+			spec: v1.GarageS3BucketSpec{AdditionalAliases: []string{"extra"}},
+			setup: func(t *testing.T, env *garageTestEnv) []client.Object {
+				env.garage.InjectFault("AddBucketAlias", http.StatusInternalServerError, 1)
+				return nil
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "GarageAPIError",
+			expectedRequeue: bucketErrorRequeueInterval,
+			expectError:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			objs := []client.Object{}
+			if tt.setup != nil {
+				objs = tt.setup(t, env)
+			}
+			bucket := newTestBucket(env, tt.spec)
+			c := env.client(append(objs, bucket)...)
+			r := &bucket_reconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bucket)})
+			if tt.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if result.RequeueAfter != tt.expectedRequeue {
+				t.Errorf("expected RequeueAfter=%v, got %v", tt.expectedRequeue, result.RequeueAfter)
+			}
+
+			got := &v1.GarageS3Bucket{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(bucket), got); err != nil {
+				t.Fatalf("failed to get bucket: %v", err)
+			}
+			cond := readyCondition(t, got.Status.Conditions)
+			if cond.Status != tt.expectedStatus || cond.Reason != tt.expectedReason {
+				t.Errorf("expected Ready %s/%s, got %s/%s: %s", tt.expectedStatus, tt.expectedReason, cond.Status, cond.Reason, cond.Message)
+			}
+			if tt.checkStatus != nil {
+				tt.checkStatus(t, got.Status)
+			}
+			if tt.check != nil {
+				garageBucket, found := env.garage.BucketByAlias("test-bucket")
+				if !found {
+					t.Fatal("expected the bucket to exist in Garage")
+				}
+				tt.check(t, env, garageBucket)
+			}
+		})
+	}
+}
+
+func TestBucketReconciler_FakeGarageDeletion(t *testing.T) {
+	tests := []struct {
+		name           string
+		objects        int64
+		unprovisioned  bool
+		expectError    bool
+		expectDeletion bool
+		expectRemoval  bool
+	}{
+		{name: "empty bucket", expectDeletion: true, expectRemoval: true},
+		{name: "bucket holding objects", objects: 3, expectError: true},
+		// e.g. refused by the tenancy policy, the Garage bucket of the same name belonging to another resource
+		{name: "bucket never provisioned", unprovisioned: true, expectRemoval: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			garageBucket := env.garage.AddBucket("test-bucket")
+			env.garage.SetBucketUsage(garageBucket.ID, tt.objects, tt.objects*1024)
+			bucket := newTestBucket(env, v1.GarageS3BucketSpec{})
+			bucket.Finalizers = []string{bucketFinalizer}
+			bucket.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+			if !tt.unprovisioned {
+				bucket.Status.BucketID = garageBucket.ID
+			}
+			c := env.client(bucket)
+			r := &bucket_reconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+
+			_, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bucket)})
+			if tt.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if deleted := env.garage.BucketCount() == 0; deleted != tt.expectDeletion {
+				t.Errorf("expected bucket deletion %v, got %v", tt.expectDeletion, deleted)
+			}
+			// The finalizer is kept until the bucket is deleted from Garage
+			err = c.Get(context.Background(), client.ObjectKeyFromObject(bucket), &v1.GarageS3Bucket{})
+			if kept := err == nil; kept == tt.expectRemoval {
+				t.Errorf("expected the GarageS3Bucket to be kept %v, got %v", !tt.expectRemoval, kept)
+			}
+		})
+	}
+}
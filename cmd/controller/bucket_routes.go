@@ -0,0 +1,447 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Label of the objects created for the website of a bucket, its value being the bucket name,
+// see websiteLabelValue
+const websiteBucketLabel = "garage-s3-operator.abucquet.com/bucket"
+
+// Annotations of cert-manager requesting a certificate for the TLS hosts of an Ingress
+const (
+	certManagerClusterIssuerAnnotation = "cert-manager.io/cluster-issuer"
+	certManagerIssuerAnnotation        = "cert-manager.io/issuer"
+)
+
+// Default web port of Garage, web_bind_addr in the Garage configuration
+const defaultWebPort = 3902
+
+// Cluster domain of the Service names Ingress backends resolve to
+const clusterDomain = "cluster.local"
+
+// HTTPRoutes are handled as unstructured objects, the Gateway API CRDs being optional
+var httpRouteGVK = schema.GroupVersionKind{Group: "gateway.networking.k8s.io", Version: "v1", Kind: "HTTPRoute"}
+
+var errNoWebService = errors.New("the instance has no web Service")
+
+// Length of the hash suffix of the object names truncated to fit the name length limit
+const websiteNameHashLength = 8
+
+// newHTTPRoute returns an empty HTTPRoute.
+func newHTTPRoute() *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(httpRouteGVK)
+	return obj
+}
+
+// HTTPRoutesInstalled returns true when the Gateway API serves HTTPRoutes in the cluster.
+func HTTPRoutesInstalled(mapper meta.RESTMapper) (bool, error) {
+	_, err := mapper.RESTMapping(httpRouteGVK.GroupKind(), httpRouteGVK.Version)
+	if meta.IsNoMatchError(err) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// websiteCacheOptions restricts the cache of the kinds created for websites to the objects
+// labeled with their bucket, rather than every Service and Ingress of the cluster.
+func websiteCacheOptions(httpRoutes bool) (map[client.Object]cache.ByObject, error) {
+	requirement, err := labels.NewRequirement(websiteBucketLabel, selection.Exists, nil)
+	if err != nil {
+		return nil, err
+	}
+	byObject := cache.ByObject{Label: labels.NewSelector().Add(*requirement)}
+	options := map[client.Object]cache.ByObject{
+		&corev1.Service{}:       byObject,
+		&networkingv1.Ingress{}: byObject,
+	}
+	if httpRoutes {
+		options[newHTTPRoute()] = byObject
+	}
+	return options, nil
+}
+
+// WebsiteDomains returns the additional aliases of the bucket which are domain names, Garage
+// serving the website of a bucket on the domains matching one of its aliases.
+func WebsiteDomains(spec *v1.GarageS3BucketSpec) []string {
+	var domains []string
+	for _, alias := range spec.AdditionalAliases {
+		if !isDomainAlias(alias) {
+			continue
+		}
+		if !slices.Contains(domains, alias) {
+			domains = append(domains, alias)
+		}
+	}
+	return domains
+}
+
+// isDomainAlias returns true when a bucket alias is a domain name.
+func isDomainAlias(alias string) bool {
+	return strings.Contains(alias, ".") && len(validation.IsDNS1123Subdomain(alias)) == 0
+}
+
+// websiteRoute returns the route of an enabled website, or nil.
+func websiteRoute(website *v1.GarageS3WebsiteAccess) *v1.GarageS3WebsiteRoute {
+	if website == nil || !website.Enabled {
+		return nil
+	}
+	return website.Route
+}
+
+// websiteObjectName returns the name of the Ingress or HTTPRoute of a domain. Names longer than
+// a DNS subdomain are truncated and suffixed with a hash of the full name to remain unique.
+func websiteObjectName(bucket *v1.GarageS3Bucket, domain string) string {
+	name := bucket.Name + "-" + domain
+	if len(name) <= validation.DNS1123SubdomainMaxLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(name))
+	prefix := strings.TrimRight(name[:validation.DNS1123SubdomainMaxLength-websiteNameHashLength-1], "-.")
+	return prefix + "-" + hex.EncodeToString(sum[:])[:websiteNameHashLength]
+}
+
+// websiteLabelValue returns the value of the website label of the objects of a bucket. Names
+// longer than a label value are truncated and suffixed with a hash of the full name.
+func websiteLabelValue(bucket *v1.GarageS3Bucket) string {
+	if len(bucket.Name) <= validation.LabelValueMaxLength {
+		return bucket.Name
+	}
+	sum := sha256.Sum256([]byte(bucket.Name))
+	prefix := strings.TrimRight(bucket.Name[:validation.LabelValueMaxLength-websiteNameHashLength-1], "-.")
+	return prefix + "-" + hex.EncodeToString(sum[:])[:websiteNameHashLength]
+}
+
+// allowedRouteAnnotations returns the route with only the annotations whose key is allowed,
+// exactly or by a <prefix>* wildcard.
+func allowedRouteAnnotations(route *v1.GarageS3WebsiteRoute, allowed []string) *v1.GarageS3WebsiteRoute {
+	filtered := route.DeepCopy()
+	for key := range filtered.Annotations {
+		if !slices.ContainsFunc(allowed, func(pattern string) bool {
+			prefix, wildcard := strings.CutSuffix(pattern, "*")
+			return key == pattern || (wildcard && strings.HasPrefix(key, prefix))
+		}) {
+			delete(filtered.Annotations, key)
+		}
+	}
+	return filtered
+}
+
+// checkRouteDomains checks the domains of a route against the website domains of the tenancy
+// policy of the instance, the alias given by the instance bucket defaults being trusted.
+func checkRouteDomains(instance garageInstance, bucket *v1.GarageS3Bucket, domains []string) error {
+	policy := instance.GetInstanceSpec().Tenancy
+	if policy == nil {
+		return nil
+	}
+	for _, domain := range domains {
+		if domain != defaultAlias(instance.GetInstanceSpec().BucketDefaults, bucket) && !DomainAllowed(policy, bucket.Namespace, domain) {
+			return newPolicyViolation("website route domain %s is not one of the website domains of the tenancy policy", domain)
+		}
+	}
+	return nil
+}
+
+// websiteServiceName returns the name of the ExternalName Service the Ingresses of a bucket
+// target, bucket names being DNS subdomains while Service names are DNS labels.
+func websiteServiceName(bucket *v1.GarageS3Bucket) string {
+	name := "web-" + strings.ReplaceAll(bucket.Name, ".", "-")
+	if len(name) > validation.DNS1035LabelMaxLength {
+		name = name[:validation.DNS1035LabelMaxLength]
+	}
+	return strings.TrimRight(name, "-")
+}
+
+// ReconcileWebsiteRoutes creates an Ingress or an HTTPRoute for each domain of a website with a
+// route, targeting the web Service of the instance. The objects of the domains and websites
+// which are gone are deleted.
+func (r *bucket_reconciler) ReconcileWebsiteRoutes(ctx context.Context, instance garageInstance, bucket *v1.GarageS3Bucket) error {
+	var desired []client.Object
+	route := websiteRoute(bucket.Spec.WebsiteAccess)
+	domains := WebsiteDomains(&bucket.Spec)
+	if route != nil && len(domains) > 0 {
+		web := instance.GetInstanceSpec().Web
+		if web == nil {
+			return errNoWebService
+		}
+		namespace := web.Namespace
+		if namespace == "" {
+			namespace = instance.GetAdminTokenSecretNamespace()
+		}
+		port := web.Port
+		if port == 0 {
+			port = defaultWebPort
+		}
+		if err := checkRouteDomains(instance, bucket, domains); err != nil {
+			return err
+		}
+		route = allowedRouteAnnotations(route, web.RouteAnnotations)
+
+		if route.Kind == httpRouteGVK.Kind {
+			if route.ParentRef == nil {
+				return errors.New("HTTPRoutes need a parentRef")
+			}
+			for _, domain := range domains {
+				desired = append(desired, websiteHTTPRoute(bucket, route, domain, web.Name, namespace, port))
+			}
+		} else {
+			// Ingress backends are Services of the namespace of the Ingress
+			service := web.Name
+			if namespace != bucket.Namespace {
+				externalService := websiteExternalService(bucket, web.Name, namespace, port)
+				desired = append(desired, externalService)
+				service = externalService.Name
+			}
+			for _, domain := range domains {
+				desired = append(desired, websiteIngress(bucket, route, domain, service, port))
+			}
+		}
+	}
+
+	for _, obj := range desired {
+		if err := r.applyWebsiteObject(ctx, bucket, obj); err != nil {
+			return err
+		}
+	}
+	return r.deleteStaleWebsiteObjects(ctx, bucket, desired)
+}
+
+// websiteIngress returns the Ingress of a domain, with TLS when a cert-manager issuer is set.
+func websiteIngress(bucket *v1.GarageS3Bucket, route *v1.GarageS3WebsiteRoute, domain string, service string, port int) *networkingv1.Ingress {
+	name := websiteObjectName(bucket, domain)
+	pathType := networkingv1.PathTypePrefix
+	ingress := &networkingv1.Ingress{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        name,
+			Namespace:   bucket.Namespace,
+			Annotations: websiteAnnotations(route),
+		},
+		Spec: networkingv1.IngressSpec{
+			Rules: []networkingv1.IngressRule{{
+				Host: domain,
+				IngressRuleValue: networkingv1.IngressRuleValue{HTTP: &networkingv1.HTTPIngressRuleValue{
+					Paths: []networkingv1.HTTPIngressPath{{
+						Path:     "/",
+						PathType: &pathType,
+						Backend: networkingv1.IngressBackend{Service: &networkingv1.IngressServiceBackend{
+							Name: service,
+							Port: networkingv1.ServiceBackendPort{Number: int32(port)},
+						}},
+					}},
+				}},
+			}},
+		},
+	}
+	if route.IngressClassName != "" {
+		ingress.Spec.IngressClassName = &route.IngressClassName
+	}
+	if route.ClusterIssuer != "" || route.Issuer != "" {
+		ingress.Spec.TLS = []networkingv1.IngressTLS{{Hosts: []string{domain}, SecretName: websiteObjectName(bucket, domain+"-tls")}}
+	}
+	return ingress
+}
+
+// websiteAnnotations returns the annotations of the objects of a route.
+func websiteAnnotations(route *v1.GarageS3WebsiteRoute) map[string]string {
+	annotations := map[string]string{"managed-by": "garage-s3-operator"}
+	for key, value := range route.Annotations {
+		annotations[key] = value
+	}
+	if route.ClusterIssuer != "" {
+		annotations[certManagerClusterIssuerAnnotation] = route.ClusterIssuer
+	}
+	if route.Issuer != "" {
+		annotations[certManagerIssuerAnnotation] = route.Issuer
+	}
+	return annotations
+}
+
+// websiteExternalService returns the ExternalName Service pointing the Ingresses of a bucket to
+// the web Service of another namespace.
+func websiteExternalService(bucket *v1.GarageS3Bucket, name string, namespace string, port int) *corev1.Service {
+	return &corev1.Service{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        websiteServiceName(bucket),
+			Namespace:   bucket.Namespace,
+			Annotations: map[string]string{"managed-by": "garage-s3-operator"},
+		},
+		Spec: corev1.ServiceSpec{
+			Type:         corev1.ServiceTypeExternalName,
+			ExternalName: fmt.Sprintf("%s.%s.svc.%s", name, namespace, clusterDomain),
+			Ports: []corev1.ServicePort{{
+				Name:       "web",
+				Protocol:   corev1.ProtocolTCP,
+				Port:       int32(port),
+				TargetPort: intstr.FromInt(port),
+			}},
+		},
+	}
+}
+
+// websiteHTTPRoute returns the HTTPRoute of a domain. The fields defaulted by the Gateway API
+// are set so that routes in sync are not updated again.
+func websiteHTTPRoute(bucket *v1.GarageS3Bucket, route *v1.GarageS3WebsiteRoute, domain string, service string, namespace string, port int) *unstructured.Unstructured {
+	parent := map[string]interface{}{
+		"group": httpRouteGVK.Group,
+		"kind":  "Gateway",
+		"name":  route.ParentRef.Name,
+	}
+	if route.ParentRef.Namespace != "" {
+		parent["namespace"] = route.ParentRef.Namespace
+	}
+	if route.ParentRef.SectionName != "" {
+		parent["sectionName"] = route.ParentRef.SectionName
+	}
+	backend := map[string]interface{}{
+		"group":  "",
+		"kind":   "Service",
+		"name":   service,
+		"port":   int64(port),
+		"weight": int64(1),
+	}
+	// Backends of other namespaces need a ReferenceGrant in their namespace
+	if namespace != bucket.Namespace {
+		backend["namespace"] = namespace
+	}
+
+	obj := newHTTPRoute()
+	obj.SetName(websiteObjectName(bucket, domain))
+	obj.SetNamespace(bucket.Namespace)
+	obj.SetAnnotations(websiteAnnotations(route))
+	obj.Object["spec"] = map[string]interface{}{
+		"parentRefs": []interface{}{parent},
+		"hostnames":  []interface{}{domain},
+		"rules": []interface{}{map[string]interface{}{
+			"matches": []interface{}{map[string]interface{}{
+				"path": map[string]interface{}{"type": "PathPrefix", "value": "/"},
+			}},
+			"backendRefs": []interface{}{backend},
+		}},
+	}
+	return obj
+}
+
+// applyWebsiteObject creates or updates an object of the website of a bucket, owned by the bucket.
+func (r *bucket_reconciler) applyWebsiteObject(ctx context.Context, bucket *v1.GarageS3Bucket, desired client.Object) error {
+	obj := desired.DeepCopyObject().(client.Object)
+	result, err := controllerutil.CreateOrUpdate(ctx, r.Client, obj, func() error {
+		labels := obj.GetLabels()
+		if labels == nil {
+			labels = map[string]string{}
+		}
+		labels[websiteBucketLabel] = websiteLabelValue(bucket)
+		obj.SetLabels(labels)
+
+		// Issuer annotations are removed from the objects when the route no longer sets them
+		annotations := obj.GetAnnotations()
+		if annotations == nil {
+			annotations = map[string]string{}
+		}
+		delete(annotations, certManagerClusterIssuerAnnotation)
+		delete(annotations, certManagerIssuerAnnotation)
+		for key, value := range desired.GetAnnotations() {
+			annotations[key] = value
+		}
+		obj.SetAnnotations(annotations)
+
+		switch desired := desired.(type) {
+		case *networkingv1.Ingress:
+			obj.(*networkingv1.Ingress).Spec = desired.Spec
+		case *corev1.Service:
+			service := obj.(*corev1.Service)
+			service.Spec.Type = desired.Spec.Type
+			service.Spec.ExternalName = desired.Spec.ExternalName
+			service.Spec.Ports = desired.Spec.Ports
+		case *unstructured.Unstructured:
+			obj.(*unstructured.Unstructured).Object["spec"] = desired.Object["spec"]
+		}
+		return controllerutil.SetControllerReference(bucket, obj, r.scheme)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to apply %s %s: %w", websiteObjectKind(desired), desired.GetName(), err)
+	}
+	if result != controllerutil.OperationResultNone {
+		log.FromContext(ctx).Info("Applied website object", "Kind", websiteObjectKind(desired), "Name", desired.GetName(), "Result", result)
+	}
+	return nil
+}
+
+// deleteStaleWebsiteObjects deletes the objects of the website of a bucket which are not desired.
+func (r *bucket_reconciler) deleteStaleWebsiteObjects(ctx context.Context, bucket *v1.GarageS3Bucket, desired []client.Object) error {
+	// Lists are served by the cache of the labeled objects, see websiteCacheOptions
+	httpRoutes := &unstructured.UnstructuredList{}
+	httpRoutes.SetGroupVersionKind(httpRouteGVK.GroupVersion().WithKind(httpRouteGVK.Kind + "List"))
+	lists := []client.ObjectList{&networkingv1.IngressList{}, &corev1.ServiceList{}, httpRoutes}
+
+	for _, list := range lists {
+		err := r.List(ctx, list, client.InNamespace(bucket.Namespace), client.MatchingLabels{websiteBucketLabel: websiteLabelValue(bucket)})
+		if meta.IsNoMatchError(err) {
+			// Gateway API not installed, no HTTPRoute to delete
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("failed to list the website objects: %w", err)
+		}
+		err = meta.EachListItem(list, func(item runtime.Object) error {
+			obj := item.(client.Object)
+			if !metav1.IsControlledBy(obj, bucket) || isDesiredWebsiteObject(obj, desired) {
+				return nil
+			}
+			if err := r.Delete(ctx, obj); client.IgnoreNotFound(err) != nil {
+				return fmt.Errorf("failed to delete %s %s: %w", websiteObjectKind(obj), obj.GetName(), err)
+			}
+			log.FromContext(ctx).Info("Deleted website object", "Kind", websiteObjectKind(obj), "Name", obj.GetName())
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// isDesiredWebsiteObject returns true when an object of the same kind and name is desired.
+func isDesiredWebsiteObject(obj client.Object, desired []client.Object) bool {
+	for _, d := range desired {
+		if websiteObjectKind(d) == websiteObjectKind(obj) && d.GetName() == obj.GetName() {
+			return true
+		}
+	}
+	return false
+}
+
+// websiteObjectKind returns the kind of a website object, typed objects listed from the API
+// having no type meta.
+func websiteObjectKind(obj client.Object) string {
+	switch obj.(type) {
+	case *networkingv1.Ingress:
+		return "Ingress"
+	case *corev1.Service:
+		return "Service"
+	}
+	return obj.GetObjectKind().GroupVersionKind().Kind
+}
@@ -0,0 +1,262 @@
+package main
+
+import (
+	"context"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	networkingv1 "k8s.io/api/networking/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/util/validation"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestWebsiteDomains(t *testing.T) {
+	spec := &v1.GarageS3BucketSpec{AdditionalAliases: []string{"assets", "www.example.com", "Not.A.Domain", "static.example.com", "www.example.com"}}
+	want := []string{"www.example.com", "static.example.com"}
+	if got := WebsiteDomains(spec); !slices.Equal(got, want) {
+		t.Errorf("expected domains %v, got %v", want, got)
+	}
+}
+
+func TestWebsiteObjectName(t *testing.T) {
+	bucket := &v1.GarageS3Bucket{ObjectMeta: metav1.ObjectMeta{Name: "site"}}
+	if got := websiteObjectName(bucket, "www.example.com"); got != "site-www.example.com" {
+		t.Errorf("expected site-www.example.com, got %s", got)
+	}
+
+	// Names longer than a DNS subdomain are truncated, distinct domains keeping distinct names
+	long := strings.Repeat("a", 240)
+	first := websiteObjectName(bucket, long+".example.com")
+	second := websiteObjectName(bucket, long+".example.org")
+	if len(first) > validation.DNS1123SubdomainMaxLength || len(validation.IsDNS1123Subdomain(first)) > 0 {
+		t.Errorf("expected a valid name of at most %d characters, got %s", validation.DNS1123SubdomainMaxLength, first)
+	}
+	if first == second {
+		t.Errorf("expected distinct names, got %s twice", first)
+	}
+}
+
+func TestWebsiteLabelValue(t *testing.T) {
+	bucket := &v1.GarageS3Bucket{ObjectMeta: metav1.ObjectMeta{Name: "site"}}
+	if got := websiteLabelValue(bucket); got != "site" {
+		t.Errorf("expected site, got %s", got)
+	}
+
+	// Names longer than a label value are truncated, distinct buckets keeping distinct values
+	long := strings.Repeat("a", 70)
+	bucket.Name = long + ".example.com"
+	first := websiteLabelValue(bucket)
+	bucket.Name = long + ".example.org"
+	second := websiteLabelValue(bucket)
+	if len(validation.IsValidLabelValue(first)) > 0 {
+		t.Errorf("expected a valid label value, got %s", first)
+	}
+	if first == second {
+		t.Errorf("expected distinct values, got %s twice", first)
+	}
+}
+
+func TestAllowedRouteAnnotations(t *testing.T) {
+	route := &v1.GarageS3WebsiteRoute{Annotations: map[string]string{
+		"nginx.ingress.kubernetes.io/proxy-body-size":       "10m",
+		"nginx.ingress.kubernetes.io/configuration-snippet": "return 200;",
+		"external-dns.alpha.kubernetes.io/ttl":              "60",
+		"example.com/other":                                 "value",
+	}}
+	filtered := allowedRouteAnnotations(route, []string{"nginx.ingress.kubernetes.io/proxy-body-size", "external-dns.alpha.kubernetes.io/*"})
+	want := map[string]string{"nginx.ingress.kubernetes.io/proxy-body-size": "10m", "external-dns.alpha.kubernetes.io/ttl": "60"}
+	if !maps.Equal(filtered.Annotations, want) {
+		t.Errorf("expected annotations %v, got %v", want, filtered.Annotations)
+	}
+	if len(route.Annotations) != 4 {
+		t.Errorf("expected the route to be left unchanged, got %v", route.Annotations)
+	}
+	if filtered := allowedRouteAnnotations(route, nil); len(filtered.Annotations) != 0 {
+		t.Errorf("expected no annotation without allowlist, got %v", filtered.Annotations)
+	}
+}
+
+func TestBucketReconciler_WebsiteRoutes(t *testing.T) {
+	website := func(route *v1.GarageS3WebsiteRoute) *v1.GarageS3WebsiteAccess {
+		return &v1.GarageS3WebsiteAccess{Enabled: true, Route: route}
+	}
+	aliases := []string{"assets", "www.example.com"}
+	gateway := &v1.GarageS3GatewayRef{Name: "public", Namespace: "gateways", SectionName: "https"}
+	// staleIngress is an Ingress of the bucket for a domain it no longer has
+	staleIngress := func(bucket *v1.GarageS3Bucket) client.Object {
+		ingress := &networkingv1.Ingress{ObjectMeta: metav1.ObjectMeta{
+			Name:      websiteObjectName(bucket, "old.example.com"),
+			Namespace: bucket.Namespace,
+			Labels:    map[string]string{websiteBucketLabel: bucket.Name},
+		}}
+		if err := ctrl.SetControllerReference(bucket, ingress, scheme); err != nil {
+			t.Fatalf("failed to set owner reference: %v", err)
+		}
+		return ingress
+	}
+
+	tests := []struct {
+		name            string
+		spec            v1.GarageS3BucketSpec
+		web             *v1.GarageS3WebService
+		tenancy         *v1.GarageS3TenancyPolicy
+		objects         func(bucket *v1.GarageS3Bucket) []client.Object
+		expectedReason  string
+		expectedRequeue time.Duration
+		check           func(t *testing.T, c client.Client, bucket *v1.GarageS3Bucket)
+	}{
+		{
+			name:            "creates an Ingress per domain",
+			spec:            v1.GarageS3BucketSpec{AdditionalAliases: aliases, WebsiteAccess: website(&v1.GarageS3WebsiteRoute{IngressClassName: "nginx", ClusterIssuer: "letsencrypt"})},
+			web:             &v1.GarageS3WebService{Name: "garage-web", Namespace: "default"},
+			objects:         func(bucket *v1.GarageS3Bucket) []client.Object { return []client.Object{staleIngress(bucket)} },
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, c client.Client, bucket *v1.GarageS3Bucket) {
+				ingresses := &networkingv1.IngressList{}
+				if err := c.List(context.Background(), ingresses); err != nil {
+					t.Fatalf("failed to list Ingresses: %v", err)
+				}
+				if len(ingresses.Items) != 1 {
+					t.Fatalf("expected a single Ingress, got %d", len(ingresses.Items))
+				}
+				ingress := ingresses.Items[0]
+				if ingress.Name != "test-bucket-www.example.com" || !metav1.IsControlledBy(&ingress, bucket) {
+					t.Errorf("unexpected Ingress %s owned by %v", ingress.Name, ingress.OwnerReferences)
+				}
+				if ingress.Annotations[certManagerClusterIssuerAnnotation] != "letsencrypt" || *ingress.Spec.IngressClassName != "nginx" {
+					t.Errorf("unexpected Ingress annotations %v and class %v", ingress.Annotations, ingress.Spec.IngressClassName)
+				}
+				if len(ingress.Spec.TLS) != 1 || ingress.Spec.TLS[0].Hosts[0] != "www.example.com" {
+					t.Errorf("expected TLS for www.example.com, got %+v", ingress.Spec.TLS)
+				}
+				backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service
+				if ingress.Spec.Rules[0].Host != "www.example.com" || backend.Name != "garage-web" || backend.Port.Number != defaultWebPort {
+					t.Errorf("unexpected Ingress rule %+v", ingress.Spec.Rules[0])
+				}
+			},
+		},
+		{
+			name:            "targets a web Service of another namespace",
+			spec:            v1.GarageS3BucketSpec{AdditionalAliases: aliases, WebsiteAccess: website(&v1.GarageS3WebsiteRoute{})},
+			web:             &v1.GarageS3WebService{Name: "garage-web", Namespace: "garage", Port: 8080},
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, c client.Client, bucket *v1.GarageS3Bucket) {
+				service := &corev1.Service{}
+				if err := c.Get(context.Background(), client.ObjectKey{Name: "web-test-bucket", Namespace: "default"}, service); err != nil {
+					t.Fatalf("failed to get the ExternalName Service: %v", err)
+				}
+				if service.Spec.ExternalName != "garage-web.garage.svc.cluster.local" {
+					t.Errorf("unexpected external name %q", service.Spec.ExternalName)
+				}
+				ingress := &networkingv1.Ingress{}
+				if err := c.Get(context.Background(), client.ObjectKey{Name: "test-bucket-www.example.com", Namespace: "default"}, ingress); err != nil {
+					t.Fatalf("failed to get the Ingress: %v", err)
+				}
+				backend := ingress.Spec.Rules[0].HTTP.Paths[0].Backend.Service
+				if backend.Name != service.Name || backend.Port.Number != 8080 || len(ingress.Spec.TLS) != 0 {
+					t.Errorf("unexpected Ingress %+v", ingress.Spec)
+				}
+			},
+		},
+		{
+			name:            "creates an HTTPRoute per domain",
+			spec:            v1.GarageS3BucketSpec{AdditionalAliases: aliases, WebsiteAccess: website(&v1.GarageS3WebsiteRoute{Kind: "HTTPRoute", ParentRef: gateway})},
+			web:             &v1.GarageS3WebService{Name: "garage-web", Namespace: "default"},
+			objects:         func(bucket *v1.GarageS3Bucket) []client.Object { return []client.Object{staleIngress(bucket)} },
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, c client.Client, bucket *v1.GarageS3Bucket) {
+				route := &unstructured.Unstructured{}
+				route.SetGroupVersionKind(httpRouteGVK)
+				if err := c.Get(context.Background(), client.ObjectKey{Name: "test-bucket-www.example.com", Namespace: "default"}, route); err != nil {
+					t.Fatalf("failed to get the HTTPRoute: %v", err)
+				}
+				hostnames, _, _ := unstructured.NestedStringSlice(route.Object, "spec", "hostnames")
+				if !slices.Equal(hostnames, []string{"www.example.com"}) || !metav1.IsControlledBy(route, bucket) {
+					t.Errorf("unexpected HTTPRoute hostnames %v owned by %v", hostnames, route.GetOwnerReferences())
+				}
+				ingresses := &networkingv1.IngressList{}
+				if err := c.List(context.Background(), ingresses); err != nil || len(ingresses.Items) != 0 {
+					t.Errorf("expected the Ingresses to be deleted, got %d (%v)", len(ingresses.Items), err)
+				}
+			},
+		},
+		{
+			name:            "deletes the Ingresses of a disabled website",
+			spec:            v1.GarageS3BucketSpec{AdditionalAliases: aliases, WebsiteAccess: &v1.GarageS3WebsiteAccess{Route: &v1.GarageS3WebsiteRoute{}}},
+			objects:         func(bucket *v1.GarageS3Bucket) []client.Object { return []client.Object{staleIngress(bucket)} },
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			check: func(t *testing.T, c client.Client, bucket *v1.GarageS3Bucket) {
+				err := c.Get(context.Background(), client.ObjectKey{Name: "test-bucket-old.example.com", Namespace: "default"}, &networkingv1.Ingress{})
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("expected the Ingress to be deleted, got %v", err)
+				}
+			},
+		},
+		{
+			name:            "refuses a domain outside the tenancy website domains",
+			spec:            v1.GarageS3BucketSpec{AdditionalAliases: []string{"test-bucket.example.org"}, WebsiteAccess: website(&v1.GarageS3WebsiteRoute{})},
+			web:             &v1.GarageS3WebService{Name: "garage-web", Namespace: "default"},
+			tenancy:         &v1.GarageS3TenancyPolicy{BucketNamePrefix: "test-", WebsiteDomains: []string{"*.example.com"}},
+			expectedReason:  "PolicyViolation",
+			expectedRequeue: bucketErrorRequeueInterval,
+			check: func(t *testing.T, c client.Client, bucket *v1.GarageS3Bucket) {
+				ingresses := &networkingv1.IngressList{}
+				if err := c.List(context.Background(), ingresses); err != nil || len(ingresses.Items) != 0 {
+					t.Errorf("expected no Ingress, got %d (%v)", len(ingresses.Items), err)
+				}
+			},
+		},
+		{
+			name:            "without web Service",
+			spec:            v1.GarageS3BucketSpec{AdditionalAliases: aliases, WebsiteAccess: website(&v1.GarageS3WebsiteRoute{})},
+			expectedReason:  "WebServiceMissing",
+			expectedRequeue: bucketErrorRequeueInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			env.instance.Spec.Web = tt.web
+			env.instance.Spec.Tenancy = tt.tenancy
+			bucket := newTestBucket(env, tt.spec)
+			objs := []client.Object{bucket}
+			if tt.objects != nil {
+				objs = append(objs, tt.objects(bucket)...)
+			}
+			c := env.client(objs...)
+			r := &bucket_reconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bucket)})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.RequeueAfter != tt.expectedRequeue {
+				t.Errorf("expected RequeueAfter=%v, got %v", tt.expectedRequeue, result.RequeueAfter)
+			}
+			got := &v1.GarageS3Bucket{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(bucket), got); err != nil {
+				t.Fatalf("failed to get bucket: %v", err)
+			}
+			if cond := readyCondition(t, got.Status.Conditions); cond.Reason != tt.expectedReason {
+				t.Errorf("expected reason %s, got %s: %s", tt.expectedReason, cond.Reason, cond.Message)
+			}
+			if tt.check != nil {
+				tt.check(t, c, got)
+			}
+		})
+	}
+}
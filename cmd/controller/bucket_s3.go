@@ -0,0 +1,418 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+// HasS3Settings returns true when the bucket spec sets settings only exposed by the S3 API.
+func HasS3Settings(spec *v1.GarageS3BucketSpec) bool {
+	return len(spec.CORS) > 0 || len(lifecycleRules(spec.Lifecycle)) > 0 || HasWebsiteS3Settings(spec.WebsiteAccess)
+}
+
+// HasWebsiteS3Settings returns true when an enabled website has redirections, only exposed by the S3 API.
+func HasWebsiteS3Settings(website *v1.GarageS3WebsiteAccess) bool {
+	return website != nil && website.Enabled && (website.RedirectAllRequestsTo != nil || len(website.RoutingRules) > 0)
+}
+
+// lifecycleRules returns the rules of a lifecycle configuration, which may be nil.
+func lifecycleRules(lifecycle *v1.GarageS3BucketLifecycle) []v1.GarageS3LifecycleRule {
+	if lifecycle == nil {
+		return nil
+	}
+	return lifecycle.Rules
+}
+
+// operatorKeyPermission returns the permission of the operator access key on the bucket, or nil.
+func operatorKeyPermission(bucketInfo *garage.GetBucketInfoResponse) *garage.GetBucketInfoKey {
+	for i := range bucketInfo.Keys {
+		if bucketInfo.Keys[i].Name == operatorKeyName {
+			return &bucketInfo.Keys[i]
+		}
+	}
+	return nil
+}
+
+// ReconcileS3Settings applies the bucket settings only exposed by the S3 API, and returns
+// the lifecycle rules of the bucket read from Garage. The operator access key is granted
+// owner on the bucket while it has such settings, and is revoked once the settings it
+// applied are removed.
+func (r *bucket_reconciler) ReconcileS3Settings(ctx context.Context, apiCtx context.Context, garageClient *garage.APIClient, instance garageInstance, bucket *v1.GarageS3Bucket, bucketInfo *garage.GetBucketInfoResponse) ([]v1.GarageS3LifecycleRule, error) {
+	managed := HasS3Settings(&bucket.Spec)
+	operatorPerm := operatorKeyPermission(bucketInfo)
+	if !managed && operatorPerm == nil {
+		return nil, nil
+	}
+	if instance.GetInstanceSpec().S3 == nil {
+		if managed {
+			return nil, errNoS3API
+		}
+		// Nothing left to clean up without S3 API
+		return nil, r.RevokeOperatorKey(apiCtx, garageClient, bucketInfo.Id, operatorPerm.AccessKeyId)
+	}
+
+	s3Client, accessKeyID, err := CreateS3Client(apiCtx, r.garageClients, garageClient, instance)
+	if err != nil {
+		return nil, err
+	}
+	if operatorPerm == nil || !ptrBoolVal(operatorPerm.Permissions.Owner) {
+		req := garage.BucketKeyPermChangeRequest{
+			AccessKeyId: accessKeyID,
+			BucketId:    bucketInfo.Id,
+			Permissions: garage.ApiBucketKeyPerm{Owner: boolPtr(true)},
+		}
+		if _, _, err := garageClient.PermissionAPI.AllowBucketKey(apiCtx).Body(req).Execute(); err != nil {
+			return nil, fmt.Errorf("failed to grant the operator access key owner on the bucket: %w", err)
+		}
+	}
+
+	if err := r.SyncBucketCORS(ctx, s3Client, bucket.Name, bucket.Spec.CORS); err != nil {
+		return nil, err
+	}
+	lifecycle, err := r.SyncBucketLifecycle(ctx, s3Client, bucket.Name, lifecycleRules(bucket.Spec.Lifecycle))
+	if err != nil {
+		return nil, err
+	}
+	if err := r.SyncBucketWebsite(ctx, s3Client, bucket.Name, bucket.Spec.WebsiteAccess); err != nil {
+		return nil, err
+	}
+
+	if !managed {
+		return lifecycle, r.RevokeOperatorKey(apiCtx, garageClient, bucketInfo.Id, accessKeyID)
+	}
+	return lifecycle, nil
+}
+
+// RevokeOperatorKey removes every permission of the operator access key on the bucket.
+func (r *bucket_reconciler) RevokeOperatorKey(apiCtx context.Context, garageClient *garage.APIClient, bucketID string, accessKeyID string) error {
+	req := garage.BucketKeyPermChangeRequest{
+		AccessKeyId: accessKeyID,
+		BucketId:    bucketID,
+		Permissions: garage.ApiBucketKeyPerm{Owner: boolPtr(true), Read: boolPtr(true), Write: boolPtr(true)},
+	}
+	if _, _, err := garageClient.PermissionAPI.DenyBucketKey(apiCtx).Body(req).Execute(); err != nil {
+		return fmt.Errorf("failed to revoke the operator access key on the bucket: %w", err)
+	}
+	return nil
+}
+
+// SyncBucketCORS replaces the CORS rules of the bucket when they differ from the given ones,
+// deleting them when none is given.
+func (r *bucket_reconciler) SyncBucketCORS(ctx context.Context, s3Client *s3.Client, bucketName string, rules []v1.GarageS3CORSRule) error {
+	var current []v1.GarageS3CORSRule
+	out, err := s3Client.GetBucketCors(ctx, &s3.GetBucketCorsInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		if !isS3ErrorCode(err, "NoSuchCORSConfiguration") {
+			return fmt.Errorf("failed to get the CORS rules of the bucket: %w", err)
+		}
+	} else {
+		current = CORSRulesFromS3(out.CORSRules)
+	}
+	if equality.Semantic.DeepEqual(current, rules) {
+		return nil
+	}
+
+	if len(rules) == 0 {
+		if _, err := s3Client.DeleteBucketCors(ctx, &s3.DeleteBucketCorsInput{Bucket: aws.String(bucketName)}); err != nil {
+			return fmt.Errorf("failed to delete the CORS rules of the bucket: %w", err)
+		}
+		return nil
+	}
+	_, err = s3Client.PutBucketCors(ctx, &s3.PutBucketCorsInput{
+		Bucket:            aws.String(bucketName),
+		CORSConfiguration: &s3types.CORSConfiguration{CORSRules: CORSRulesToS3(rules)},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put the CORS rules of the bucket: %w", err)
+	}
+	return nil
+}
+
+// CORSRulesToS3 converts CORS rules of a bucket spec to the S3 API ones.
+func CORSRulesToS3(rules []v1.GarageS3CORSRule) []s3types.CORSRule {
+	out := make([]s3types.CORSRule, 0, len(rules))
+	for _, rule := range rules {
+		s3Rule := s3types.CORSRule{
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedHeaders: rule.AllowedHeaders,
+			ExposeHeaders:  rule.ExposeHeaders,
+			MaxAgeSeconds:  rule.MaxAgeSeconds,
+		}
+		if rule.ID != "" {
+			s3Rule.ID = aws.String(rule.ID)
+		}
+		out = append(out, s3Rule)
+	}
+	return out
+}
+
+// CORSRulesFromS3 converts CORS rules of the S3 API to the bucket spec ones.
+func CORSRulesFromS3(rules []s3types.CORSRule) []v1.GarageS3CORSRule {
+	var out []v1.GarageS3CORSRule
+	for _, rule := range rules {
+		out = append(out, v1.GarageS3CORSRule{
+			ID:             aws.ToString(rule.ID),
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedHeaders: rule.AllowedHeaders,
+			ExposeHeaders:  rule.ExposeHeaders,
+			MaxAgeSeconds:  rule.MaxAgeSeconds,
+		})
+	}
+	return out
+}
+
+// GetBucketLifecycle returns the lifecycle rules of the bucket, and whether they are all enabled.
+func (r *bucket_reconciler) GetBucketLifecycle(ctx context.Context, s3Client *s3.Client, bucketName string) ([]v1.GarageS3LifecycleRule, bool, error) {
+	out, err := s3Client.GetBucketLifecycleConfiguration(ctx, &s3.GetBucketLifecycleConfigurationInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		if isS3ErrorCode(err, "NoSuchLifecycleConfiguration") {
+			return nil, true, nil
+		}
+		return nil, false, fmt.Errorf("failed to get the lifecycle rules of the bucket: %w", err)
+	}
+	enabled := true
+	for _, rule := range out.Rules {
+		enabled = enabled && rule.Status == s3types.ExpirationStatusEnabled
+	}
+	return LifecycleRulesFromS3(out.Rules), enabled, nil
+}
+
+// SyncBucketLifecycle replaces the lifecycle rules of the bucket when they differ from the
+// given ones or some are disabled, deleting them when none is given. It returns the rules
+// Garage holds afterwards, read back rather than assumed from the given ones.
+func (r *bucket_reconciler) SyncBucketLifecycle(ctx context.Context, s3Client *s3.Client, bucketName string, rules []v1.GarageS3LifecycleRule) ([]v1.GarageS3LifecycleRule, error) {
+	current, enabled, err := r.GetBucketLifecycle(ctx, s3Client, bucketName)
+	if err != nil {
+		return nil, err
+	}
+	if enabled && equality.Semantic.DeepEqual(current, rules) {
+		return current, nil
+	}
+
+	if len(rules) == 0 {
+		if _, err := s3Client.DeleteBucketLifecycle(ctx, &s3.DeleteBucketLifecycleInput{Bucket: aws.String(bucketName)}); err != nil {
+			return nil, fmt.Errorf("failed to delete the lifecycle rules of the bucket: %w", err)
+		}
+		return nil, nil
+	}
+	s3Rules, err := LifecycleRulesToS3(rules)
+	if err != nil {
+		return nil, err
+	}
+	_, err = s3Client.PutBucketLifecycleConfiguration(ctx, &s3.PutBucketLifecycleConfigurationInput{
+		Bucket:                 aws.String(bucketName),
+		LifecycleConfiguration: &s3types.BucketLifecycleConfiguration{Rules: s3Rules},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to put the lifecycle rules of the bucket: %w", err)
+	}
+	applied, _, err := r.GetBucketLifecycle(ctx, s3Client, bucketName)
+	return applied, err
+}
+
+// LifecycleRulesToS3 converts lifecycle rules of a bucket spec to enabled S3 API ones.
+func LifecycleRulesToS3(rules []v1.GarageS3LifecycleRule) ([]s3types.LifecycleRule, error) {
+	out := make([]s3types.LifecycleRule, 0, len(rules))
+	for _, rule := range rules {
+		s3Rule := s3types.LifecycleRule{
+			Status: s3types.ExpirationStatusEnabled,
+			Filter: &s3types.LifecycleRuleFilter{Prefix: aws.String(rule.Prefix)},
+		}
+		if rule.ID != "" {
+			s3Rule.ID = aws.String(rule.ID)
+		}
+		if rule.ExpirationDays != nil {
+			s3Rule.Expiration = &s3types.LifecycleExpiration{Days: rule.ExpirationDays}
+		}
+		if rule.ExpirationDate != "" {
+			date, err := time.Parse(time.DateOnly, rule.ExpirationDate)
+			if err != nil {
+				return nil, fmt.Errorf("invalid expiration date of lifecycle rule %q: %w", rule.ID, err)
+			}
+			s3Rule.Expiration = &s3types.LifecycleExpiration{Date: &date}
+		}
+		if rule.AbortIncompleteMultipartUploadDays != nil {
+			s3Rule.AbortIncompleteMultipartUpload = &s3types.AbortIncompleteMultipartUpload{
+				DaysAfterInitiation: rule.AbortIncompleteMultipartUploadDays,
+			}
+		}
+		out = append(out, s3Rule)
+	}
+	return out, nil
+}
+
+// LifecycleRulesFromS3 converts lifecycle rules of the S3 API to the bucket spec ones.
+func LifecycleRulesFromS3(rules []s3types.LifecycleRule) []v1.GarageS3LifecycleRule {
+	var out []v1.GarageS3LifecycleRule
+	for _, s3Rule := range rules {
+		rule := v1.GarageS3LifecycleRule{ID: aws.ToString(s3Rule.ID)}
+		if s3Rule.Filter != nil {
+			rule.Prefix = aws.ToString(s3Rule.Filter.Prefix)
+		}
+		if s3Rule.Expiration != nil {
+			rule.ExpirationDays = s3Rule.Expiration.Days
+			if s3Rule.Expiration.Date != nil {
+				rule.ExpirationDate = s3Rule.Expiration.Date.UTC().Format(time.DateOnly)
+			}
+		}
+		if s3Rule.AbortIncompleteMultipartUpload != nil {
+			rule.AbortIncompleteMultipartUploadDays = s3Rule.AbortIncompleteMultipartUpload.DaysAfterInitiation
+		}
+		out = append(out, rule)
+	}
+	return out
+}
+
+// SyncBucketWebsite replaces the website configuration of the bucket when it differs from
+// the given enabled website. Disabled websites are handled by the admin API.
+func (r *bucket_reconciler) SyncBucketWebsite(ctx context.Context, s3Client *s3.Client, bucketName string, website *v1.GarageS3WebsiteAccess) error {
+	if website == nil || !website.Enabled {
+		return nil
+	}
+	desired := WebsiteToS3(website)
+	out, err := s3Client.GetBucketWebsite(ctx, &s3.GetBucketWebsiteInput{Bucket: aws.String(bucketName)})
+	if err != nil {
+		if !isS3ErrorCode(err, "NoSuchWebsiteConfiguration") {
+			return fmt.Errorf("failed to get the website configuration of the bucket: %w", err)
+		}
+	} else {
+		current := &s3types.WebsiteConfiguration{
+			IndexDocument:         out.IndexDocument,
+			ErrorDocument:         out.ErrorDocument,
+			RedirectAllRequestsTo: out.RedirectAllRequestsTo,
+			RoutingRules:          out.RoutingRules,
+		}
+		if equality.Semantic.DeepEqual(WebsiteFromS3(current), WebsiteFromS3(desired)) {
+			return nil
+		}
+	}
+
+	_, err = s3Client.PutBucketWebsite(ctx, &s3.PutBucketWebsiteInput{
+		Bucket:               aws.String(bucketName),
+		WebsiteConfiguration: desired,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to put the website configuration of the bucket: %w", err)
+	}
+	return nil
+}
+
+// WebsiteToS3 converts the website access of a bucket spec to the S3 API configuration.
+// Websites redirecting all requests have no other setting.
+func WebsiteToS3(website *v1.GarageS3WebsiteAccess) *s3types.WebsiteConfiguration {
+	if redirect := website.RedirectAllRequestsTo; redirect != nil {
+		return &s3types.WebsiteConfiguration{
+			RedirectAllRequestsTo: &s3types.RedirectAllRequestsTo{
+				HostName: aws.String(redirect.HostName),
+				Protocol: s3types.Protocol(redirect.Protocol),
+			},
+		}
+	}
+
+	indexDocument := website.IndexDocument
+	if indexDocument == "" {
+		indexDocument = defaultIndexDocument
+	}
+	config := &s3types.WebsiteConfiguration{IndexDocument: &s3types.IndexDocument{Suffix: aws.String(indexDocument)}}
+	if website.ErrorDocument != "" {
+		config.ErrorDocument = &s3types.ErrorDocument{Key: aws.String(website.ErrorDocument)}
+	}
+	for _, rule := range website.RoutingRules {
+		s3Rule := s3types.RoutingRule{
+			Redirect: &s3types.Redirect{
+				HostName:             optionalString(rule.Redirect.HostName),
+				Protocol:             s3types.Protocol(rule.Redirect.Protocol),
+				ReplaceKeyPrefixWith: optionalString(rule.Redirect.ReplaceKeyPrefixWith),
+				ReplaceKeyWith:       optionalString(rule.Redirect.ReplaceKeyWith),
+			},
+		}
+		if rule.Redirect.HttpRedirectCode != nil {
+			s3Rule.Redirect.HttpRedirectCode = aws.String(strconv.Itoa(int(*rule.Redirect.HttpRedirectCode)))
+		}
+		if condition := rule.Condition; condition != nil {
+			s3Rule.Condition = &s3types.Condition{KeyPrefixEquals: optionalString(condition.KeyPrefixEquals)}
+			if condition.HttpErrorCodeReturnedEquals != nil {
+				s3Rule.Condition.HttpErrorCodeReturnedEquals = aws.String(strconv.Itoa(int(*condition.HttpErrorCodeReturnedEquals)))
+			}
+		}
+		config.RoutingRules = append(config.RoutingRules, s3Rule)
+	}
+	return config
+}
+
+// WebsiteFromS3 converts an S3 API website configuration to the enabled website access of a bucket spec.
+func WebsiteFromS3(config *s3types.WebsiteConfiguration) *v1.GarageS3WebsiteAccess {
+	website := &v1.GarageS3WebsiteAccess{Enabled: true}
+	if redirect := config.RedirectAllRequestsTo; redirect != nil {
+		website.RedirectAllRequestsTo = &v1.GarageS3WebsiteRedirectAll{
+			HostName: aws.ToString(redirect.HostName),
+			Protocol: string(redirect.Protocol),
+		}
+	}
+	if config.IndexDocument != nil {
+		website.IndexDocument = aws.ToString(config.IndexDocument.Suffix)
+	}
+	if config.ErrorDocument != nil {
+		website.ErrorDocument = aws.ToString(config.ErrorDocument.Key)
+	}
+	for _, s3Rule := range config.RoutingRules {
+		rule := v1.GarageS3WebsiteRoutingRule{}
+		if redirect := s3Rule.Redirect; redirect != nil {
+			rule.Redirect = v1.GarageS3WebsiteRoutingRedirect{
+				HostName:             aws.ToString(redirect.HostName),
+				Protocol:             string(redirect.Protocol),
+				HttpRedirectCode:     parseStatusCode(redirect.HttpRedirectCode),
+				ReplaceKeyPrefixWith: aws.ToString(redirect.ReplaceKeyPrefixWith),
+				ReplaceKeyWith:       aws.ToString(redirect.ReplaceKeyWith),
+			}
+		}
+		if condition := s3Rule.Condition; condition != nil {
+			rule.Condition = &v1.GarageS3WebsiteRoutingCondition{
+				KeyPrefixEquals:             aws.ToString(condition.KeyPrefixEquals),
+				HttpErrorCodeReturnedEquals: parseStatusCode(condition.HttpErrorCodeReturnedEquals),
+			}
+		}
+		website.RoutingRules = append(website.RoutingRules, rule)
+	}
+	return website
+}
+
+// optionalString returns nil for an empty string, and a pointer to it otherwise.
+func optionalString(value string) *string {
+	if value == "" {
+		return nil
+	}
+	return &value
+}
+
+// parseStatusCode parses the HTTP status code of the S3 API, returning nil when unset or invalid.
+func parseStatusCode(code *string) *int32 {
+	if code == nil {
+		return nil
+	}
+	value, err := strconv.ParseInt(*code, 10, 32)
+	if err != nil {
+		return nil
+	}
+	status := int32(value)
+	return &status
+}
+
+// isS3ErrorCode returns true when the error is an S3 API error with the given code.
+func isS3ErrorCode(err error, code string) bool {
+	var apiErr smithy.APIError
+	return errors.As(err, &apiErr) && apiErr.ErrorCode() == code
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+)
+
+func TestCORSRules_RoundTrip(t *testing.T) {
+	maxAge := int32(600)
+	tests := []struct {
+		name  string
+		rules []v1.GarageS3CORSRule
+	}{
+		{name: "no rule"},
+		{
+			name: "rules",
+			rules: []v1.GarageS3CORSRule{
+				{ID: "app", AllowedOrigins: []string{"https://app.example.com"}, AllowedMethods: []string{"GET"}, MaxAgeSeconds: &maxAge},
+				{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"PUT", "POST"}, AllowedHeaders: []string{"*"}, ExposeHeaders: []string{"ETag"}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s3Rules := CORSRulesToS3(tt.rules)
+			if len(tt.rules) > 0 && s3Rules[1].ID != nil {
+				t.Errorf("expected no ID for a rule without one, got %q", *s3Rules[1].ID)
+			}
+			if got := CORSRulesFromS3(s3Rules); !equality.Semantic.DeepEqual(got, tt.rules) {
+				t.Errorf("expected rules %+v, got %+v", tt.rules, got)
+			}
+		})
+	}
+}
+
+func TestLifecycleRules_RoundTrip(t *testing.T) {
+	days := int32(30)
+	rules := []v1.GarageS3LifecycleRule{
+		{ID: "logs", Prefix: "logs/", ExpirationDays: &days},
+		{ExpirationDate: "2030-01-01", AbortIncompleteMultipartUploadDays: &days},
+	}
+	s3Rules, err := LifecycleRulesToS3(rules)
+	if err != nil {
+		t.Fatalf("failed to convert the rules: %v", err)
+	}
+	if got := LifecycleRulesFromS3(s3Rules); !equality.Semantic.DeepEqual(got, rules) {
+		t.Errorf("expected rules %+v, got %+v", rules, got)
+	}
+
+	if _, err := LifecycleRulesToS3([]v1.GarageS3LifecycleRule{{ExpirationDate: "next year"}}); err == nil {
+		t.Error("expected an error for an invalid expiration date")
+	}
+}
+
+func TestWebsite_RoundTrip(t *testing.T) {
+	code, status := int32(301), int32(404)
+	routingRules := []v1.GarageS3WebsiteRoutingRule{
+		{Condition: &v1.GarageS3WebsiteRoutingCondition{HttpErrorCodeReturnedEquals: &status}, Redirect: v1.GarageS3WebsiteRoutingRedirect{ReplaceKeyWith: "404.html"}},
+		{Redirect: v1.GarageS3WebsiteRoutingRedirect{HostName: "example.com", Protocol: "https", HttpRedirectCode: &code}},
+	}
+	tests := []struct {
+		name     string
+		website  v1.GarageS3WebsiteAccess
+		expected v1.GarageS3WebsiteAccess
+	}{
+		{
+			name:     "default index document",
+			website:  v1.GarageS3WebsiteAccess{Enabled: true, ErrorDocument: "404.html"},
+			expected: v1.GarageS3WebsiteAccess{Enabled: true, IndexDocument: defaultIndexDocument, ErrorDocument: "404.html"},
+		},
+		{
+			name:     "routing rules",
+			website:  v1.GarageS3WebsiteAccess{Enabled: true, IndexDocument: "home.html", RoutingRules: routingRules},
+			expected: v1.GarageS3WebsiteAccess{Enabled: true, IndexDocument: "home.html", RoutingRules: routingRules},
+		},
+		{
+			name: "redirection of all requests",
+			website: v1.GarageS3WebsiteAccess{
+				Enabled:               true,
+				IndexDocument:         "inherited.html",
+				RedirectAllRequestsTo: &v1.GarageS3WebsiteRedirectAll{HostName: "www.example.com"},
+			},
+			expected: v1.GarageS3WebsiteAccess{Enabled: true, RedirectAllRequestsTo: &v1.GarageS3WebsiteRedirectAll{HostName: "www.example.com"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := WebsiteFromS3(WebsiteToS3(&tt.website)); !equality.Semantic.DeepEqual(*got, tt.expected) {
+				t.Errorf("expected website %+v, got %+v", tt.expected, *got)
+			}
+		})
+	}
+}
+
+func TestHasS3Settings(t *testing.T) {
+	if HasS3Settings(&v1.GarageS3BucketSpec{}) {
+		t.Error("expected no S3 settings in an empty spec")
+	}
+	spec := &v1.GarageS3BucketSpec{CORS: []v1.GarageS3CORSRule{{AllowedOrigins: []string{"*"}, AllowedMethods: []string{"GET"}}}}
+	if !HasS3Settings(spec) {
+		t.Error("expected CORS rules to be S3 settings")
+	}
+	if HasS3Settings(&v1.GarageS3BucketSpec{Lifecycle: &v1.GarageS3BucketLifecycle{}}) {
+		t.Error("expected no S3 settings in a lifecycle without rule")
+	}
+	redirect := &v1.GarageS3WebsiteRedirectAll{HostName: "www.example.com"}
+	if HasS3Settings(&v1.GarageS3BucketSpec{WebsiteAccess: &v1.GarageS3WebsiteAccess{RedirectAllRequestsTo: redirect}}) {
+		t.Error("expected no S3 settings in a disabled website")
+	}
+	if !HasS3Settings(&v1.GarageS3BucketSpec{WebsiteAccess: &v1.GarageS3WebsiteAccess{Enabled: true, RedirectAllRequestsTo: redirect}}) {
+		t.Error("expected website redirections to be S3 settings")
+	}
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Timeout of the health check of an admin API endpoint
+const endpointProbeTimeout = 5 * time.Second
+
+// Time during which the last good endpoint is used without being health-checked again
+const endpointProbeTTL = 30 * time.Second
+
+// goodEndpoint is a reachable endpoint and the time of its last successful health check.
+type goodEndpoint struct {
+	host     string
+	probedAt time.Time
+}
+
+// lastGoodEndpoints remembers the last reachable endpoint of each instance,
+// tried first by the next clients.
+var lastGoodEndpoints = struct {
+	sync.Mutex
+	hosts map[string]goodEndpoint
+}{hosts: map[string]goodEndpoint{}}
+
+// InstanceEndpoints returns the admin API endpoints of an instance as host:port,
+// the url and port being used when no endpoint is given.
+func InstanceEndpoints(spec *v1.GarageS3InstanceSpec) []string {
+	if len(spec.Endpoints) == 0 {
+		return []string{spec.Url + ":" + strconv.Itoa(spec.Port)}
+	}
+	hosts := []string{}
+	for _, endpoint := range spec.Endpoints {
+		if _, _, err := net.SplitHostPort(endpoint); err != nil {
+			endpoint = net.JoinHostPort(endpoint, strconv.Itoa(spec.Port))
+		}
+		hosts = append(hosts, endpoint)
+	}
+	return hosts
+}
+
+// OrderEndpoints puts the last good endpoint first, keeping the order of the others.
+func OrderEndpoints(endpoints []string, lastGood string) []string {
+	ordered := []string{}
+	for _, endpoint := range endpoints {
+		if endpoint == lastGood {
+			ordered = append(ordered, endpoint)
+		}
+	}
+	for _, endpoint := range endpoints {
+		if endpoint != lastGood {
+			ordered = append(ordered, endpoint)
+		}
+	}
+	return ordered
+}
+
+// endpointKey identifies an instance in the last good endpoints.
+func endpointKey(instance garageInstance) string {
+	return fmt.Sprintf("%T/%s", instance, client.ObjectKeyFromObject(instance))
+}
+
+// lastGoodEndpoint returns the last reachable endpoint of the instance, and whether its health
+// check is recent enough to skip another one.
+func lastGoodEndpoint(instance garageInstance) (string, bool) {
+	lastGoodEndpoints.Lock()
+	defer lastGoodEndpoints.Unlock()
+	good := lastGoodEndpoints.hosts[endpointKey(instance)]
+	return good.host, time.Since(good.probedAt) < endpointProbeTTL
+}
+
+func rememberEndpoint(instance garageInstance, host string) {
+	lastGoodEndpoints.Lock()
+	defer lastGoodEndpoints.Unlock()
+	lastGoodEndpoints.hosts[endpointKey(instance)] = goodEndpoint{host: host, probedAt: time.Now()}
+}
+
+// forgetEndpoint drops the last good endpoint of the instance if it is host, so that the
+// next clients health-check the endpoints again.
+func forgetEndpoint(instance garageInstance, host string) {
+	lastGoodEndpoints.Lock()
+	defer lastGoodEndpoints.Unlock()
+	if lastGoodEndpoints.hosts[endpointKey(instance)].host == host {
+		delete(lastGoodEndpoints.hosts, endpointKey(instance))
+	}
+}
+
+// forgetfulTransport forgets the endpoint it calls when a call fails to reach it, or the
+// endpoint answers that it is unavailable.
+type forgetfulTransport struct {
+	base     http.RoundTripper
+	instance garageInstance
+	host     string
+}
+
+func (t *forgetfulTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil || resp.StatusCode == http.StatusBadGateway || resp.StatusCode == http.StatusServiceUnavailable || resp.StatusCode == http.StatusGatewayTimeout {
+		forgetEndpoint(t.instance, t.host)
+	}
+	return resp, err
+}
+
+// EndpointClient returns a client to the given endpoint of the instance, whose failed calls
+// make the next clients select an endpoint again instead of reusing it until endpointProbeTTL.
+func EndpointClient(garageClients GarageClientFactory, instance garageInstance, host string) *garage.APIClient {
+	garageClient := garageClients.ClientForHost(host)
+	configuration := garageClient.GetConfig()
+	httpClient := http.Client{}
+	if configuration.HTTPClient != nil {
+		httpClient = *configuration.HTTPClient
+	}
+	base := httpClient.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	httpClient.Transport = &forgetfulTransport{base: base, instance: instance, host: host}
+	configuration.HTTPClient = &httpClient
+	return garageClient
+}
+
+// probeEndpoint checks that an admin API endpoint answers.
+func probeEndpoint(apiCtx context.Context, garageClients GarageClientFactory, host string) error {
+	ctx, cancel := context.WithTimeout(apiCtx, endpointProbeTimeout)
+	defer cancel()
+	_, _, err := garageClients.ClientForHost(host).ClusterAPI.GetClusterHealth(ctx).Execute()
+	return err
+}
+
+// SelectEndpoint returns the endpoint clients of the instance use: the last good one
+// while it answers, or the first next one which answers. The last good endpoint is
+// only health-checked again once endpointProbeTTL has elapsed.
+func SelectEndpoint(apiCtx context.Context, garageClients GarageClientFactory, instance garageInstance) (string, error) {
+	endpoints := InstanceEndpoints(instance.GetInstanceSpec())
+	if len(endpoints) == 1 {
+		return endpoints[0], nil
+	}
+
+	lastGood, fresh := lastGoodEndpoint(instance)
+	if fresh && slices.Contains(endpoints, lastGood) {
+		return lastGood, nil
+	}
+	failures := []string{}
+	for _, endpoint := range OrderEndpoints(endpoints, lastGood) {
+		if err := probeEndpoint(apiCtx, garageClients, endpoint); err != nil {
+			failures = append(failures, endpoint+": "+err.Error())
+			continue
+		}
+		rememberEndpoint(instance, endpoint)
+		return endpoint, nil
+	}
+	return "", fmt.Errorf("no admin API endpoint reachable (%s)", strings.Join(failures, "; "))
+}
+
+// CheckEndpoints health-checks every admin API endpoint of the instance, reports their
+// reachability in the instance status and returns the endpoint to use.
+func (r *instance_reconciler) CheckEndpoints(apiCtx context.Context, instance garageInstance) (string, error) {
+	instanceStatus := instance.GetInstanceStatus()
+	endpoints := InstanceEndpoints(instance.GetInstanceSpec())
+	if len(endpoints) == 1 {
+		instanceStatus.ActiveEndpoint = endpoints[0]
+		instanceStatus.Endpoints = nil
+		return endpoints[0], nil
+	}
+
+	reachable := map[string]bool{}
+	instanceStatus.Endpoints = []v1.GarageS3EndpointStatus{}
+	for _, endpoint := range endpoints {
+		status := v1.GarageS3EndpointStatus{Endpoint: endpoint, Reachable: true}
+		if err := probeEndpoint(apiCtx, r.garageClients, endpoint); err != nil {
+			status.Reachable = false
+			status.Error = err.Error()
+		}
+		reachable[endpoint] = status.Reachable
+		instanceStatus.Endpoints = append(instanceStatus.Endpoints, status)
+	}
+
+	lastGood, _ := lastGoodEndpoint(instance)
+	for _, endpoint := range OrderEndpoints(endpoints, lastGood) {
+		if reachable[endpoint] {
+			rememberEndpoint(instance, endpoint)
+			instanceStatus.ActiveEndpoint = endpoint
+			return endpoint, nil
+		}
+	}
+	instanceStatus.ActiveEndpoint = ""
+	return "", fmt.Errorf("no admin API endpoint reachable")
+}
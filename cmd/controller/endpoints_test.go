@@ -0,0 +1,97 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"abucquet.com/garage-s3-operator/internal/garagefake"
+)
+
+func TestInstanceEndpoints(t *testing.T) {
+	spec := &v1.GarageS3InstanceSpec{Url: "garage.garage.svc", Port: 3903}
+	if endpoints := InstanceEndpoints(spec); !reflect.DeepEqual(endpoints, []string{"garage.garage.svc:3903"}) {
+		t.Errorf("unexpected endpoints %v", endpoints)
+	}
+
+	spec.Endpoints = []string{"garage-0.garage", "garage-1.garage:3913", "fd00::1"}
+	expected := []string{"garage-0.garage:3903", "garage-1.garage:3913", "[fd00::1]:3903"}
+	if endpoints := InstanceEndpoints(spec); !reflect.DeepEqual(endpoints, expected) {
+		t.Errorf("expected %v, got %v", expected, endpoints)
+	}
+}
+
+func TestOrderEndpoints(t *testing.T) {
+	endpoints := []string{"a:3903", "b:3903", "c:3903"}
+
+	if ordered := OrderEndpoints(endpoints, "b:3903"); !reflect.DeepEqual(ordered, []string{"b:3903", "a:3903", "c:3903"}) {
+		t.Errorf("unexpected order %v", ordered)
+	}
+	if ordered := OrderEndpoints(endpoints, "d:3903"); !reflect.DeepEqual(ordered, endpoints) {
+		t.Errorf("unexpected order %v", ordered)
+	}
+}
+
+func TestSelectEndpoint(t *testing.T) {
+	env := newGarageTestEnv(t)
+	host, port := env.garage.Address()
+	env.instance.Name = "select-endpoint"
+	env.instance.Spec.Endpoints = []string{"garage-0.invalid:3903", fmt.Sprintf("%s:%d", host, port)}
+	c := env.client()
+	garageClients := NewGarageClientFactory(c)
+	apiCtx, err := garageClients.APIContext(context.Background(), env.instance)
+	if err != nil {
+		t.Fatalf("failed to create API context: %v", err)
+	}
+
+	// The reachable endpoint is found once, then used without health checks until the TTL elapses
+	for range 3 {
+		endpoint, err := SelectEndpoint(apiCtx, garageClients, env.instance)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if endpoint != env.instance.Spec.Endpoints[1] {
+			t.Errorf("expected endpoint %s, got %s", env.instance.Spec.Endpoints[1], endpoint)
+		}
+	}
+	if calls := env.garage.Calls("GetClusterHealth"); calls != 1 {
+		t.Errorf("expected a single health check, got %d", calls)
+	}
+}
+
+func TestCreateGarageClient_Failover(t *testing.T) {
+	env := newGarageTestEnv(t)
+	first := garagefake.NewServer(fakeAdminToken)
+	firstHost, firstPort := first.Address()
+	host, port := env.garage.Address()
+	env.instance.Name = "failover"
+	env.instance.Spec.Endpoints = []string{fmt.Sprintf("%s:%d", firstHost, firstPort), fmt.Sprintf("%s:%d", host, port)}
+	c := env.client()
+	garageClients := NewGarageClientFactory(c)
+
+	garageClient, apiCtx, err := CreateGarageClient(context.Background(), garageClients, env.instance)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := garageClient.ClusterAPI.GetClusterStatus(apiCtx).Execute(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	// A failed call to the cached endpoint makes the next client fall back to the other one
+	first.Close()
+	if _, _, err := garageClient.ClusterAPI.GetClusterStatus(apiCtx).Execute(); err == nil {
+		t.Fatal("expected the call to the closed endpoint to fail")
+	}
+	garageClient, apiCtx, err = CreateGarageClient(context.Background(), garageClients, env.instance)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, _, err := garageClient.ClusterAPI.GetClusterStatus(apiCtx).Execute(); err != nil {
+		t.Errorf("expected the call to fall back to the other endpoint, got %v", err)
+	}
+	if calls := env.garage.Calls("GetClusterStatus"); calls != 1 {
+		t.Errorf("expected a single call to the other endpoint, got %d", calls)
+	}
+}
@@ -2,18 +2,61 @@ package main
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"strconv"
 
 	v1 "abucquet.com/garage-s3-operator/api/v1"
 	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
-	"k8s.io/client-go/kubernetes"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/credentials"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	corev1 "k8s.io/api/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-func RetrieveAdminToken(kubeClient *kubernetes.Clientset, namespace string, secretName string) (string, error) {
-	secret, err := kubeClient.CoreV1().Secrets(namespace).Get(context.TODO(), secretName, metav1.GetOptions{})
-	if err != nil {
+// garageInstance is implemented by both GarageS3Instance and GarageS3ClusterInstance.
+type garageInstance interface {
+	client.Object
+	GetInstanceSpec() *v1.GarageS3InstanceSpec
+	GetInstanceStatus() *v1.GarageS3InstanceStatus
+	GetAdminTokenSecretNamespace() string
+}
+
+// GetInstanceForRef fetches the GarageS3Instance or GarageS3ClusterInstance an instanceRef points to.
+func GetInstanceForRef(ctx context.Context, c client.Reader, ref v1.GarageS3InstanceRef) (garageInstance, error) {
+	switch ref.Kind {
+	case "", v1.GarageS3InstanceKind:
+		instance := &v1.GarageS3Instance{}
+		if err := c.Get(ctx, client.ObjectKey{Name: ref.Name, Namespace: ref.Namespace}, instance); err != nil {
+			return nil, err
+		}
+		return instance, nil
+	case v1.GarageS3ClusterInstanceKind:
+		instance := &v1.GarageS3ClusterInstance{}
+		if err := c.Get(ctx, client.ObjectKey{Name: ref.Name}, instance); err != nil {
+			return nil, err
+		}
+		return instance, nil
+	default:
+		return nil, fmt.Errorf("unsupported instanceRef kind %q", ref.Kind)
+	}
+}
+
+// RefersToInstance returns true when the instanceRef points to the given instance.
+func RefersToInstance(ref v1.GarageS3InstanceRef, instance garageInstance) bool {
+	if ref.Name != instance.GetName() {
+		return false
+	}
+	if _, isCluster := instance.(*v1.GarageS3ClusterInstance); isCluster {
+		return ref.IsClusterScoped()
+	}
+	return !ref.IsClusterScoped() && ref.Namespace == instance.GetNamespace()
+}
+
+// RetrieveAdminToken reads the admin token from the token key of a Secret.
+func RetrieveAdminToken(ctx context.Context, c client.Reader, namespace string, secretName string) (string, error) {
+	secret := &corev1.Secret{}
+	if err := c.Get(ctx, client.ObjectKey{Name: secretName, Namespace: namespace}, secret); err != nil {
 		return "", err
 	}
 	tokenBytes, exists := secret.Data["token"]
@@ -23,19 +66,122 @@ func RetrieveAdminToken(kubeClient *kubernetes.Clientset, namespace string, secr
 	return string(tokenBytes), nil
 }
 
-func CreateGarageClient(kubeClient *kubernetes.Clientset, instance *v1.GarageS3Instance) (*garage.APIClient, context.Context, error) {
-	// Setup Garage S3 client configuration
-	configuration := garage.NewConfiguration()
-	configuration.Host = instance.Spec.Url + ":" + strconv.Itoa(instance.Spec.Port)
-	client := garage.NewAPIClient(configuration)
+// GarageClientFactory creates the clients reconcilers call the Garage admin API with.
+type GarageClientFactory interface {
+	// APIContext returns the context authenticating admin API calls with the admin token of the instance.
+	APIContext(ctx context.Context, instance garageInstance) (context.Context, error)
+	// ClientForHost returns a client to the admin API served at host:port.
+	ClientForHost(host string) *garage.APIClient
+	// S3Client returns a client to the S3 API of an instance, signing its requests with the given key.
+	S3Client(api v1.GarageS3S3API, accessKeyID string, secretAccessKey string) *s3.Client
+}
 
+// secretGarageClientFactory authenticates with the admin token Secret of each instance,
+// read through the controller-runtime client and its cache.
+type secretGarageClientFactory struct {
+	reader client.Reader
+}
+
+// NewGarageClientFactory returns the factory reading admin tokens with the given reader.
+func NewGarageClientFactory(reader client.Reader) GarageClientFactory {
+	return &secretGarageClientFactory{reader: reader}
+}
+
+func (f *secretGarageClientFactory) APIContext(ctx context.Context, instance garageInstance) (context.Context, error) {
 	// Retrieve admin token from Kubernetes Secret
-	namespace := instance.ObjectMeta.Namespace
-	secretName := instance.Spec.AdminTokenSecret
-	adminToken, err := RetrieveAdminToken(kubeClient, namespace, secretName)
+	namespace := instance.GetAdminTokenSecretNamespace()
+	secretName := instance.GetInstanceSpec().AdminTokenSecret
+	adminToken, err := RetrieveAdminToken(ctx, f.reader, namespace, secretName)
+	if err != nil {
+		return nil, err
+	}
+	return context.WithValue(ctx, garage.ContextAccessToken, adminToken), nil
+}
+
+func (f *secretGarageClientFactory) ClientForHost(host string) *garage.APIClient {
+	configuration := garage.NewConfiguration()
+	configuration.Host = host
+	return garage.NewAPIClient(configuration)
+}
+
+func (f *secretGarageClientFactory) S3Client(api v1.GarageS3S3API, accessKeyID string, secretAccessKey string) *s3.Client {
+	region := api.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	return s3.New(s3.Options{
+		BaseEndpoint: aws.String(api.Endpoint),
+		Region:       region,
+		Credentials:  credentials.NewStaticCredentialsProvider(accessKeyID, secretAccessKey, ""),
+		// Garage serves buckets on the path of the endpoint unless root_domain is configured
+		UsePathStyle: true,
+		// Only send the checksums S3 requires, as not every Garage version supports the others
+		RequestChecksumCalculation: aws.RequestChecksumCalculationWhenRequired,
+		ResponseChecksumValidation: aws.ResponseChecksumValidationWhenRequired,
+	})
+}
+
+// CreateGarageClient returns a client to the admin API of the instance, and the context
+// authenticating its calls.
+func CreateGarageClient(ctx context.Context, garageClients GarageClientFactory, instance garageInstance) (*garage.APIClient, context.Context, error) {
+	apiCtx, err := garageClients.APIContext(ctx, instance)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Fail over to another endpoint when the instance has several
+	host, err := SelectEndpoint(apiCtx, garageClients, instance)
 	if err != nil {
 		return nil, nil, err
 	}
-	ctx := context.WithValue(context.Background(), garage.ContextAccessToken, adminToken)
-	return client, ctx, nil
+	return EndpointClient(garageClients, instance, host), apiCtx, nil
+}
+
+// Region of the S3 API when the instance doesn't set one, the default of Garage
+const defaultS3Region = "garage"
+
+// Name of the Garage access key the operator calls the S3 API with. It isn't a valid
+// Kubernetes name, so it can't be the key of a GarageS3AccessKey.
+const operatorKeyName = "garage-s3-operator/s3-api"
+
+// errNoS3API is returned when an instance without S3 API is needed to apply bucket settings.
+var errNoS3API = errors.New("the instance has no S3 API")
+
+// OperatorKey returns the ID and secret of the operator access key, creating the key when missing.
+func OperatorKey(apiCtx context.Context, garageClient *garage.APIClient) (string, string, error) {
+	keys, _, err := garageClient.AccessKeyAPI.ListKeys(apiCtx).Execute()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to list access keys: %w", err)
+	}
+	for _, key := range keys {
+		if key.Name == operatorKeyName {
+			keyInfo, _, err := garageClient.AccessKeyAPI.GetKeyInfo(apiCtx).Id(key.Id).ShowSecretKey(true).Execute()
+			if err != nil {
+				return "", "", fmt.Errorf("failed to get the operator access key: %w", err)
+			}
+			return keyInfo.AccessKeyId, keyInfo.GetSecretAccessKey(), nil
+		}
+	}
+
+	name := operatorKeyName
+	req := garage.UpdateKeyRequestBody{Name: *garage.NewNullableString(&name)}
+	keyInfo, _, err := garageClient.AccessKeyAPI.CreateKey(apiCtx).Body(req).Execute()
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create the operator access key: %w", err)
+	}
+	return keyInfo.AccessKeyId, keyInfo.GetSecretAccessKey(), nil
+}
+
+// CreateS3Client returns a client to the S3 API of the instance authenticated with the
+// operator access key, and the ID of the key.
+func CreateS3Client(apiCtx context.Context, garageClients GarageClientFactory, garageClient *garage.APIClient, instance garageInstance) (*s3.Client, string, error) {
+	api := instance.GetInstanceSpec().S3
+	if api == nil {
+		return nil, "", errNoS3API
+	}
+	accessKeyID, secretAccessKey, err := OperatorKey(apiCtx, garageClient)
+	if err != nil {
+		return nil, "", err
+	}
+	return garageClients.S3Client(*api, accessKeyID, secretAccessKey), accessKeyID, nil
 }
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"abucquet.com/garage-s3-operator/internal/garagefake"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+const (
+	fakeAdminToken       = "fake-admin-token"
+	fakeAdminTokenSecret = "garage-admin-token"
+)
+
+// garageTestEnv is a fake Garage admin and S3 API with a GarageS3Instance pointing to it,
+// for end-to-end tests of the reconcilers.
+type garageTestEnv struct {
+	garage   *garagefake.Server
+	instance *v1.GarageS3Instance
+	// Objects of the fake Kubernetes client besides the instance, such as the admin token Secret
+	objects []client.Object
+}
+
+func newGarageTestEnv(t *testing.T) *garageTestEnv {
+	t.Helper()
+	server := garagefake.NewServer(fakeAdminToken)
+	t.Cleanup(server.Close)
+
+	host, port := server.Address()
+	instance := &v1.GarageS3Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "garage", Namespace: "default"},
+		Spec: v1.GarageS3InstanceSpec{
+			Url:              host,
+			Port:             port,
+			AdminTokenSecret: fakeAdminTokenSecret,
+			S3:               &v1.GarageS3S3API{Endpoint: server.S3URL(), Region: "garage"},
+		},
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: fakeAdminTokenSecret, Namespace: "default"},
+		Data:       map[string][]byte{"token": []byte(fakeAdminToken)},
+	}
+	return &garageTestEnv{
+		garage:   server,
+		instance: instance,
+		objects:  []client.Object{secret},
+	}
+}
+
+// instanceRef returns a reference to the instance of the environment.
+func (e *garageTestEnv) instanceRef() v1.GarageS3InstanceRef {
+	return v1.GarageS3InstanceRef{Name: e.instance.Name, Namespace: e.instance.Namespace}
+}
+
+// client returns a fake Kubernetes client holding the objects of the environment and the given ones.
+func (e *garageTestEnv) client(objs ...client.Object) client.Client {
+	return e.clientWithInterceptor(interceptor.Funcs{}, objs...)
+}
+
+// clientWithInterceptor returns a fake Kubernetes client like client, whose calls go through
+// the given interceptor functions, e.g. to make status writes fail.
+func (e *garageTestEnv) clientWithInterceptor(funcs interceptor.Funcs, objs ...client.Object) client.Client {
+	objs = append(append(objs, e.objects...), e.instance)
+	return fake.NewClientBuilder().
+		WithScheme(scheme).
+		WithObjects(objs...).
+		WithStatusSubresource(objs...).
+		WithInterceptorFuncs(funcs).
+		Build()
+}
+
+// readyCondition returns the Ready condition of the conditions, failing the test when missing.
+func readyCondition(t *testing.T, conditions []metav1.Condition) metav1.Condition {
+	t.Helper()
+	cond := meta.FindStatusCondition(conditions, "Ready")
+	if cond == nil {
+		t.Fatal("expected a Ready condition")
+	}
+	return *cond
+}
+
+func TestGarageClientFactory_APIContext(t *testing.T) {
+	tests := []struct {
+		name          string
+		data          map[string][]byte
+		expectError   bool
+		expectedToken string
+	}{
+		{name: "token", data: map[string][]byte{"token": []byte("secret-token")}, expectedToken: "secret-token"},
+		{name: "missing token key", data: map[string][]byte{"other": []byte("value")}, expectError: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &v1.GarageS3Instance{
+				ObjectMeta: metav1.ObjectMeta{Name: "garage", Namespace: "default"},
+				Spec:       v1.GarageS3InstanceSpec{AdminTokenSecret: "admin-token"},
+			}
+			secret := &corev1.Secret{
+				ObjectMeta: metav1.ObjectMeta{Name: "admin-token", Namespace: "default"},
+				Data:       tt.data,
+			}
+			c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(secret).Build()
+
+			apiCtx, err := NewGarageClientFactory(c).APIContext(context.Background(), instance)
+			if tt.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if err == nil && apiCtx.Value(garage.ContextAccessToken) != tt.expectedToken {
+				t.Errorf("expected token %q, got %v", tt.expectedToken, apiCtx.Value(garage.ContextAccessToken))
+			}
+		})
+	}
+}
@@ -2,21 +2,27 @@ package main
 
 import (
 	"context"
+	"fmt"
+	"slices"
 	"time"
 
 	v1 "abucquet.com/garage-s3-operator/api/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/apimachinery/pkg/runtime"
-	"k8s.io/client-go/kubernetes"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
+// instance_reconciler handles both GarageS3Instance and GarageS3ClusterInstance,
+// one controller being registered per kind.
 type instance_reconciler struct {
 	client.Client
-	scheme     *runtime.Scheme
-	kubeClient *kubernetes.Clientset
+	scheme        *runtime.Scheme
+	garageClients GarageClientFactory
+	// Kind handled by this reconciler and constructor for empty objects of that kind
+	kind        string
+	newInstance func() garageInstance
 }
 
 const instanceFinalizer = "garage.abucquet.com/finalizer"
@@ -26,7 +32,8 @@ const (
 	instanceErrorRequeueInterval = 30 * time.Second
 )
 
-func (r *instance_reconciler) UpdateStatus(ctx context.Context, status metav1.ConditionStatus, reason string, message string, instance *v1.GarageS3Instance) {
+func (r *instance_reconciler) UpdateStatus(ctx context.Context, status metav1.ConditionStatus, reason string, message string, instance garageInstance) {
+	instanceStatus := instance.GetInstanceStatus()
 	cond := metav1.Condition{
 		Type:    "Ready",
 		Status:  status,
@@ -35,52 +42,52 @@ func (r *instance_reconciler) UpdateStatus(ctx context.Context, status metav1.Co
 	}
 	// Replace existing Ready condition if present, preserve LastTransitionTime when status unchanged
 	found := false
-	for i := range instance.Status.Conditions {
-		if instance.Status.Conditions[i].Type == "Ready" {
-			if instance.Status.Conditions[i].Status == cond.Status {
-				cond.LastTransitionTime = instance.Status.Conditions[i].LastTransitionTime
+	for i := range instanceStatus.Conditions {
+		if instanceStatus.Conditions[i].Type == "Ready" {
+			if instanceStatus.Conditions[i].Status == cond.Status {
+				cond.LastTransitionTime = instanceStatus.Conditions[i].LastTransitionTime
 			} else {
 				cond.LastTransitionTime = metav1.Now()
 			}
-			instance.Status.Conditions[i] = cond
+			instanceStatus.Conditions[i] = cond
 			found = true
 			break
 		}
 	}
 	if !found {
 		cond.LastTransitionTime = metav1.Now()
-		instance.Status.Conditions = append(instance.Status.Conditions, cond)
+		instanceStatus.Conditions = append(instanceStatus.Conditions, cond)
 	}
 
 	if err := r.Status().Update(ctx, instance); err != nil {
-		log.Log.Error(err, "Failed to update GarageS3Instance status")
+		log.Log.Error(err, "Failed to update "+r.kind+" status")
 	}
 }
 
-func (r *instance_reconciler) AddFinalizer(ctx context.Context, instance *v1.GarageS3Instance) error {
-	if instance.ObjectMeta.DeletionTimestamp == nil {
+func (r *instance_reconciler) AddFinalizer(ctx context.Context, instance garageInstance) error {
+	if instance.GetDeletionTimestamp() == nil {
 		has := false
-		for _, f := range instance.ObjectMeta.Finalizers {
+		for _, f := range instance.GetFinalizers() {
 			if f == instanceFinalizer {
 				has = true
 				break
 			}
 		}
 		if !has {
-			instance.ObjectMeta.Finalizers = append(instance.ObjectMeta.Finalizers, instanceFinalizer)
+			instance.SetFinalizers(append(instance.GetFinalizers(), instanceFinalizer))
 			return r.Update(ctx, instance)
 		}
 	}
 	return nil
 }
 
-func (r *instance_reconciler) HasChildren(ctx context.Context, instance *v1.GarageS3Instance) (bool, error) {
+func (r *instance_reconciler) HasChildren(ctx context.Context, instance garageInstance) (bool, error) {
 	accessKeyList := &v1.GarageS3AccessKeyList{}
 	if err := r.List(ctx, accessKeyList); err != nil {
 		return true, err
 	}
 	for _, key := range accessKeyList.Items {
-		if key.Spec.InstanceRef.Name == instance.Name && key.Spec.InstanceRef.Namespace == instance.Namespace {
+		if RefersToInstance(key.Spec.InstanceRef, instance) {
 			return true, nil
 		}
 	}
@@ -90,29 +97,90 @@ func (r *instance_reconciler) HasChildren(ctx context.Context, instance *v1.Gara
 		return true, err
 	}
 	for _, bucket := range bucketList.Items {
-		if bucket.Spec.InstanceRef.Name == instance.Name && bucket.Spec.InstanceRef.Namespace == instance.Namespace {
+		if RefersToInstance(bucket.Spec.InstanceRef, instance) {
+			return true, nil
+		}
+	}
+
+	tokenList := &v1.GarageS3AdminTokenList{}
+	if err := r.List(ctx, tokenList); err != nil {
+		return true, err
+	}
+	for _, token := range tokenList.Items {
+		if RefersToInstance(token.Spec.InstanceRef, instance) {
+			return true, nil
+		}
+	}
+
+	nodeList := &v1.GarageS3NodeList{}
+	if err := r.List(ctx, nodeList); err != nil {
+		return true, err
+	}
+	for _, node := range nodeList.Items {
+		if RefersToInstance(node.Spec.InstanceRef, instance) {
+			return true, nil
+		}
+	}
+
+	scheduleList := &v1.GarageS3MetadataSnapshotScheduleList{}
+	if err := r.List(ctx, scheduleList); err != nil {
+		return true, err
+	}
+	for _, schedule := range scheduleList.Items {
+		if RefersToInstance(schedule.Spec.InstanceRef, instance) {
+			return true, nil
+		}
+	}
+
+	repairJobList := &v1.GarageS3RepairJobList{}
+	if err := r.List(ctx, repairJobList); err != nil {
+		return true, err
+	}
+	for _, job := range repairJobList.Items {
+		if RefersToInstance(job.Spec.InstanceRef, instance) {
 			return true, nil
 		}
 	}
 	return false, nil
 }
 
-func (r *instance_reconciler) RemoveFinalizer(ctx context.Context, instance *v1.GarageS3Instance) error {
+func (r *instance_reconciler) RemoveFinalizer(ctx context.Context, instance garageInstance) error {
 	orig := instance.DeepCopyObject().(client.Object)
 	newFinalizers := []string{}
-	for _, f := range instance.ObjectMeta.Finalizers {
+	for _, f := range instance.GetFinalizers() {
 		if f != instanceFinalizer {
 			newFinalizers = append(newFinalizers, f)
 		}
 	}
-	instance.ObjectMeta.Finalizers = newFinalizers
+	instance.SetFinalizers(newFinalizers)
 	return r.Patch(ctx, instance, client.MergeFrom(orig))
 }
 
+// RemoveAnnotations removes annotations of the instance once acted on.
+func (r *instance_reconciler) RemoveAnnotations(ctx context.Context, instance garageInstance, names ...string) error {
+	orig := instance.DeepCopyObject().(client.Object)
+	annotations := map[string]string{}
+	for k, v := range instance.GetAnnotations() {
+		if !slices.Contains(names, k) {
+			annotations[k] = v
+		}
+	}
+	instance.SetAnnotations(annotations)
+
+	// The patch response overwrites the status computed during this reconciliation
+	status := v1.GarageS3InstanceStatus{}
+	instance.GetInstanceStatus().DeepCopyInto(&status)
+	if err := r.Patch(ctx, instance, client.MergeFrom(orig)); err != nil {
+		return err
+	}
+	*instance.GetInstanceStatus() = status
+	return nil
+}
+
 func (r *instance_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
-	log := log.FromContext(ctx).WithValues("GarageS3Instance", req.NamespacedName)
+	instance := r.newInstance()
+	log := log.FromContext(ctx).WithValues(r.kind, req.NamespacedName)
 
-	instance := &v1.GarageS3Instance{}
 	if err := r.Get(ctx, req.NamespacedName, instance); err != nil {
 		return ctrl.Result{}, client.IgnoreNotFound(err)
 	}
@@ -124,7 +192,7 @@ func (r *instance_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 
 	// Handle deletion
-	if instance.ObjectMeta.DeletionTimestamp != nil {
+	if instance.GetDeletionTimestamp() != nil {
 		// Check for child resources
 		hasChildren, err := r.HasChildren(ctx, instance)
 		if err != nil {
@@ -142,17 +210,25 @@ func (r *instance_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 			log.Error(err, "Failed to remove finalizer from Garage S3 instance")
 			return ctrl.Result{}, err
 		}
+		DeleteInstanceMetrics(r.kind, instance)
 		log.Info("Deleted Garage S3 instance")
 		return ctrl.Result{}, nil
 	}
 
 	// Create client to Garage S3 instance
-	client, apiCtx, err := CreateGarageClient(r.kubeClient, instance)
+	apiCtx, err := r.garageClients.APIContext(ctx, instance)
 	if err != nil {
 		log.Error(err, "Failed to create Garage S3 client")
 		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageClientError", "Failed to create Garage S3 client", instance)
 		return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, err
 	}
+	host, err := r.CheckEndpoints(apiCtx, instance)
+	if err != nil {
+		log.Error(err, "Failed to connect to Garage S3 instance")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "ConnectionError", "No admin API endpoint of the Garage S3 instance is reachable", instance)
+		return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, err
+	}
+	client := r.garageClients.ClientForHost(host)
 
 	// Test connection to Garage S3 instance and get status
 	health, _, err := client.ClusterAPI.GetClusterHealth(apiCtx).Execute()
@@ -163,6 +239,78 @@ func (r *instance_reconciler) Reconcile(ctx context.Context, req ctrl.Request) (
 	}
 	log.Info("Connected to Garage S3 instance", "status", health.Status)
 
+	status, _, err := client.ClusterAPI.GetClusterStatus(apiCtx).Execute()
+	if err != nil {
+		log.Error(err, "Failed to get Garage cluster status")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "ConnectionError", "Failed to get Garage cluster status", instance)
+		return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, err
+	}
+	instance.GetInstanceStatus().LayoutVersion = status.LayoutVersion
+
+	// Connect the cluster to the peers it doesn't know yet
+	if len(instance.GetInstanceSpec().Peers) > 0 || instance.GetInstanceSpec().PeerService != nil {
+		connected, err := r.ConnectPeers(ctx, client, apiCtx, instance, status)
+		if err != nil {
+			log.Error(err, "Failed to connect Garage peers")
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "PeerConnectionError", "Failed to connect Garage peers", instance)
+			return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, err
+		}
+		if connected {
+			// Refresh the node list, for the bootstrap to see the new nodes
+			if status, _, err = client.ClusterAPI.GetClusterStatus(apiCtx).Execute(); err != nil {
+				log.Error(err, "Failed to get Garage cluster status")
+				r.UpdateStatus(ctx, metav1.ConditionFalse, "ConnectionError", "Failed to get Garage cluster status", instance)
+				return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, err
+			}
+		}
+	} else {
+		instance.GetInstanceStatus().Peers = nil
+	}
+
+	// Assign the first layout of a fresh cluster
+	bootstrapPending := ""
+	if instance.GetInstanceSpec().Bootstrap != nil && status.LayoutVersion == 0 {
+		bootstrapPending, err = r.BootstrapLayout(ctx, client, apiCtx, instance, status)
+		if err != nil {
+			log.Error(err, "Failed to bootstrap cluster layout")
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "BootstrapError", err.Error(), instance)
+			return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, err
+		}
+	}
+
+	// Preview staged layout changes, applied or reverted once approved
+	pendingLayout, err := r.ReconcileStagedLayout(ctx, client, apiCtx, instance)
+	if err != nil {
+		log.Error(err, "Failed to reconcile staged layout changes")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "LayoutError", "Failed to reconcile staged layout changes", instance)
+		return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, err
+	}
+
+	// Tune the background workers of the nodes
+	r.ReconcileWorkerVariables(ctx, client, apiCtx, instance, status)
+
+	// Block errors and unhealthy workers don't prevent the instance from being used
+	if err := r.ReconcileBlockErrors(ctx, client, apiCtx, instance); err != nil {
+		log.Error(err, "Failed to reconcile block errors")
+	}
+	if err := r.ReconcileWorkers(ctx, client, apiCtx, instance); err != nil {
+		log.Error(err, "Failed to reconcile worker status")
+	}
+
+	if bootstrapPending != "" && instance.GetInstanceStatus().LayoutVersion == 0 {
+		log.Info(bootstrapPending)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "BootstrapPending", bootstrapPending, instance)
+		return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, nil
+	}
+	if pendingLayout != nil {
+		message := fmt.Sprintf("Layout version %d staged, waiting for the %s annotation", pendingLayout.Version, v1.LayoutApproveAnnotation)
+		if pendingLayout.Error != "" {
+			message = fmt.Sprintf("Layout version %d staged, but can't be applied: %s", pendingLayout.Version, pendingLayout.Error)
+		}
+		r.UpdateStatus(ctx, metav1.ConditionTrue, "LayoutApprovalPending", message, instance)
+		return ctrl.Result{RequeueAfter: instanceErrorRequeueInterval}, nil
+	}
+
 	// Update instance status with Connected condition
 	r.UpdateStatus(ctx, metav1.ConditionTrue, "Connected", "Successfully connected to Garage S3 instance", instance)
 
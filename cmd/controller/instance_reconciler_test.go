@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestInstanceReconciler_FakeGarage(t *testing.T) {
+	tests := []struct {
+		name            string
+		setup           func(env *garageTestEnv)
+		expectedStatus  metav1.ConditionStatus
+		expectedReason  string
+		expectedRequeue time.Duration
+		expectError     bool
+		check           func(t *testing.T, env *garageTestEnv, instance *v1.GarageS3Instance)
+	}{
+		{
+			name:            "connected",
+			setup:           func(env *garageTestEnv) { env.garage.SetLayoutVersion(3) },
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Connected",
+			expectedRequeue: instanceRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, instance *v1.GarageS3Instance) {
+				if instance.Status.LayoutVersion != 3 {
+					t.Errorf("expected layout version 3, got %d", instance.Status.LayoutVersion)
+				}
+				if instance.Status.ActiveEndpoint != env.garage.Host() {
+					t.Errorf("expected active endpoint %s, got %s", env.garage.Host(), instance.Status.ActiveEndpoint)
+				}
+			},
+		},
+		{
+			name:            "missing admin token",
+			setup:           func(env *garageTestEnv) { env.instance.Spec.AdminTokenSecret = "missing" },
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "GarageClientError",
+			expectedRequeue: instanceErrorRequeueInterval,
+			expectError:     true,
+		},
+		{
+			name: "health check failure",
+			setup: func(env *garageTestEnv) {
+				env.garage.InjectFault("GetClusterHealth", http.StatusInternalServerError, -1)
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "ConnectionError",
+			expectedRequeue: instanceErrorRequeueInterval,
+			expectError:     true,
+		},
+		{
+			name:            "cluster status failure",
+			setup:           func(env *garageTestEnv) { env.garage.InjectFault("GetClusterStatus", http.StatusServiceUnavailable, 1) },
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "ConnectionError",
+			expectedRequeue: instanceErrorRequeueInterval,
+			expectError:     true,
+		},
+		{
+			name: "layout failure",
+			setup: func(env *garageTestEnv) {
+				env.garage.InjectFault("GetClusterLayout", http.StatusInternalServerError, 1)
+			},
+			expectedStatus:  metav1.ConditionFalse,
+			expectedReason:  "LayoutError",
+			expectedRequeue: instanceErrorRequeueInterval,
+			expectError:     true,
+		},
+		{
+			name: "failover to the second endpoint",
+			setup: func(env *garageTestEnv) {
+				env.instance.Spec.Endpoints = []string{"127.0.0.1:1", env.garage.Host()}
+			},
+			expectedStatus:  metav1.ConditionTrue,
+			expectedReason:  "Connected",
+			expectedRequeue: instanceRequeueInterval,
+			check: func(t *testing.T, env *garageTestEnv, instance *v1.GarageS3Instance) {
+				if instance.Status.ActiveEndpoint != env.garage.Host() {
+					t.Errorf("expected active endpoint %s, got %s", env.garage.Host(), instance.Status.ActiveEndpoint)
+				}
+				if len(instance.Status.Endpoints) != 2 || instance.Status.Endpoints[0].Reachable || !instance.Status.Endpoints[1].Reachable {
+					t.Errorf("expected only the second endpoint reachable, got %+v", instance.Status.Endpoints)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			if tt.setup != nil {
+				tt.setup(env)
+			}
+			c := env.client()
+			r := &instance_reconciler{
+				Client:        c,
+				scheme:        scheme,
+				garageClients: NewGarageClientFactory(c),
+				kind:          v1.GarageS3InstanceKind,
+				newInstance:   func() garageInstance { return &v1.GarageS3Instance{} },
+			}
+
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(env.instance)})
+			if tt.expectError != (err != nil) {
+				t.Fatalf("expected error %v, got %v", tt.expectError, err)
+			}
+			if result.RequeueAfter != tt.expectedRequeue {
+				t.Errorf("expected RequeueAfter=%v, got %v", tt.expectedRequeue, result.RequeueAfter)
+			}
+
+			instance := &v1.GarageS3Instance{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(env.instance), instance); err != nil {
+				t.Fatalf("failed to get instance: %v", err)
+			}
+			cond := readyCondition(t, instance.Status.Conditions)
+			if cond.Status != tt.expectedStatus || cond.Reason != tt.expectedReason {
+				t.Errorf("expected Ready %s/%s, got %s/%s: %s", tt.expectedStatus, tt.expectedReason, cond.Status, cond.Reason, cond.Message)
+			}
+			if tt.check != nil {
+				tt.check(t, env, instance)
+			}
+		})
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"strconv"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// layoutRole is a role to assign to a node, in the wire format of the layout API.
+// A nil capacity makes the node a gateway.
+type layoutRole struct {
+	ID       string   `json:"id"`
+	Zone     string   `json:"zone"`
+	Capacity *int64   `json:"capacity"`
+	Tags     []string `json:"tags"`
+}
+
+// layoutPreview is the result of a layout preview, in the wire format of the layout API.
+type layoutPreview struct {
+	Error     *string  `json:"error"`
+	Message   []string `json:"message"`
+	NewLayout *struct {
+		Roles []struct {
+			ID               string `json:"id"`
+			Zone             string `json:"zone"`
+			Capacity         *int64 `json:"capacity"`
+			UsableCapacity   *int64 `json:"usableCapacity"`
+			StoredPartitions *int64 `json:"storedPartitions"`
+		} `json:"roles"`
+	} `json:"newLayout"`
+}
+
+// convertJSON converts between equivalent types through their JSON representation.
+// The SDK models layout role changes and previews as oneOf types, which are easier
+// to build and read in their wire format.
+func convertJSON(in any, out any) error {
+	raw, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(raw, out)
+}
+
+// nodeRoleChanges converts roles to the SDK role changes.
+func nodeRoleChanges(roles []layoutRole) ([]garage.NodeRoleChange, error) {
+	changes := []garage.NodeRoleChange{}
+	if err := convertJSON(roles, &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// PendingLayoutFromPreview builds the pending layout reported in the instance status.
+func PendingLayoutFromPreview(version int64, preview layoutPreview) *v1.GarageS3PendingLayout {
+	pending := &v1.GarageS3PendingLayout{
+		Version: version,
+		Message: preview.Message,
+	}
+	if preview.Error != nil {
+		pending.Error = *preview.Error
+	}
+	if preview.NewLayout != nil {
+		for _, role := range preview.NewLayout.Roles {
+			pending.Roles = append(pending.Roles, v1.GarageS3LayoutRole{
+				NodeID:           role.ID,
+				Zone:             role.Zone,
+				Capacity:         role.Capacity,
+				UsableCapacity:   role.UsableCapacity,
+				StoredPartitions: role.StoredPartitions,
+			})
+		}
+	}
+	return pending
+}
+
+// ApplyLayout applies the staged layout changes as the given version.
+func (r *instance_reconciler) ApplyLayout(ctx context.Context, garageClient *garage.APIClient, apiCtx context.Context, instance garageInstance, version int64) error {
+	res, _, err := garageClient.ClusterLayoutAPI.ApplyClusterLayout(apiCtx).ApplyClusterLayoutRequest(garage.ApplyClusterLayoutRequest{Version: version}).Execute()
+	if err != nil {
+		return err
+	}
+	instance.GetInstanceStatus().LayoutVersion = version
+	instance.GetInstanceStatus().PendingLayout = nil
+	log.FromContext(ctx).Info("Applied cluster layout", "Version", version, "Message", res.Message)
+	return nil
+}
+
+// ReconcileStagedLayout previews the staged layout changes, and applies or reverts them
+// once the matching annotation is set. Without requireLayoutApproval, valid changes are
+// applied right away. It returns the changes still waiting for approval.
+func (r *instance_reconciler) ReconcileStagedLayout(ctx context.Context, garageClient *garage.APIClient, apiCtx context.Context, instance garageInstance) (*v1.GarageS3PendingLayout, error) {
+	log := log.FromContext(ctx)
+	instanceStatus := instance.GetInstanceStatus()
+
+	layout, _, err := garageClient.ClusterLayoutAPI.GetClusterLayout(apiCtx).Execute()
+	if err != nil {
+		return nil, err
+	}
+	if len(layout.StagedRoleChanges) == 0 && layout.StagedParameters.Get() == nil {
+		instanceStatus.PendingLayout = nil
+		return nil, nil
+	}
+
+	// Annotations hold the version they apply to, so a stale one is never acted on
+	version := layout.Version + 1
+	annotations := instance.GetAnnotations()
+	if annotations[v1.LayoutRevertAnnotation] == strconv.FormatInt(version, 10) {
+		if _, _, err := garageClient.ClusterLayoutAPI.RevertClusterLayout(apiCtx).Execute(); err != nil {
+			return nil, err
+		}
+		log.Info("Reverted staged layout changes", "Version", version)
+		instanceStatus.PendingLayout = nil
+		return nil, r.RemoveAnnotations(ctx, instance, v1.LayoutApproveAnnotation, v1.LayoutRevertAnnotation)
+	}
+
+	res, _, err := garageClient.ClusterLayoutAPI.PreviewClusterLayoutChanges(apiCtx).Execute()
+	if err != nil {
+		return nil, err
+	}
+	preview := layoutPreview{}
+	if err := convertJSON(res, &preview); err != nil {
+		return nil, err
+	}
+	pending := PendingLayoutFromPreview(version, preview)
+	instanceStatus.PendingLayout = pending
+
+	approved := annotations[v1.LayoutApproveAnnotation] == strconv.FormatInt(version, 10)
+	if (approved || !instance.GetInstanceSpec().RequireLayoutApproval) && pending.Error == "" {
+		if err := r.ApplyLayout(ctx, garageClient, apiCtx, instance, version); err != nil {
+			return nil, err
+		}
+		if !approved {
+			return nil, nil
+		}
+		return nil, r.RemoveAnnotations(ctx, instance, v1.LayoutApproveAnnotation, v1.LayoutRevertAnnotation)
+	}
+	return pending, nil
+}
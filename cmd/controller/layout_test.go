@@ -0,0 +1,124 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"abucquet.com/garage-s3-operator/internal/garagefake"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestPendingLayoutFromPreview(t *testing.T) {
+	raw := `{
+		"message": ["==== PARTITION ASSIGNATION ====", "256 new partitions assigned"],
+		"newLayout": {
+			"version": 3,
+			"roles": [
+				{"id": "a", "zone": "dc1", "tags": ["garage-0"], "capacity": 1000, "usableCapacity": 900, "storedPartitions": 256},
+				{"id": "b", "zone": "dc1", "tags": ["gateway"], "capacity": null}
+			]
+		}
+	}`
+	preview := layoutPreview{}
+	if err := json.Unmarshal([]byte(raw), &preview); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	pending := PendingLayoutFromPreview(3, preview)
+
+	if pending.Version != 3 || len(pending.Message) != 2 || pending.Error != "" {
+		t.Errorf("unexpected pending layout %+v", pending)
+	}
+	if len(pending.Roles) != 2 || pending.Roles[0].NodeID != "a" || *pending.Roles[0].UsableCapacity != 900 || *pending.Roles[0].StoredPartitions != 256 {
+		t.Errorf("unexpected roles %+v", pending.Roles)
+	}
+	if pending.Roles[1].Capacity != nil {
+		t.Errorf("expected gateway node without capacity, got %d", *pending.Roles[1].Capacity)
+	}
+
+	preview = layoutPreview{}
+	if err := json.Unmarshal([]byte(`{"error": "not enough nodes"}`), &preview); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if pending := PendingLayoutFromPreview(3, preview); pending.Error != "not enough nodes" || len(pending.Roles) != 0 {
+		t.Errorf("unexpected pending layout %+v", pending)
+	}
+}
+
+func TestReconcileStagedLayout(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireApproval bool
+		annotations     map[string]string
+		// Stage the removal of the only storage node, refused by the preview
+		invalid       bool
+		expectPending bool
+		expectApplied bool
+		expectStaged  bool
+	}{
+		{name: "waiting for approval", requireApproval: true, expectPending: true, expectStaged: true},
+		{name: "approved", requireApproval: true, annotations: map[string]string{v1.LayoutApproveAnnotation: "2"}, expectApplied: true},
+		{name: "approval of another version", requireApproval: true, annotations: map[string]string{v1.LayoutApproveAnnotation: "3"}, expectPending: true, expectStaged: true},
+		{name: "reverted", requireApproval: true, annotations: map[string]string{v1.LayoutRevertAnnotation: "2"}},
+		{name: "revert of another version", requireApproval: true, annotations: map[string]string{v1.LayoutRevertAnnotation: "1"}, expectPending: true, expectStaged: true},
+		{name: "applied without approval required", expectApplied: true},
+		{name: "invalid changes not applied", invalid: true, expectPending: true, expectStaged: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			capacity := int64(1 << 30)
+			env.garage.SetNodes(
+				garagefake.Node{ID: "a", Hostname: "garage-0", Zone: "dc1", Capacity: &capacity, IsUp: true},
+				garagefake.Node{ID: "b", Hostname: "garage-1", Zone: "dc1", IsUp: true},
+			)
+			if tt.invalid {
+				env.garage.StageRoleChanges(garagefake.RoleChange{ID: "a", Remove: true})
+			} else {
+				env.garage.StageRoleChanges(garagefake.RoleChange{ID: "b", Zone: "dc1", Capacity: &capacity})
+			}
+			env.instance.Spec.RequireLayoutApproval = tt.requireApproval
+			env.instance.Annotations = tt.annotations
+			c := env.client()
+			instance := &v1.GarageS3Instance{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(env.instance), instance); err != nil {
+				t.Fatalf("failed to get instance: %v", err)
+			}
+			r := &instance_reconciler{Client: c, scheme: scheme}
+			garageClient, apiCtx, err := CreateGarageClient(context.Background(), NewGarageClientFactory(c), instance)
+			if err != nil {
+				t.Fatalf("failed to create Garage client: %v", err)
+			}
+
+			pending, err := r.ReconcileStagedLayout(context.Background(), garageClient, apiCtx, instance)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if (pending != nil) != tt.expectPending {
+				t.Errorf("expected pending layout %v, got %+v", tt.expectPending, pending)
+			}
+			if applied := env.garage.LayoutVersion() == 2; applied != tt.expectApplied {
+				t.Errorf("expected layout applied %v, got version %d", tt.expectApplied, env.garage.LayoutVersion())
+			}
+			if staged := len(env.garage.StagedRoleChanges()) > 0; staged != tt.expectStaged {
+				t.Errorf("expected staged changes %v, got %v", tt.expectStaged, env.garage.StagedRoleChanges())
+			}
+
+			// Annotations are removed once acted on, and kept otherwise
+			got := &v1.GarageS3Instance{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(env.instance), got); err != nil {
+				t.Fatalf("failed to get instance: %v", err)
+			}
+			wantAnnotations := len(tt.annotations)
+			if !tt.expectStaged {
+				wantAnnotations = 0
+			}
+			if len(got.Annotations) != wantAnnotations {
+				t.Errorf("expected %d annotations, got %v", wantAnnotations, got.Annotations)
+			}
+		})
+	}
+}
@@ -7,14 +7,22 @@ import (
 	"path/filepath"
 
 	garageS3types "abucquet.com/garage-s3-operator/api/v1"
+	garageS3typesv1beta2 "abucquet.com/garage-s3-operator/api/v1beta2"
 
+	corev1 "k8s.io/api/core/v1"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	networkingv1 "k8s.io/api/networking/v1"
 	"k8s.io/apimachinery/pkg/runtime"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
-	"k8s.io/client-go/kubernetes"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
 	"k8s.io/client-go/rest"
 	"k8s.io/client-go/tools/clientcmd"
 	"k8s.io/client-go/util/homedir"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 )
@@ -24,114 +32,276 @@ var (
 )
 
 func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
 	utilruntime.Must(garageS3types.AddToScheme(scheme))
+	utilruntime.Must(garageS3typesv1beta2.AddToScheme(scheme))
 }
 
-func getClientSetAndConfig() (clientset *kubernetes.Clientset, config *rest.Config, err error) {
+func getConfig() (*rest.Config, error) {
 
 	// if kube config doesn't exist, try incluster config
 	kubeconfigFilePath := filepath.Join(homedir.HomeDir(), ".kube", "config")
 	if _, err := os.Stat(kubeconfigFilePath); errors.Is(err, os.ErrNotExist) {
-		config, err = rest.InClusterConfig()
-		if err != nil {
-			return nil, nil, err
-		}
-	} else {
-		config, err = clientcmd.BuildConfigFromFlags("", kubeconfigFilePath)
-		if err != nil {
-			return nil, nil, err
-		}
+		return rest.InClusterConfig()
 	}
+	return clientcmd.BuildConfigFromFlags("", kubeconfigFilePath)
+}
 
-	// kubernetes client set
-	clientset, err = kubernetes.NewForConfig(config)
+// httpRoutesInstalledForConfig returns true when the cluster serves HTTPRoutes, before the
+// manager and its REST mapper exist.
+func httpRoutesInstalledForConfig(config *rest.Config) (bool, error) {
+	httpClient, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return false, err
+	}
+	mapper, err := apiutil.NewDynamicRESTMapper(config, httpClient)
 	if err != nil {
-		return nil, config, err
+		return false, err
 	}
-	return clientset, config, nil
+	return HTTPRoutesInstalled(mapper)
+}
+
+// envEnabled returns true when the given environment variable is set to "true" or "1".
+func envEnabled(name string) bool {
+	value := os.Getenv(name)
+	return value == "true" || value == "1"
 }
 
 func main() {
 
-	// Retrieve Kubernetes clientset
-	clientset, config, err := getClientSetAndConfig()
+	// Retrieve Kubernetes config
+	config, err := getConfig()
 	if err != nil {
-		fmt.Printf("Failed to get clientset: %v\n", err)
+		fmt.Printf("Failed to get config: %v\n", err)
 		return
 	}
 
 	// Set logger
 	// Choose dev mode based on DEBUG variable
-	debug := os.Getenv("DEBUG")
-	useDevMode := false
-	if debug == "true" || debug == "1" {
-		useDevMode = true
-	}
-	ctrl.SetLogger(zap.New(zap.UseDevMode(useDevMode)))
+	ctrl.SetLogger(zap.New(zap.UseDevMode(envEnabled("DEBUG"))))
 	setupLog := ctrl.Log.WithName("Setup")
 
+	// Website objects are cached by label, HTTPRoutes only when the Gateway API is installed
+	httpRoutes, err := httpRoutesInstalledForConfig(config)
+	if err != nil {
+		setupLog.Error(err, "Unable to look up the Gateway API")
+		return
+	}
+	websiteCache, err := websiteCacheOptions(httpRoutes)
+	if err != nil {
+		setupLog.Error(err, "Unable to configure the website objects cache")
+		return
+	}
+	// Only the admin token Secrets are cached, for their controller to watch them
+	secretCache, err := adminTokenSecretCacheOptions()
+	if err != nil {
+		setupLog.Error(err, "Unable to configure the Secrets cache")
+		return
+	}
+	websiteCache[&corev1.Secret{}] = secretCache
+
 	// Start controller manager
 	mgr, err := ctrl.NewManager(config, ctrl.Options{
 		Scheme:                 scheme,
 		HealthProbeBindAddress: ":8081",
+		Cache:                  cache.Options{ByObject: websiteCache},
+		// Pods and EndpointSlices are read once in a while, watching all of them isn't worth it,
+		// and Secrets are read from the API as the cache only holds the admin token ones
+		Client: client.Options{
+			Cache: &client.CacheOptions{
+				DisableFor: []client.Object{&corev1.Pod{}, &discoveryv1.EndpointSlice{}, &corev1.Secret{}},
+			},
+		},
 	})
 	if err != nil {
 		setupLog.Error(err, "Unable to start manager")
 		return
 	}
-	// register simple health & readiness checks
+	// register health & readiness checks
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to add healthz check")
 		os.Exit(1)
 	}
-	if err := mgr.AddReadyzCheck("readyz", healthz.Ping); err != nil {
+	readiness := &readinessChecker{cache: mgr.GetCache()}
+	if envEnabled("ENABLE_WEBHOOKS") {
+		readiness.webhookChecker = mgr.GetWebhookServer().StartedChecker()
+	}
+	if err := mgr.AddReadyzCheck("readyz", readiness.Check); err != nil {
 		setupLog.Error(err, "unable to add readyz check")
 		os.Exit(1)
 	}
+	// Optionally report Garage connectivity in pod readiness
+	if envEnabled("READYZ_CHECK_INSTANCES") {
+		instances := &instanceConnectivityChecker{Reader: mgr.GetCache()}
+		if err := mgr.AddReadyzCheck("garage-instances", instances.Check); err != nil {
+			setupLog.Error(err, "unable to add garage-instances readyz check")
+			os.Exit(1)
+		}
+	}
+
+	// Optionally answer the on-demand TLS ask requests of reverse proxies for the website domains
+	if addr := os.Getenv("WEBSITE_ASK_BIND_ADDRESS"); addr != "" {
+		if err := mgr.Add(&websiteAskServer{Reader: mgr.GetCache(), Addr: addr}); err != nil {
+			setupLog.Error(err, "unable to add website ask server")
+			os.Exit(1)
+		}
+	}
+
+	// Admin tokens are read from Secrets through the client, without its cache
+	garageClients := NewGarageClientFactory(mgr.GetClient())
+
+	if err := setupControllers(mgr, garageClients); err != nil {
+		setupLog.Error(err, "Unable to create controllers")
+		os.Exit(1)
+	}
+	// The conversion webhook serves the kinds available in several API versions
+	if envEnabled("ENABLE_WEBHOOKS") {
+		if err := setupWebhooks(mgr); err != nil {
+			setupLog.Error(err, "Unable to create webhooks")
+			os.Exit(1)
+		}
+	}
 
+	setupLog.Info("Starting manager")
+	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+		setupLog.Error(err, "Problem running manager")
+		os.Exit(1)
+	}
+
+}
+
+// setupControllers registers the controllers of all kinds with the manager.
+func setupControllers(mgr ctrl.Manager, garageClients GarageClientFactory) error {
 	// Controller for GarageS3Instance
-	err = ctrl.NewControllerManagedBy(mgr).
+	err := ctrl.NewControllerManagedBy(mgr).
 		For(&garageS3types.GarageS3Instance{}).
+		Watches(&garageS3types.GarageS3Node{}, handler.EnqueueRequestsFromMapFunc(NodeInstanceRequests(garageS3types.GarageS3InstanceKind))).
 		Complete(&instance_reconciler{
-			Client:     mgr.GetClient(),
-			scheme:     mgr.GetScheme(),
-			kubeClient: clientset,
+			Client:        mgr.GetClient(),
+			scheme:        mgr.GetScheme(),
+			garageClients: garageClients,
+			kind:          garageS3types.GarageS3InstanceKind,
+			newInstance:   func() garageInstance { return &garageS3types.GarageS3Instance{} },
 		})
 	if err != nil {
-		setupLog.Error(err, "Unable to create controller")
-		os.Exit(1)
+		return fmt.Errorf("unable to create instance controller: %w", err)
+	}
+
+	// Controller for GarageS3ClusterInstance, sharing the GarageS3Instance logic
+	err = ctrl.NewControllerManagedBy(mgr).
+		For(&garageS3types.GarageS3ClusterInstance{}).
+		Watches(&garageS3types.GarageS3Node{}, handler.EnqueueRequestsFromMapFunc(NodeInstanceRequests(garageS3types.GarageS3ClusterInstanceKind))).
+		Complete(&instance_reconciler{
+			Client:        mgr.GetClient(),
+			scheme:        mgr.GetScheme(),
+			garageClients: garageClients,
+			kind:          garageS3types.GarageS3ClusterInstanceKind,
+			newInstance:   func() garageInstance { return &garageS3types.GarageS3ClusterInstance{} },
+		})
+	if err != nil {
+		return fmt.Errorf("unable to create cluster instance controller: %w", err)
 	}
 
 	// Controller for GarageS3AccessKey
 	err = ctrl.NewControllerManagedBy(mgr).
 		For(&garageS3types.GarageS3AccessKey{}).
 		Complete(&accessKeyReconciler{
-			Client:     mgr.GetClient(),
-			scheme:     mgr.GetScheme(),
-			kubeClient: clientset,
+			Client:        mgr.GetClient(),
+			scheme:        mgr.GetScheme(),
+			garageClients: garageClients,
 		})
 	if err != nil {
-		setupLog.Error(err, "Unable to create accesskey controller")
-		os.Exit(1)
+		return fmt.Errorf("unable to create accesskey controller: %w", err)
 	}
 
-	// Controller for GarageS3Bucket
-	err = ctrl.NewControllerManagedBy(mgr).
+	// Controller for GarageS3Bucket, owning the Ingresses, Services and HTTPRoutes of websites
+	httpRoutes, err := HTTPRoutesInstalled(mgr.GetRESTMapper())
+	if err != nil {
+		return fmt.Errorf("unable to look up the Gateway API: %w", err)
+	}
+	bucketController := ctrl.NewControllerManagedBy(mgr).
 		For(&garageS3types.GarageS3Bucket{}).
+		Owns(&networkingv1.Ingress{}).
+		Owns(&corev1.Service{})
+	if httpRoutes {
+		bucketController = bucketController.Owns(newHTTPRoute())
+	}
+	err = bucketController.
 		Complete(&bucket_reconciler{
-			Client:     mgr.GetClient(),
-			scheme:     mgr.GetScheme(),
-			kubeClient: clientset,
+			Client:        mgr.GetClient(),
+			scheme:        mgr.GetScheme(),
+			garageClients: garageClients,
 		})
 	if err != nil {
-		setupLog.Error(err, "Unable to create bucket controller")
-		os.Exit(1)
+		return fmt.Errorf("unable to create bucket controller: %w", err)
 	}
 
-	setupLog.Info("Starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
-		setupLog.Error(err, "Problem running manager")
-		os.Exit(1)
+	// Controller for GarageS3AdminToken
+	err = ctrl.NewControllerManagedBy(mgr).
+		For(&garageS3types.GarageS3AdminToken{}).
+		Owns(&corev1.Secret{}).
+		Complete(&adminTokenReconciler{
+			Client:        mgr.GetClient(),
+			scheme:        mgr.GetScheme(),
+			garageClients: garageClients,
+		})
+	if err != nil {
+		return fmt.Errorf("unable to create admin token controller: %w", err)
 	}
 
+	// Controller for GarageS3Node
+	err = ctrl.NewControllerManagedBy(mgr).
+		For(&garageS3types.GarageS3Node{}).
+		Complete(&nodeReconciler{
+			Client:        mgr.GetClient(),
+			scheme:        mgr.GetScheme(),
+			garageClients: garageClients,
+		})
+	if err != nil {
+		return fmt.Errorf("unable to create node controller: %w", err)
+	}
+
+	// Controller for GarageS3MetadataSnapshotSchedule
+	err = ctrl.NewControllerManagedBy(mgr).
+		For(&garageS3types.GarageS3MetadataSnapshotSchedule{}).
+		Complete(&metadataSnapshotReconciler{
+			Client:        mgr.GetClient(),
+			scheme:        mgr.GetScheme(),
+			garageClients: garageClients,
+			recorder:      mgr.GetEventRecorderFor("garage-s3-operator"),
+		})
+	if err != nil {
+		return fmt.Errorf("unable to create metadata snapshot controller: %w", err)
+	}
+
+	// Controller for GarageS3RepairJob
+	err = ctrl.NewControllerManagedBy(mgr).
+		For(&garageS3types.GarageS3RepairJob{}).
+		Complete(&repairJobReconciler{
+			Client:        mgr.GetClient(),
+			scheme:        mgr.GetScheme(),
+			garageClients: garageClients,
+			recorder:      mgr.GetEventRecorderFor("garage-s3-operator"),
+		})
+	if err != nil {
+		return fmt.Errorf("unable to create repair job controller: %w", err)
+	}
+	return nil
+}
+
+// setupWebhooks registers the conversion webhook of the kinds served in v1 and v1beta2.
+func setupWebhooks(mgr ctrl.Manager) error {
+	hubs := []client.Object{
+		&garageS3types.GarageS3Instance{},
+		&garageS3types.GarageS3ClusterInstance{},
+		&garageS3types.GarageS3AccessKey{},
+		&garageS3types.GarageS3Bucket{},
+		&garageS3types.GarageS3AdminToken{},
+	}
+	for _, hub := range hubs {
+		if err := ctrl.NewWebhookManagedBy(mgr).For(hub).Complete(); err != nil {
+			return fmt.Errorf("unable to create %T conversion webhook: %w", hub, err)
+		}
+	}
+	return nil
 }
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Metrics about the Garage clusters, served with the controller-runtime metrics.
+var (
+	blockErrorsGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "garage_s3_operator_block_errors",
+		Help: "Number of blocks which failed to resync, per Garage node",
+	}, []string{"kind", "namespace", "name", "node"})
+
+	unrecoverableBlocksGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "garage_s3_operator_unrecoverable_blocks",
+		Help: "Number of blocks still referenced which reached the unrecoverable error count, per Garage node",
+	}, []string{"kind", "namespace", "name", "node"})
+
+	workersGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "garage_s3_operator_workers",
+		Help: "Number of background workers per state, per Garage node",
+	}, []string{"kind", "namespace", "name", "node", "state"})
+
+	unhealthyWorkersGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "garage_s3_operator_unhealthy_workers",
+		Help: "Number of background workers failing repeatedly or holding persistent errors, per Garage node",
+	}, []string{"kind", "namespace", "name", "node"})
+
+	resyncQueueGauge = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "garage_s3_operator_resync_queue_length",
+		Help: "Number of blocks waiting to be resynced, per Garage node",
+	}, []string{"kind", "namespace", "name", "node"})
+
+	instanceGauges = []*prometheus.GaugeVec{
+		blockErrorsGauge,
+		unrecoverableBlocksGauge,
+		workersGauge,
+		unhealthyWorkersGauge,
+		resyncQueueGauge,
+	}
+)
+
+func init() {
+	for _, gauge := range instanceGauges {
+		metrics.Registry.MustRegister(gauge)
+	}
+}
+
+// instanceLabels returns the labels identifying an instance of the given kind in the metrics.
+func instanceLabels(kind string, instance garageInstance) prometheus.Labels {
+	return prometheus.Labels{
+		"kind":      kind,
+		"namespace": instance.GetNamespace(),
+		"name":      instance.GetName(),
+	}
+}
+
+// nodeLabels returns the labels identifying a node of an instance in the metrics.
+func nodeLabels(kind string, instance garageInstance, nodeID string) prometheus.Labels {
+	labels := instanceLabels(kind, instance)
+	labels["node"] = nodeID
+	return labels
+}
+
+// ResetInstanceMetrics removes the values of the given metrics for an instance,
+// before they are collected again.
+func ResetInstanceMetrics(kind string, instance garageInstance, gauges ...*prometheus.GaugeVec) {
+	for _, gauge := range gauges {
+		gauge.DeletePartialMatch(instanceLabels(kind, instance))
+	}
+}
+
+// DeleteInstanceMetrics removes the metrics of a deleted instance.
+func DeleteInstanceMetrics(kind string, instance garageInstance) {
+	ResetInstanceMetrics(kind, instance, instanceGauges...)
+}
@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	nodeRequeueInterval      = 5 * time.Minute
+	nodeErrorRequeueInterval = 30 * time.Second
+)
+
+// Finalizer keeping a draining GarageS3Node until its drain completes
+const nodeFinalizer = "garage-s3-operator.abucquet.com/node-finalizer"
+
+// nodeReconciler is a reconciler for GarageS3Node resources.
+type nodeReconciler struct {
+	client.Client
+	scheme        *runtime.Scheme
+	garageClients GarageClientFactory
+}
+
+// layoutRoleRemoval removes the role of a node, in the wire format of the layout API.
+type layoutRoleRemoval struct {
+	ID     string `json:"id"`
+	Remove bool   `json:"remove"`
+}
+
+// layoutHistory is the history of layout versions, in the wire format of the layout API.
+type layoutHistory struct {
+	CurrentVersion int64 `json:"currentVersion"`
+	Versions       []struct {
+		Version int64  `json:"version"`
+		Status  string `json:"status"`
+	} `json:"versions"`
+}
+
+func (r *nodeReconciler) UpdateStatus(ctx context.Context, status metav1.ConditionStatus, reason string, message string, node *v1.GarageS3Node) {
+	cond := metav1.Condition{
+		Type:    "Ready",
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	// Replace existing Ready condition if present, preserve LastTransitionTime when status unchanged
+	found := false
+	for i := range node.Status.Conditions {
+		if node.Status.Conditions[i].Type == "Ready" {
+			if node.Status.Conditions[i].Status == cond.Status {
+				cond.LastTransitionTime = node.Status.Conditions[i].LastTransitionTime
+			} else {
+				cond.LastTransitionTime = metav1.Now()
+			}
+			node.Status.Conditions[i] = cond
+			found = true
+			break
+		}
+	}
+	if !found {
+		cond.LastTransitionTime = metav1.Now()
+		node.Status.Conditions = append(node.Status.Conditions, cond)
+	}
+
+	if err := r.Status().Update(ctx, node); err != nil {
+		log.Log.Error(err, "Failed to update GarageS3Node status")
+	}
+}
+
+// ResolveNodeID finds the node ID matching the given ID or prefix.
+func ResolveNodeID(prefix string, ids []string) (string, error) {
+	match := ""
+	for _, id := range ids {
+		if !strings.HasPrefix(id, prefix) || id == match {
+			continue
+		}
+		if match != "" {
+			return "", fmt.Errorf("node ID prefix %s is ambiguous", prefix)
+		}
+		match = id
+	}
+	if match == "" {
+		return "", fmt.Errorf("no node matches ID %s", prefix)
+	}
+	return match, nil
+}
+
+// DrainSynced returns true when no layout version older than the drain one is still being synced.
+func DrainSynced(history layoutHistory, drainVersion int64) bool {
+	if history.CurrentVersion < drainVersion {
+		return false
+	}
+	for _, version := range history.Versions {
+		if version.Version < drainVersion && version.Status == "Draining" {
+			return false
+		}
+	}
+	return true
+}
+
+// Reconcile performs reconciliation for GarageS3Node.
+func (r *nodeReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithValues("GarageS3Node", req.NamespacedName)
+
+	node := &v1.GarageS3Node{}
+	if err := r.Get(ctx, req.NamespacedName, node); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// A draining node is kept until drained, so deleting it doesn't leave a drain half done
+	if node.DeletionTimestamp.IsZero() {
+		if node.Spec.Drain && !controllerutil.ContainsFinalizer(node, nodeFinalizer) {
+			controllerutil.AddFinalizer(node, nodeFinalizer)
+			if err := r.Update(ctx, node); err != nil {
+				log.Error(err, "Failed to add finalizer to GarageS3Node")
+				return ctrl.Result{}, err
+			}
+		}
+	} else if !node.Spec.Drain || node.Status.Phase == v1.NodePhaseDrained {
+		return ctrl.Result{}, r.RemoveFinalizer(ctx, node)
+	}
+
+	// Fetch the associated instance
+	instanceRef := node.Spec.InstanceRef
+	instance, err := GetInstanceForRef(ctx, r, instanceRef)
+	if err != nil {
+		if apierrors.IsNotFound(err) && !node.DeletionTimestamp.IsZero() {
+			log.Info("Associated GarageS3Instance deleted, abandoning the drain", "InstanceRef", instanceRef)
+			return ctrl.Result{}, r.RemoveFinalizer(ctx, node)
+		}
+		log.Error(err, "Failed to get associated GarageS3Instance", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "InstanceNotFound", "Associated GarageS3Instance not found", node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, err
+	}
+
+	garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
+	if err != nil {
+		log.Error(err, "Failed to create Garage S3 client for associated instance", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageClientError", "Could not create Garage S3 client", node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, err
+	}
+
+	clusterStatus, _, err := garageClient.ClusterAPI.GetClusterStatus(apiCtx).Execute()
+	if err != nil {
+		log.Error(err, "Failed to get Garage cluster status")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when retrieving cluster status from Garage", node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, err
+	}
+	layout, _, err := garageClient.ClusterLayoutAPI.GetClusterLayout(apiCtx).Execute()
+	if err != nil {
+		log.Error(err, "Failed to get Garage cluster layout")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when retrieving cluster layout from Garage", node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, err
+	}
+
+	// Resolve the node among the known nodes and the layout roles
+	ids := []string{}
+	for _, n := range clusterStatus.Nodes {
+		ids = append(ids, n.Id)
+	}
+	for _, role := range layout.Roles {
+		ids = append(ids, role.Id)
+	}
+	nodeID, err := ResolveNodeID(node.Spec.NodeID, ids)
+	if err != nil {
+		log.Info("Garage node not resolved", "Reason", err.Error())
+		if !node.DeletionTimestamp.IsZero() {
+			return ctrl.Result{}, r.RemoveFinalizer(ctx, node)
+		}
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "NodeNotFound", err.Error(), node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, nil
+	}
+	node.Status.NodeID = nodeID
+
+	var role *garage.LayoutNodeRole
+	for i := range layout.Roles {
+		if layout.Roles[i].Id == nodeID {
+			role = &layout.Roles[i]
+		}
+	}
+	node.Status.Zone = ""
+	node.Status.Capacity = nil
+	if role != nil {
+		node.Status.Zone = role.Zone
+		node.Status.Capacity = role.Capacity.Get()
+	}
+
+	if !node.Spec.Drain {
+		if role == nil {
+			node.Status.Phase = ""
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "NoRole", "Node has no role in the current layout", node)
+			return ctrl.Result{RequeueAfter: nodeRequeueInterval}, nil
+		}
+		node.Status.Phase = v1.NodePhaseActive
+		node.Status.DrainLayoutVersion = 0
+		r.UpdateStatus(ctx, metav1.ConditionTrue, "Active", "Node is part of the current layout", node)
+		return ctrl.Result{RequeueAfter: nodeRequeueInterval}, nil
+	}
+
+	previousPhase := node.Status.Phase
+	node.Status.Phase = v1.NodePhaseDraining
+	if role != nil {
+		// Stage the role removal, unless already staged
+		staged := []layoutRoleRemoval{}
+		if err := convertJSON(layout.StagedRoleChanges, &staged); err != nil {
+			return ctrl.Result{}, err
+		}
+		removalStaged := false
+		for _, change := range staged {
+			if change.ID == nodeID && change.Remove {
+				removalStaged = true
+			}
+		}
+		if !removalStaged {
+			changes := []garage.NodeRoleChange{}
+			if err := convertJSON([]layoutRoleRemoval{{ID: nodeID, Remove: true}}, &changes); err != nil {
+				return ctrl.Result{}, err
+			}
+			if _, _, err := garageClient.ClusterLayoutAPI.UpdateClusterLayout(apiCtx).UpdateClusterLayoutRequest(garage.UpdateClusterLayoutRequest{Roles: changes}).Execute(); err != nil {
+				log.Error(err, "Failed to stage node role removal", "Node", nodeID)
+				r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when staging node role removal", node)
+				return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, err
+			}
+			log.Info("Staged node role removal", "Node", nodeID)
+		}
+
+		// The instance reconciler applies the staged layout, along with the other staged changes
+		if instance.GetInstanceSpec().RequireLayoutApproval {
+			message := fmt.Sprintf("Role removal staged, waiting for approval of layout version %d", layout.Version+1)
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "LayoutApprovalPending", message, node)
+			return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, nil
+		}
+		message := fmt.Sprintf("Role removal staged, waiting for layout version %d to be applied", layout.Version+1)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "LayoutPending", message, node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, nil
+	}
+
+	// The role was removed by the layout applied by the instance reconciler
+	if node.Status.DrainLayoutVersion == 0 {
+		node.Status.DrainLayoutVersion = layout.Version
+	}
+
+	// Wait for the data of the node to be moved to the other nodes
+	res, _, err := garageClient.ClusterLayoutAPI.GetClusterLayoutHistory(apiCtx).Execute()
+	if err != nil {
+		log.Error(err, "Failed to get Garage cluster layout history")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when retrieving layout history from Garage", node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, err
+	}
+	history := layoutHistory{}
+	if err := convertJSON(res, &history); err != nil {
+		return ctrl.Result{}, err
+	}
+	health, _, err := garageClient.ClusterAPI.GetClusterHealth(apiCtx).Execute()
+	if err != nil {
+		log.Error(err, "Failed to get Garage cluster health")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when retrieving cluster health from Garage", node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, err
+	}
+	if !DrainSynced(history, node.Status.DrainLayoutVersion) || health.Status != "healthy" {
+		message := fmt.Sprintf("Waiting for layout version %d to be synced (cluster %s)", node.Status.DrainLayoutVersion, health.Status)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "Syncing", message, node)
+		return ctrl.Result{RequeueAfter: nodeErrorRequeueInterval}, nil
+	}
+
+	if previousPhase != v1.NodePhaseDrained {
+		log.Info("Garage node drained", "Node", nodeID)
+	}
+	node.Status.Phase = v1.NodePhaseDrained
+	r.UpdateStatus(ctx, metav1.ConditionTrue, "Drained", "Node holds no data anymore and can be removed", node)
+	if !node.DeletionTimestamp.IsZero() {
+		return ctrl.Result{}, r.RemoveFinalizer(ctx, node)
+	}
+	return ctrl.Result{RequeueAfter: nodeRequeueInterval}, nil
+}
+
+// NodeInstanceRequests maps a GarageS3Node to the instance of the given kind it references,
+// for the instance reconciler to apply the role removal staged by the drain.
+func NodeInstanceRequests(kind string) handler.MapFunc {
+	return func(ctx context.Context, obj client.Object) []reconcile.Request {
+		node, ok := obj.(*v1.GarageS3Node)
+		if !ok || node.Spec.InstanceRef.IsClusterScoped() != (kind == v1.GarageS3ClusterInstanceKind) {
+			return nil
+		}
+		key := types.NamespacedName{Name: node.Spec.InstanceRef.Name}
+		if !node.Spec.InstanceRef.IsClusterScoped() {
+			key.Namespace = node.Spec.InstanceRef.Namespace
+		}
+		return []reconcile.Request{{NamespacedName: key}}
+	}
+}
+
+// RemoveFinalizer lets the deletion of the node proceed.
+func (r *nodeReconciler) RemoveFinalizer(ctx context.Context, node *v1.GarageS3Node) error {
+	if !controllerutil.ContainsFinalizer(node, nodeFinalizer) {
+		return nil
+	}
+	controllerutil.RemoveFinalizer(node, nodeFinalizer)
+	return r.Update(ctx, node)
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"abucquet.com/garage-s3-operator/internal/garagefake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+func TestResolveNodeID(t *testing.T) {
+	ids := []string{"563e1ac825ee3323", "563e9f0b3c1d2a44", "a1b2c3d4e5f60718", "a1b2c3d4e5f60718"}
+	tests := []struct {
+		prefix  string
+		want    string
+		wantErr bool
+	}{
+		{prefix: "563e1", want: "563e1ac825ee3323"},
+		{prefix: "a1b2", want: "a1b2c3d4e5f60718"},
+		{prefix: "563e", wantErr: true},
+		{prefix: "ffff", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.prefix, func(t *testing.T) {
+			got, err := ResolveNodeID(tt.prefix, ids)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if got != tt.want {
+				t.Errorf("expected %q, got %q", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestDrainSynced(t *testing.T) {
+	tests := []struct {
+		name    string
+		history string
+		want    bool
+	}{
+		{
+			name:    "older version draining",
+			history: `{"currentVersion": 4, "versions": [{"version": 4, "status": "Current"}, {"version": 3, "status": "Draining"}]}`,
+		},
+		{
+			name:    "older versions historical",
+			history: `{"currentVersion": 4, "versions": [{"version": 4, "status": "Current"}, {"version": 3, "status": "Historical"}]}`,
+			want:    true,
+		},
+		{
+			name:    "later version draining",
+			history: `{"currentVersion": 5, "versions": [{"version": 5, "status": "Current"}, {"version": 4, "status": "Draining"}]}`,
+			want:    true,
+		},
+		{
+			name:    "drain version not applied",
+			history: `{"currentVersion": 3, "versions": [{"version": 3, "status": "Current"}]}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			history := layoutHistory{}
+			if err := json.Unmarshal([]byte(tt.history), &history); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got := DrainSynced(history, 4); got != tt.want {
+				t.Errorf("expected %v, got %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func newTestNode(env *garageTestEnv, name string, nodeID string) *v1.GarageS3Node {
+	return &v1.GarageS3Node{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+		Spec:       v1.GarageS3NodeSpec{InstanceRef: env.instanceRef(), NodeID: nodeID, Drain: true},
+	}
+}
+
+func TestNodeReconciler_Drain(t *testing.T) {
+	tests := []struct {
+		name            string
+		requireApproval bool
+		// Delete the first node before its drain completes
+		deleted        bool
+		expectedReason string
+		expectDrained  bool
+	}{
+		{name: "nodes drained together", expectedReason: "LayoutPending", expectDrained: true},
+		{name: "waiting for approval", requireApproval: true, expectedReason: "LayoutApprovalPending"},
+		{name: "deleted while draining", deleted: true, expectedReason: "LayoutPending", expectDrained: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			capacity := int64(1 << 30)
+			env.garage.SetNodes(
+				garagefake.Node{ID: "aaaa", Hostname: "garage-0", Zone: "dc1", Capacity: &capacity, IsUp: true},
+				garagefake.Node{ID: "bbbb", Hostname: "garage-1", Zone: "dc1", Capacity: &capacity, IsUp: true},
+				garagefake.Node{ID: "cccc", Hostname: "garage-2", Zone: "dc1", Capacity: &capacity, IsUp: true},
+			)
+			env.instance.Spec.RequireLayoutApproval = tt.requireApproval
+			nodes := []*v1.GarageS3Node{newTestNode(env, "garage-1", "bbbb"), newTestNode(env, "garage-2", "cccc")}
+			c := env.client(nodes[0], nodes[1])
+			r := &nodeReconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+			reconcileNodes := func() {
+				t.Helper()
+				for _, node := range nodes {
+					if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(node)}); err != nil {
+						t.Fatalf("unexpected error: %v", err)
+					}
+				}
+			}
+
+			// Both removals are staged, none is applied by the node reconciler
+			reconcileNodes()
+			if tt.deleted {
+				if err := c.Delete(context.Background(), nodes[0]); err != nil {
+					t.Fatalf("failed to delete node: %v", err)
+				}
+				reconcileNodes()
+			}
+			if staged := env.garage.StagedRoleChanges(); len(staged) != 2 || env.garage.LayoutVersion() != 1 {
+				t.Fatalf("expected both removals staged in version 1, got %v in version %d", staged, env.garage.LayoutVersion())
+			}
+			for _, node := range nodes {
+				got := &v1.GarageS3Node{}
+				if err := c.Get(context.Background(), client.ObjectKeyFromObject(node), got); err != nil {
+					t.Fatalf("failed to get node: %v", err)
+				}
+				if cond := readyCondition(t, got.Status.Conditions); cond.Reason != tt.expectedReason {
+					t.Errorf("expected reason %s, got %s: %s", tt.expectedReason, cond.Reason, cond.Message)
+				}
+			}
+
+			// The instance reconciler applies the staged layout
+			instance := &v1.GarageS3Instance{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(env.instance), instance); err != nil {
+				t.Fatalf("failed to get instance: %v", err)
+			}
+			garageClient, apiCtx, err := CreateGarageClient(context.Background(), r.garageClients, instance)
+			if err != nil {
+				t.Fatalf("failed to create Garage client: %v", err)
+			}
+			ir := &instance_reconciler{Client: c, scheme: scheme}
+			if _, err := ir.ReconcileStagedLayout(context.Background(), garageClient, apiCtx, instance); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			reconcileNodes()
+
+			for i, node := range nodes {
+				got := &v1.GarageS3Node{}
+				err := c.Get(context.Background(), client.ObjectKeyFromObject(node), got)
+				if tt.deleted && i == 0 {
+					// The finalizer is removed once drained
+					if err == nil {
+						t.Errorf("expected the deleted node to be gone once drained, got phase %s", got.Status.Phase)
+					}
+					continue
+				}
+				if err != nil {
+					t.Fatalf("failed to get node: %v", err)
+				}
+				if drained := got.Status.Phase == v1.NodePhaseDrained; drained != tt.expectDrained {
+					t.Errorf("expected drained %v, got phase %s", tt.expectDrained, got.Status.Phase)
+				}
+				if tt.expectDrained && got.Status.DrainLayoutVersion != 2 {
+					t.Errorf("expected drain layout version 2, got %d", got.Status.DrainLayoutVersion)
+				}
+			}
+		})
+	}
+}
+
+func TestNodeReconciler_DeletionWithoutDrain(t *testing.T) {
+	env := newGarageTestEnv(t)
+	node := newTestNode(env, "garage-0", "0")
+	node.Spec.Drain = false
+	node.Finalizers = []string{nodeFinalizer}
+	node.DeletionTimestamp = &metav1.Time{Time: time.Now()}
+	c := env.client(node)
+	r := &nodeReconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+
+	if _, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(node)}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := c.Get(context.Background(), client.ObjectKeyFromObject(node), &v1.GarageS3Node{}); err == nil {
+		t.Error("expected the node not being drained to be deleted")
+	}
+	if calls := env.garage.Calls("UpdateClusterLayout"); calls != 0 {
+		t.Errorf("expected no layout change, got %d calls", calls)
+	}
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// RPC port of Garage nodes when the peer Service doesn't give one.
+const defaultRPCPort = 3901
+
+// Names of the EndpointSlice ports of the Garage admin API and RPC
+const (
+	peerAdminPortName = "admin"
+	peerRPCPortName   = "rpc"
+)
+
+// Timeout of the node info request sent to a discovered peer
+const peerNodeInfoTimeout = 5 * time.Second
+
+// ParsePeer splits a peer in the <node id>@<address> format.
+func ParsePeer(peer string) (v1.GarageS3PeerStatus, error) {
+	id, addr, found := strings.Cut(peer, "@")
+	if !found || id == "" || addr == "" {
+		return v1.GarageS3PeerStatus{}, fmt.Errorf("invalid peer %q, expected <node id>@<address>", peer)
+	}
+	return v1.GarageS3PeerStatus{NodeID: id, Address: addr}, nil
+}
+
+// PeersToConnect returns the indexes of the peers which are not connected yet,
+// and marks the others as connected.
+func PeersToConnect(peers []v1.GarageS3PeerStatus, nodes []garage.NodeResp) []int {
+	up := map[string]bool{}
+	for _, node := range nodes {
+		if node.IsUp {
+			up[node.Id] = true
+		}
+	}
+	toConnect := []int{}
+	for i := range peers {
+		if peers[i].NodeID == "" {
+			continue
+		}
+		if up[peers[i].NodeID] {
+			peers[i].Connected = true
+			continue
+		}
+		toConnect = append(toConnect, i)
+	}
+	return toConnect
+}
+
+// endpointSlicePort returns the port of the EndpointSlice with the given name, or the fallback.
+func endpointSlicePort(slice *discoveryv1.EndpointSlice, name string, fallback int) int {
+	for _, port := range slice.Ports {
+		if port.Name != nil && *port.Name == name && port.Port != nil {
+			return int(*port.Port)
+		}
+	}
+	return fallback
+}
+
+// connectedNodes returns the IDs of the nodes up in the cluster by the host of their address.
+func connectedNodes(nodes []garage.NodeResp) map[string]string {
+	connected := map[string]string{}
+	for _, node := range nodes {
+		if !node.IsUp || node.Addr.Get() == nil {
+			continue
+		}
+		host, _, err := net.SplitHostPort(*node.Addr.Get())
+		if err != nil {
+			continue
+		}
+		connected[host] = node.Id
+	}
+	return connected
+}
+
+// DiscoverPeers lists the peers of the instance, from its static list and the
+// endpoints of its peer Service. The node ID of an endpoint is asked to the node itself,
+// unless a node already connected to the cluster has its address.
+func (r *instance_reconciler) DiscoverPeers(ctx context.Context, apiCtx context.Context, instance garageInstance, nodes []garage.NodeResp) ([]v1.GarageS3PeerStatus, error) {
+	spec := instance.GetInstanceSpec()
+
+	peers := []v1.GarageS3PeerStatus{}
+	for _, p := range spec.Peers {
+		peer, err := ParsePeer(p)
+		if err != nil {
+			peer = v1.GarageS3PeerStatus{Address: p, Error: err.Error()}
+		}
+		peers = append(peers, peer)
+	}
+
+	if spec.PeerService == nil {
+		return peers, nil
+	}
+	namespace := spec.PeerService.Namespace
+	if namespace == "" {
+		namespace = instance.GetAdminTokenSecretNamespace()
+	}
+	connected := connectedNodes(nodes)
+
+	slices := &discoveryv1.EndpointSliceList{}
+	err := r.List(ctx, slices, client.InNamespace(namespace), client.MatchingLabels{discoveryv1.LabelServiceName: spec.PeerService.Name})
+	if err != nil {
+		return nil, err
+	}
+	for _, slice := range slices.Items {
+		// Ports are the ones of the pods, which may differ from the ports of the Services
+		rpcPort := spec.PeerService.RPCPort
+		if rpcPort == 0 {
+			rpcPort = endpointSlicePort(&slice, peerRPCPortName, defaultRPCPort)
+		}
+		adminPort := endpointSlicePort(&slice, peerAdminPortName, spec.Port)
+
+		for _, endpoint := range slice.Endpoints {
+			if len(endpoint.Addresses) == 0 {
+				continue
+			}
+			address := endpoint.Addresses[0]
+			peer := v1.GarageS3PeerStatus{Address: net.JoinHostPort(address, strconv.Itoa(rpcPort))}
+			if id, found := connected[address]; found {
+				peer.NodeID = id
+				peers = append(peers, peer)
+				continue
+			}
+
+			// Every node serves the admin API with the same token
+			nodeClient := r.garageClients.ClientForHost(net.JoinHostPort(address, strconv.Itoa(adminPort)))
+			nodeCtx, cancel := context.WithTimeout(apiCtx, peerNodeInfoTimeout)
+			info, _, err := nodeClient.NodeAPI.GetNodeInfo(nodeCtx).Node("self").Execute()
+			cancel()
+			if err != nil {
+				peer.Error = fmt.Sprintf("failed to get node info: %v", err)
+			} else {
+				for id := range info.Success {
+					peer.NodeID = id
+				}
+				if peer.NodeID == "" {
+					peer.Error = "node info missing in response"
+				}
+			}
+			peers = append(peers, peer)
+		}
+	}
+	return peers, nil
+}
+
+// ConnectPeers connects the cluster to the peers it doesn't know yet, and records
+// the state of every peer in the instance status. It returns true when new peers were connected.
+func (r *instance_reconciler) ConnectPeers(ctx context.Context, garageClient *garage.APIClient, apiCtx context.Context, instance garageInstance, status *garage.GetClusterStatusResponse) (bool, error) {
+	log := log.FromContext(ctx)
+
+	peers, err := r.DiscoverPeers(ctx, apiCtx, instance, status.Nodes)
+	if err != nil {
+		return false, err
+	}
+
+	connected := false
+	toConnect := PeersToConnect(peers, status.Nodes)
+	if len(toConnect) > 0 {
+		body := []string{}
+		for _, i := range toConnect {
+			body = append(body, peers[i].NodeID+"@"+peers[i].Address)
+		}
+		res, _, err := garageClient.ClusterAPI.ConnectClusterNodes(apiCtx).RequestBody(body).Execute()
+		if err != nil {
+			return false, err
+		}
+		// Results are given in the order of the request
+		for j, i := range toConnect {
+			if j >= len(res) {
+				break
+			}
+			peers[i].Connected = res[j].Success
+			if res[j].Error.Get() != nil {
+				peers[i].Error = *res[j].Error.Get()
+			}
+			if res[j].Success {
+				connected = true
+				log.Info("Connected Garage node", "Node", peers[i].NodeID, "Address", peers[i].Address)
+			}
+		}
+	}
+
+	instance.GetInstanceStatus().Peers = peers
+	return connected, nil
+}
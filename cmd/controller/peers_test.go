@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	discoveryv1 "k8s.io/api/discovery/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestParsePeer(t *testing.T) {
+	tests := []struct {
+		peer    string
+		wantErr bool
+	}{
+		{peer: "563e1ac825ee3323@10.0.0.1:3901"},
+		{peer: "563e1ac825ee3323@[fd00::1]:3901"},
+		{peer: "10.0.0.1:3901", wantErr: true},
+		{peer: "@10.0.0.1:3901", wantErr: true},
+		{peer: "563e1ac825ee3323@", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.peer, func(t *testing.T) {
+			peer, err := ParsePeer(tt.peer)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("expected error %v, got %v", tt.wantErr, err)
+			}
+			if err == nil && peer.NodeID+"@"+peer.Address != tt.peer {
+				t.Errorf("unexpected peer %+v", peer)
+			}
+		})
+	}
+}
+
+func TestPeersToConnect(t *testing.T) {
+	peers := []v1.GarageS3PeerStatus{
+		{NodeID: "a", Address: "10.0.0.1:3901"},
+		{NodeID: "b", Address: "10.0.0.2:3901"},
+		{NodeID: "c", Address: "10.0.0.3:3901"},
+		{Address: "10.0.0.4:3901", Error: "failed to get node info"},
+	}
+	nodes := []garage.NodeResp{
+		{Id: "a", IsUp: true},
+		{Id: "b", IsUp: false},
+	}
+
+	toConnect := PeersToConnect(peers, nodes)
+
+	if len(toConnect) != 2 || toConnect[0] != 1 || toConnect[1] != 2 {
+		t.Errorf("expected peers 1 and 2 to be connected, got %v", toConnect)
+	}
+	if !peers[0].Connected || peers[1].Connected || peers[3].Connected {
+		t.Errorf("unexpected connection state %+v", peers)
+	}
+}
+
+func TestDiscoverPeers(t *testing.T) {
+	env := newGarageTestEnv(t)
+	host, port := env.garage.Address()
+	env.instance.Spec.PeerService = &v1.GarageS3PeerService{Name: "garage-headless"}
+	adminPort, rpcPort := int32(port), int32(3911)
+	adminPortName, rpcPortName := peerAdminPortName, peerRPCPortName
+	slice := &discoveryv1.EndpointSlice{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "garage-headless-abcde",
+			Namespace: "default",
+			Labels:    map[string]string{discoveryv1.LabelServiceName: "garage-headless"},
+		},
+		AddressType: discoveryv1.AddressTypeIPv4,
+		Endpoints: []discoveryv1.Endpoint{
+			{Addresses: []string{"10.0.0.1"}},
+			{Addresses: []string{host}},
+		},
+		Ports: []discoveryv1.EndpointPort{
+			{Name: &adminPortName, Port: &adminPort},
+			{Name: &rpcPortName, Port: &rpcPort},
+		},
+	}
+	c := env.client(slice)
+	r := &instance_reconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+	apiCtx, err := r.garageClients.APIContext(context.Background(), env.instance)
+	if err != nil {
+		t.Fatalf("failed to create API context: %v", err)
+	}
+	addr := "10.0.0.1:3901"
+	nodes := []garage.NodeResp{{Id: "connected", IsUp: true, Addr: *garage.NewNullableString(&addr)}}
+
+	peers, err := r.DiscoverPeers(context.Background(), apiCtx, env.instance, nodes)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(peers) != 2 {
+		t.Fatalf("expected 2 peers, got %+v", peers)
+	}
+	// The connected node is known from its address, the other one asked on the admin port of the slice
+	if peers[0].NodeID != "connected" || peers[0].Address != "10.0.0.1:3911" {
+		t.Errorf("unexpected connected peer %+v", peers[0])
+	}
+	if peers[1].NodeID == "" || peers[1].Error != "" || peers[1].Address != host+":3911" {
+		t.Errorf("unexpected discovered peer %+v", peers[1])
+	}
+	if calls := env.garage.Calls("GetNodeInfo"); calls != 1 {
+		t.Errorf("expected a single node info request, got %d", calls)
+	}
+}
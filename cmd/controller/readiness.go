@@ -0,0 +1,88 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/healthz"
+)
+
+// Maximum time a readiness probe waits for the informer caches before failing.
+const cacheSyncProbeTimeout = 2 * time.Second
+
+// readinessChecker reports the operator as ready once the manager informer
+// caches have synced and, when webhooks are enabled, the webhook server is serving.
+type readinessChecker struct {
+	cache cache.Cache
+	// Optional checker for the webhook server, nil when webhooks are disabled
+	webhookChecker healthz.Checker
+}
+
+func (c *readinessChecker) Check(req *http.Request) error {
+	ctx, cancel := context.WithTimeout(req.Context(), cacheSyncProbeTimeout)
+	defer cancel()
+	if !c.cache.WaitForCacheSync(ctx) {
+		return fmt.Errorf("informer caches are not synced yet")
+	}
+	if c.webhookChecker != nil {
+		if err := c.webhookChecker(req); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// instanceConnectivityChecker reports the aggregate connectivity of all known
+// GarageS3Instances and GarageS3ClusterInstances, based on the Ready condition
+// set by the instance reconciler.
+type instanceConnectivityChecker struct {
+	client.Reader
+}
+
+func (c *instanceConnectivityChecker) Check(req *http.Request) error {
+	instanceList := &v1.GarageS3InstanceList{}
+	if err := c.List(req.Context(), instanceList); err != nil {
+		return fmt.Errorf("failed to list GarageS3Instances: %w", err)
+	}
+	clusterInstanceList := &v1.GarageS3ClusterInstanceList{}
+	if err := c.List(req.Context(), clusterInstanceList); err != nil {
+		return fmt.Errorf("failed to list GarageS3ClusterInstances: %w", err)
+	}
+
+	var instances []garageInstance
+	for i := range instanceList.Items {
+		instances = append(instances, &instanceList.Items[i])
+	}
+	for i := range clusterInstanceList.Items {
+		instances = append(instances, &clusterInstanceList.Items[i])
+	}
+
+	var notConnected []string
+	for _, instance := range instances {
+		connected := false
+		for _, cond := range instance.GetInstanceStatus().Conditions {
+			if cond.Type == "Ready" && cond.Status == metav1.ConditionTrue {
+				connected = true
+				break
+			}
+		}
+		if !connected {
+			name := instance.GetName()
+			if instance.GetNamespace() != "" {
+				name = instance.GetNamespace() + "/" + name
+			}
+			notConnected = append(notConnected, name)
+		}
+	}
+	if len(notConnected) > 0 {
+		return fmt.Errorf("%d/%d Garage instances not connected: %s", len(notConnected), len(instances), strings.Join(notConnected, ", "))
+	}
+	return nil
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func newInstanceWithReady(name string, status metav1.ConditionStatus) *v1.GarageS3Instance {
+	return &v1.GarageS3Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "garage"},
+		Status: v1.GarageS3InstanceStatus{
+			Conditions: []metav1.Condition{{Type: "Ready", Status: status, Reason: "Test"}},
+		},
+	}
+}
+
+func TestInstanceConnectivityChecker(t *testing.T) {
+	tests := []struct {
+		name      string
+		instances []client.Object
+		wantErr   string
+	}{
+		{
+			name: "no instances is ready",
+		},
+		{
+			name:      "all instances connected",
+			instances: []client.Object{newInstanceWithReady("a", metav1.ConditionTrue), newInstanceWithReady("b", metav1.ConditionTrue)},
+		},
+		{
+			name:      "one instance not connected",
+			instances: []client.Object{newInstanceWithReady("a", metav1.ConditionTrue), newInstanceWithReady("b", metav1.ConditionFalse)},
+			wantErr:   "1/2 Garage instances not connected: garage/b",
+		},
+		{
+			name: "cluster instance not connected",
+			instances: []client.Object{
+				newInstanceWithReady("a", metav1.ConditionTrue),
+				&v1.GarageS3ClusterInstance{ObjectMeta: metav1.ObjectMeta{Name: "platform"}},
+			},
+			wantErr: "1/2 Garage instances not connected: platform",
+		},
+		{
+			name:      "instance without status",
+			instances: []client.Object{&v1.GarageS3Instance{ObjectMeta: metav1.ObjectMeta{Name: "new", Namespace: "garage"}}},
+			wantErr:   "garage/new",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(tt.instances...).
+				Build()
+			checker := &instanceConnectivityChecker{Reader: fakeClient}
+
+			err := checker.Check(httptest.NewRequest("GET", "/readyz", nil))
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("expected ready, got: %v", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error containing %q, got: %v", tt.wantErr, err)
+			}
+		})
+	}
+}
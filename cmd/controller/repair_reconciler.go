@@ -0,0 +1,355 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	repairTrackInterval        = 30 * time.Second
+	repairErrorRequeueInterval = 30 * time.Second
+)
+
+// repairTypes are the repairs Garage can launch, scrub taking a command.
+var repairTypes = map[string]bool{
+	"tables":           true,
+	"blocks":           true,
+	"versions":         true,
+	"multipartUploads": true,
+	"blockRefs":        true,
+	"blockRc":          true,
+	"rebalance":        true,
+	"aliases":          true,
+	"clearResyncQueue": true,
+	"scrub":            true,
+}
+
+var scrubCommands = map[string]bool{
+	"start":  true,
+	"pause":  true,
+	"resume": true,
+	"cancel": true,
+}
+
+// repairJobReconciler is a reconciler for GarageS3RepairJob resources.
+type repairJobReconciler struct {
+	client.Client
+	scheme        *runtime.Scheme
+	garageClients GarageClientFactory
+	recorder      record.EventRecorder
+}
+
+// UpdateStatus sets the Ready condition and writes the status, returning the error of the write.
+func (r *repairJobReconciler) UpdateStatus(ctx context.Context, status metav1.ConditionStatus, reason string, message string, job *v1.GarageS3RepairJob) error {
+	cond := metav1.Condition{
+		Type:    "Ready",
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	// Replace existing Ready condition if present, preserve LastTransitionTime when status unchanged
+	found := false
+	for i := range job.Status.Conditions {
+		if job.Status.Conditions[i].Type == "Ready" {
+			if job.Status.Conditions[i].Status == cond.Status {
+				cond.LastTransitionTime = job.Status.Conditions[i].LastTransitionTime
+			} else {
+				cond.LastTransitionTime = metav1.Now()
+			}
+			job.Status.Conditions[i] = cond
+			found = true
+			break
+		}
+	}
+	if !found {
+		cond.LastTransitionTime = metav1.Now()
+		job.Status.Conditions = append(job.Status.Conditions, cond)
+	}
+
+	if err := r.Status().Update(ctx, job); err != nil {
+		log.Log.Error(err, "Failed to update GarageS3RepairJob status")
+		return err
+	}
+	return nil
+}
+
+// RepairRequest builds the launch request of a repair, in the wire format of the repair API.
+func RepairRequest(spec v1.GarageS3RepairJobSpec) (garage.LocalLaunchRepairOperationRequest, error) {
+	if !repairTypes[spec.Repair] {
+		return garage.LocalLaunchRepairOperationRequest{}, fmt.Errorf("unknown repair %q", spec.Repair)
+	}
+	var repairType any = spec.Repair
+	if spec.Repair == "scrub" {
+		if !scrubCommands[spec.ScrubCommand] {
+			return garage.LocalLaunchRepairOperationRequest{}, fmt.Errorf("scrub needs a scrubCommand among start, pause, resume and cancel, got %q", spec.ScrubCommand)
+		}
+		repairType = map[string]string{"scrub": spec.ScrubCommand}
+	}
+	request := garage.LocalLaunchRepairOperationRequest{}
+	if err := convertJSON(map[string]any{"repairType": repairType}, &request); err != nil {
+		return garage.LocalLaunchRepairOperationRequest{}, err
+	}
+	return request, nil
+}
+
+// NewWorkers returns the workers which were not running before the launch of a repair.
+func NewWorkers(before []v1.GarageS3WorkerStatus, after []v1.GarageS3WorkerStatus) []v1.GarageS3WorkerStatus {
+	known := map[string]map[int64]bool{}
+	for _, worker := range before {
+		if known[worker.NodeID] == nil {
+			known[worker.NodeID] = map[int64]bool{}
+		}
+		known[worker.NodeID][worker.WorkerID] = true
+	}
+	workers := []v1.GarageS3WorkerStatus{}
+	for _, worker := range after {
+		if !known[worker.NodeID][worker.WorkerID] {
+			workers = append(workers, worker)
+		}
+	}
+	return workers
+}
+
+// TrackWorkers refreshes the tracked workers from a worker listing. A worker which
+// disappeared from a node that answered is done. It returns true when all workers are done.
+func TrackWorkers(tracked []v1.GarageS3WorkerStatus, list workerList) bool {
+	current := map[string]map[int64]v1.GarageS3WorkerStatus{}
+	for _, worker := range WorkerStatuses(list) {
+		if current[worker.NodeID] == nil {
+			current[worker.NodeID] = map[int64]v1.GarageS3WorkerStatus{}
+		}
+		current[worker.NodeID][worker.WorkerID] = worker
+	}
+	done := true
+	for i := range tracked {
+		if _, answered := list.Success[tracked[i].NodeID]; answered {
+			if worker, found := current[tracked[i].NodeID][tracked[i].WorkerID]; found {
+				tracked[i] = worker
+			} else {
+				tracked[i].State = "done"
+			}
+		}
+		if tracked[i].State != "done" {
+			done = false
+		}
+	}
+	return done
+}
+
+// Complete ends the current run of the job, failed when the repair could not be launched on some node,
+// and returns the error of the status write.
+func (r *repairJobReconciler) Complete(ctx context.Context, job *v1.GarageS3RepairJob) error {
+	job.Status.CompletionTime = &metav1.Time{Time: time.Now()}
+	failed := 0
+	for _, result := range job.Status.Nodes {
+		if !result.Success {
+			failed++
+		}
+	}
+	if failed > 0 {
+		job.Status.Phase = v1.RepairJobPhaseFailed
+		return r.UpdateStatus(ctx, metav1.ConditionFalse, "RepairFailed", fmt.Sprintf("Repair %s failed on %d/%d nodes", job.Spec.Repair, failed, len(job.Status.Nodes)), job)
+	}
+	job.Status.Phase = v1.RepairJobPhaseSucceeded
+	return r.UpdateStatus(ctx, metav1.ConditionTrue, "RepairCompleted", fmt.Sprintf("Repair %s completed on %d nodes", job.Spec.Repair, len(job.Status.Nodes)), job)
+}
+
+// Reconcile performs reconciliation for GarageS3RepairJob.
+func (r *repairJobReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithValues("GarageS3RepairJob", req.NamespacedName)
+
+	job := &v1.GarageS3RepairJob{}
+	if err := r.Get(ctx, req.NamespacedName, job); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	request, err := RepairRequest(job.Spec)
+	if err != nil {
+		log.Error(err, "Invalid repair")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "SyntaxError", err.Error(), job)
+		return ctrl.Result{}, nil // spec error, no point retrying until spec changes
+	}
+	var sched cron.Schedule
+	if job.Spec.Schedule != "" {
+		if sched, err = cron.ParseStandard(job.Spec.Schedule); err != nil {
+			log.Error(err, "Failed to parse repair schedule")
+			r.UpdateStatus(ctx, metav1.ConditionFalse, "SyntaxError", err.Error(), job)
+			return ctrl.Result{}, nil // spec error, no point retrying until spec changes
+		}
+	}
+
+	// A new run is launched once the previous one is over, missed schedules not being caught up
+	now := time.Now()
+	if job.Status.Phase != v1.RepairJobPhaseRunning {
+		if sched == nil {
+			if job.Status.Phase != "" {
+				return ctrl.Result{}, nil
+			}
+		} else {
+			last := job.CreationTimestamp.Time
+			if job.Status.StartTime != nil {
+				last = job.Status.StartTime.Time
+			}
+			if next := sched.Next(last); now.Before(next) {
+				if len(job.Status.Conditions) == 0 {
+					r.UpdateStatus(ctx, metav1.ConditionTrue, "Scheduled", "First repair at "+next.UTC().Format(time.RFC3339), job)
+				}
+				return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+			}
+		}
+	}
+
+	// Fetch the associated instance
+	instanceRef := job.Spec.InstanceRef
+	instance, err := GetInstanceForRef(ctx, r, instanceRef)
+	if err != nil {
+		log.Error(err, "Failed to get associated GarageS3Instance", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "InstanceNotFound", "Associated GarageS3Instance not found", job)
+		return ctrl.Result{RequeueAfter: repairErrorRequeueInterval}, err
+	}
+
+	garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
+	if err != nil {
+		log.Error(err, "Failed to create Garage S3 client for associated instance", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageClientError", "Could not create Garage S3 client", job)
+		return ctrl.Result{RequeueAfter: repairErrorRequeueInterval}, err
+	}
+
+	if job.Status.Phase == v1.RepairJobPhaseRunning {
+		return r.Track(ctx, garageClient, apiCtx, job, sched)
+	}
+
+	clusterStatus, _, err := garageClient.ClusterAPI.GetClusterStatus(apiCtx).Execute()
+	if err != nil {
+		log.Error(err, "Failed to get Garage cluster status")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when retrieving cluster status from Garage", job)
+		return ctrl.Result{RequeueAfter: repairErrorRequeueInterval}, err
+	}
+	ids := []string{}
+	for _, node := range clusterStatus.Nodes {
+		ids = append(ids, node.Id)
+	}
+	targets, err := ResolveNodeTargets(job.Spec.Nodes, ids)
+	if err != nil {
+		log.Info("Repair nodes not resolved", "Reason", err.Error())
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "NodeNotFound", err.Error(), job)
+		return ctrl.Result{RequeueAfter: repairErrorRequeueInterval}, nil
+	}
+
+	// Workers are not tied to the repair that started them, the ones started by the
+	// launch are found by comparing the workers before and after it
+	before, err := ListWorkers(garageClient, apiCtx, targets)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	// The run is recorded before the launch so that a lost status write cannot launch it twice,
+	// a run interrupted before its workers are recorded being completed by the next reconcile
+	job.Status.Phase = v1.RepairJobPhaseRunning
+	job.Status.StartTime = &metav1.Time{Time: now}
+	job.Status.CompletionTime = nil
+	job.Status.Nodes = nil
+	job.Status.Workers = nil
+	if err := r.Status().Update(ctx, job); err != nil {
+		log.Error(err, "Failed to record the repair run")
+		return ctrl.Result{}, err
+	}
+
+	results := []v1.GarageS3NodeOperationStatus{}
+	for _, target := range targets {
+		res, _, err := garageClient.NodeAPI.LaunchRepairOperation(apiCtx).Node(target).LocalLaunchRepairOperationRequest(request).Execute()
+		if err != nil {
+			results = append(results, v1.GarageS3NodeOperationStatus{NodeID: target, Error: err.Error()})
+			continue
+		}
+		response := multiNodeResponse{}
+		if err := convertJSON(res, &response); err != nil {
+			return ctrl.Result{}, err
+		}
+		results = append(results, NodeResults(response)...)
+	}
+	for _, result := range results {
+		if !result.Success {
+			r.recorder.Eventf(job, corev1.EventTypeWarning, "RepairFailed", "Repair %s failed on node %s: %s", job.Spec.Repair, result.NodeID, result.Error)
+		}
+	}
+
+	after, err := ListWorkers(garageClient, apiCtx, targets)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	log.Info("Launched repair", "Repair", job.Spec.Repair, "Nodes", len(results))
+	job.Status.Nodes = results
+	job.Status.Workers = NewWorkers(WorkerStatuses(before), WorkerStatuses(after))
+
+	// Scrub commands are handled by the long-running scrub worker, and quick repairs may
+	// be over before being listed: without new workers the run is over
+	if len(job.Status.Workers) == 0 {
+		if err := r.Complete(ctx, job); err != nil {
+			return ctrl.Result{}, err
+		}
+		return r.Requeue(sched), nil
+	}
+	if err := r.UpdateStatus(ctx, metav1.ConditionFalse, "Running", fmt.Sprintf("Repair %s running on %d workers", job.Spec.Repair, len(job.Status.Workers)), job); err != nil {
+		return ctrl.Result{}, err
+	}
+	return ctrl.Result{RequeueAfter: repairTrackInterval}, nil
+}
+
+// Track follows the workers of the current run until they are done.
+func (r *repairJobReconciler) Track(ctx context.Context, garageClient *garage.APIClient, apiCtx context.Context, job *v1.GarageS3RepairJob, sched cron.Schedule) (ctrl.Result, error) {
+	log := log.FromContext(ctx)
+
+	nodes := []string{}
+	seen := map[string]bool{}
+	for _, worker := range job.Status.Workers {
+		if !seen[worker.NodeID] {
+			seen[worker.NodeID] = true
+			nodes = append(nodes, worker.NodeID)
+		}
+	}
+	list, err := ListWorkers(garageClient, apiCtx, nodes)
+	if err != nil {
+		return ctrl.Result{}, err
+	}
+
+	if !TrackWorkers(job.Status.Workers, list) {
+		running := 0
+		for _, worker := range job.Status.Workers {
+			if worker.State != "done" {
+				running++
+			}
+		}
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "Running", fmt.Sprintf("Repair %s running on %d/%d workers", job.Spec.Repair, running, len(job.Status.Workers)), job)
+		return ctrl.Result{RequeueAfter: repairTrackInterval}, nil
+	}
+
+	log.Info("Repair completed", "Repair", job.Spec.Repair)
+	if err := r.Complete(ctx, job); err != nil {
+		return ctrl.Result{}, err
+	}
+	return r.Requeue(sched), nil
+}
+
+// Requeue schedules the next run of a scheduled job.
+func (r *repairJobReconciler) Requeue(sched cron.Schedule) ctrl.Result {
+	if sched == nil {
+		return ctrl.Result{}
+	}
+	now := time.Now()
+	return ctrl.Result{RequeueAfter: sched.Next(now).Sub(now)}
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestRepairRequest(t *testing.T) {
+	if _, err := RepairRequest(v1.GarageS3RepairJobSpec{Repair: "tables"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := RepairRequest(v1.GarageS3RepairJobSpec{Repair: "scrub", ScrubCommand: "start"}); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if _, err := RepairRequest(v1.GarageS3RepairJobSpec{Repair: "scrub"}); err == nil {
+		t.Errorf("expected an error for a scrub without command")
+	}
+	if _, err := RepairRequest(v1.GarageS3RepairJobSpec{Repair: "everything"}); err == nil {
+		t.Errorf("expected an error for an unknown repair")
+	}
+}
+
+func TestTrackWorkers(t *testing.T) {
+	before := []v1.GarageS3WorkerStatus{{NodeID: "a", WorkerID: 1}, {NodeID: "b", WorkerID: 1}}
+	after := []v1.GarageS3WorkerStatus{{NodeID: "a", WorkerID: 1}, {NodeID: "a", WorkerID: 5}, {NodeID: "b", WorkerID: 1}, {NodeID: "b", WorkerID: 4}}
+
+	tracked := NewWorkers(before, after)
+	if len(tracked) != 2 || tracked[0].WorkerID != 5 || tracked[1].WorkerID != 4 {
+		t.Fatalf("unexpected new workers %+v", tracked)
+	}
+
+	// Worker 5 is still busy, node b didn't answer
+	list := workerList{
+		Success: map[string][]workerInfo{"a": {{ID: 5, Name: "Table repair", State: json.RawMessage(`"busy"`)}}},
+		Error:   map[string]string{"b": "unreachable"},
+	}
+	if TrackWorkers(tracked, list) {
+		t.Fatalf("expected workers to be running")
+	}
+	if tracked[0].State != "busy" || tracked[1].State == "done" {
+		t.Errorf("unexpected workers %+v", tracked)
+	}
+
+	// Worker 5 is done and worker 4 is gone
+	list = workerList{Success: map[string][]workerInfo{
+		"a": {{ID: 5, State: json.RawMessage(`"done"`)}},
+		"b": {},
+	}}
+	if !TrackWorkers(tracked, list) {
+		t.Errorf("expected workers to be done, got %+v", tracked)
+	}
+}
+
+func TestRepairJobReconciler_FakeGarage(t *testing.T) {
+	tests := []struct {
+		name string
+		// Make the status writes fail
+		statusFailure bool
+		expectError   bool
+		expectedCalls int
+	}{
+		{name: "repair launched once", expectedCalls: 1},
+		{name: "status write failure", statusFailure: true, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			job := &v1.GarageS3RepairJob{
+				ObjectMeta: metav1.ObjectMeta{Name: "tables", Namespace: "default"},
+				Spec:       v1.GarageS3RepairJobSpec{InstanceRef: env.instanceRef(), Repair: "tables"},
+			}
+			funcs := interceptor.Funcs{}
+			if tt.statusFailure {
+				funcs.SubResourceUpdate = func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					return errors.New("status write lost")
+				}
+			}
+			c := env.clientWithInterceptor(funcs, job)
+			r := &repairJobReconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c), recorder: record.NewFakeRecorder(10)}
+
+			// A second reconciliation of the one-shot job launches no other run
+			var err error
+			for range 2 {
+				_, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(job)})
+				if tt.expectError != (err != nil) {
+					t.Fatalf("expected error %v, got %v", tt.expectError, err)
+				}
+			}
+			if calls := env.garage.Calls("LaunchRepairOperation"); calls != tt.expectedCalls {
+				t.Errorf("expected %d launches, got %d", tt.expectedCalls, calls)
+			}
+			if tt.expectError {
+				return
+			}
+
+			got := &v1.GarageS3RepairJob{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(job), got); err != nil {
+				t.Fatalf("failed to get repair job: %v", err)
+			}
+			if got.Status.Phase != v1.RepairJobPhaseSucceeded || got.Status.StartTime == nil || len(got.Status.Nodes) != 1 {
+				t.Errorf("expected the completed run in status, got %+v", got.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"github.com/robfig/cron/v3"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const snapshotErrorRequeueInterval = 30 * time.Second
+
+// metadataSnapshotReconciler is a reconciler for GarageS3MetadataSnapshotSchedule resources.
+type metadataSnapshotReconciler struct {
+	client.Client
+	scheme        *runtime.Scheme
+	garageClients GarageClientFactory
+	recorder      record.EventRecorder
+}
+
+// multiNodeResponse is the response of an admin API call on several nodes, in its wire format.
+type multiNodeResponse struct {
+	Success map[string]json.RawMessage `json:"success"`
+	Error   map[string]string          `json:"error"`
+}
+
+// NodeResults converts the response of a multi-node call to per-node results, sorted by node.
+func NodeResults(res multiNodeResponse) []v1.GarageS3NodeOperationStatus {
+	results := []v1.GarageS3NodeOperationStatus{}
+	for id := range res.Success {
+		results = append(results, v1.GarageS3NodeOperationStatus{NodeID: id, Success: true})
+	}
+	for id, message := range res.Error {
+		results = append(results, v1.GarageS3NodeOperationStatus{NodeID: id, Error: message})
+	}
+	sort.Slice(results, func(i, j int) bool { return results[i].NodeID < results[j].NodeID })
+	return results
+}
+
+// ResolveNodeTargets resolves the node IDs or prefixes an operation targets,
+// all nodes being targeted with * when none is given.
+func ResolveNodeTargets(selectors []string, ids []string) ([]string, error) {
+	if len(selectors) == 0 {
+		return []string{"*"}, nil
+	}
+	targets := []string{}
+	for _, selector := range selectors {
+		id, err := ResolveNodeID(selector, ids)
+		if err != nil {
+			return nil, err
+		}
+		targets = append(targets, id)
+	}
+	return targets, nil
+}
+
+// UpdateStatus sets the Ready condition and writes the status, returning the error of the write.
+func (r *metadataSnapshotReconciler) UpdateStatus(ctx context.Context, status metav1.ConditionStatus, reason string, message string, schedule *v1.GarageS3MetadataSnapshotSchedule) error {
+	cond := metav1.Condition{
+		Type:    "Ready",
+		Status:  status,
+		Reason:  reason,
+		Message: message,
+	}
+
+	// Replace existing Ready condition if present, preserve LastTransitionTime when status unchanged
+	found := false
+	for i := range schedule.Status.Conditions {
+		if schedule.Status.Conditions[i].Type == "Ready" {
+			if schedule.Status.Conditions[i].Status == cond.Status {
+				cond.LastTransitionTime = schedule.Status.Conditions[i].LastTransitionTime
+			} else {
+				cond.LastTransitionTime = metav1.Now()
+			}
+			schedule.Status.Conditions[i] = cond
+			found = true
+			break
+		}
+	}
+	if !found {
+		cond.LastTransitionTime = metav1.Now()
+		schedule.Status.Conditions = append(schedule.Status.Conditions, cond)
+	}
+
+	if err := r.Status().Update(ctx, schedule); err != nil {
+		log.Log.Error(err, "Failed to update GarageS3MetadataSnapshotSchedule status")
+		return err
+	}
+	return nil
+}
+
+// Reconcile performs reconciliation for GarageS3MetadataSnapshotSchedule.
+func (r *metadataSnapshotReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	log := log.FromContext(ctx).WithValues("GarageS3MetadataSnapshotSchedule", req.NamespacedName)
+
+	schedule := &v1.GarageS3MetadataSnapshotSchedule{}
+	if err := r.Get(ctx, req.NamespacedName, schedule); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	sched, err := cron.ParseStandard(schedule.Spec.Schedule)
+	if err != nil {
+		log.Error(err, "Failed to parse snapshot schedule")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "SyntaxError", err.Error(), schedule)
+		return ctrl.Result{}, nil // spec error, no point retrying until spec changes
+	}
+
+	if schedule.Spec.Suspend {
+		r.UpdateStatus(ctx, metav1.ConditionTrue, "Suspended", "Snapshot schedule is suspended", schedule)
+		return ctrl.Result{}, nil
+	}
+
+	// Missed schedules are not caught up, a single snapshot is taken
+	now := time.Now()
+	last := schedule.CreationTimestamp.Time
+	if schedule.Status.LastScheduleTime != nil {
+		last = schedule.Status.LastScheduleTime.Time
+	}
+	if next := sched.Next(last); now.Before(next) {
+		if len(schedule.Status.Conditions) == 0 {
+			r.UpdateStatus(ctx, metav1.ConditionTrue, "Scheduled", "First snapshot at "+next.UTC().Format(time.RFC3339), schedule)
+		}
+		return ctrl.Result{RequeueAfter: next.Sub(now)}, nil
+	}
+
+	// Fetch the associated instance
+	instanceRef := schedule.Spec.InstanceRef
+	instance, err := GetInstanceForRef(ctx, r, instanceRef)
+	if err != nil {
+		log.Error(err, "Failed to get associated GarageS3Instance", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "InstanceNotFound", "Associated GarageS3Instance not found", schedule)
+		return ctrl.Result{RequeueAfter: snapshotErrorRequeueInterval}, err
+	}
+
+	garageClient, apiCtx, err := CreateGarageClient(ctx, r.garageClients, instance)
+	if err != nil {
+		log.Error(err, "Failed to create Garage S3 client for associated instance", "InstanceRef", instanceRef)
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageClientError", "Could not create Garage S3 client", schedule)
+		return ctrl.Result{RequeueAfter: snapshotErrorRequeueInterval}, err
+	}
+
+	clusterStatus, _, err := garageClient.ClusterAPI.GetClusterStatus(apiCtx).Execute()
+	if err != nil {
+		log.Error(err, "Failed to get Garage cluster status")
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "GarageAPIError", "Error when retrieving cluster status from Garage", schedule)
+		return ctrl.Result{RequeueAfter: snapshotErrorRequeueInterval}, err
+	}
+	ids := []string{}
+	for _, node := range clusterStatus.Nodes {
+		ids = append(ids, node.Id)
+	}
+	targets, err := ResolveNodeTargets(schedule.Spec.Nodes, ids)
+	if err != nil {
+		log.Info("Snapshot nodes not resolved", "Reason", err.Error())
+		r.UpdateStatus(ctx, metav1.ConditionFalse, "NodeNotFound", err.Error(), schedule)
+		return ctrl.Result{RequeueAfter: snapshotErrorRequeueInterval}, nil
+	}
+
+	// Record the schedule before taking the snapshots, so that a lost status write can't
+	// make them taken twice
+	schedule.Status.LastScheduleTime = &metav1.Time{Time: now}
+	if err := r.Status().Update(ctx, schedule); err != nil {
+		log.Error(err, "Failed to record the snapshot schedule time")
+		return ctrl.Result{RequeueAfter: snapshotErrorRequeueInterval}, err
+	}
+
+	// Take the snapshots, a failing node not preventing the others
+	results := []v1.GarageS3NodeOperationStatus{}
+	for _, target := range targets {
+		res, _, err := garageClient.NodeAPI.CreateMetadataSnapshot(apiCtx).Node(target).Execute()
+		if err != nil {
+			results = append(results, v1.GarageS3NodeOperationStatus{NodeID: target, Error: err.Error()})
+			continue
+		}
+		response := multiNodeResponse{}
+		if err := convertJSON(res, &response); err != nil {
+			return ctrl.Result{}, err
+		}
+		results = append(results, NodeResults(response)...)
+	}
+
+	schedule.Status.Nodes = results
+	failed := 0
+	for _, result := range results {
+		if !result.Success {
+			failed++
+			r.recorder.Eventf(schedule, corev1.EventTypeWarning, "SnapshotFailed", "Metadata snapshot failed on node %s: %s", result.NodeID, result.Error)
+		}
+	}
+	requeueAfter := sched.Next(now).Sub(now)
+	if failed > 0 {
+		log.Info("Metadata snapshot failed on some nodes", "Failed", failed, "Nodes", len(results))
+		if err := r.UpdateStatus(ctx, metav1.ConditionFalse, "SnapshotFailed", fmt.Sprintf("Metadata snapshot failed on %d/%d nodes", failed, len(results)), schedule); err != nil {
+			return ctrl.Result{RequeueAfter: requeueAfter}, err
+		}
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	log.Info("Metadata snapshot taken", "Nodes", len(results))
+	schedule.Status.LastSuccessfulTime = &metav1.Time{Time: now}
+	if err := r.UpdateStatus(ctx, metav1.ConditionTrue, "SnapshotTaken", fmt.Sprintf("Metadata snapshot taken on %d nodes", len(results)), schedule); err != nil {
+		return ctrl.Result{RequeueAfter: requeueAfter}, err
+	}
+	return ctrl.Result{RequeueAfter: requeueAfter}, nil
+}
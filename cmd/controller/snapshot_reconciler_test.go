@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func TestNodeResults(t *testing.T) {
+	raw := `{"success": {"b": {}, "a": {}}, "error": {"c": "snapshot already in progress"}}`
+	response := multiNodeResponse{}
+	if err := json.Unmarshal([]byte(raw), &response); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	results := NodeResults(response)
+
+	if len(results) != 3 || results[0].NodeID != "a" || results[1].NodeID != "b" || results[2].NodeID != "c" {
+		t.Fatalf("unexpected results %+v", results)
+	}
+	if !results[0].Success || !results[1].Success || results[2].Success || results[2].Error != "snapshot already in progress" {
+		t.Errorf("unexpected results %+v", results)
+	}
+}
+
+func TestResolveNodeTargets(t *testing.T) {
+	ids := []string{"563e1ac825ee3323", "a1b2c3d4e5f60718"}
+
+	targets, err := ResolveNodeTargets(nil, ids)
+	if err != nil || len(targets) != 1 || targets[0] != "*" {
+		t.Errorf("expected all nodes, got %v (%v)", targets, err)
+	}
+	targets, err = ResolveNodeTargets([]string{"a1b2", "563e"}, ids)
+	if err != nil || len(targets) != 2 || targets[0] != "a1b2c3d4e5f60718" || targets[1] != "563e1ac825ee3323" {
+		t.Errorf("unexpected targets %v (%v)", targets, err)
+	}
+	if _, err := ResolveNodeTargets([]string{"ffff"}, ids); err == nil {
+		t.Errorf("expected an error for an unknown node")
+	}
+}
+
+func TestMetadataSnapshotReconciler_FakeGarage(t *testing.T) {
+	tests := []struct {
+		name string
+		// Make the status writes fail
+		statusFailure bool
+		expectError   bool
+		expectedCalls int
+	}{
+		{name: "snapshot taken once", expectedCalls: 1},
+		{name: "status write failure", statusFailure: true, expectError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			schedule := &v1.GarageS3MetadataSnapshotSchedule{
+				ObjectMeta: metav1.ObjectMeta{Name: "nightly", Namespace: "default", CreationTimestamp: metav1.NewTime(time.Now().Add(-time.Hour))},
+				Spec:       v1.GarageS3MetadataSnapshotScheduleSpec{InstanceRef: env.instanceRef(), Schedule: "*/5 * * * *"},
+			}
+			funcs := interceptor.Funcs{}
+			if tt.statusFailure {
+				funcs.SubResourceUpdate = func(ctx context.Context, c client.Client, subResourceName string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					return errors.New("status write lost")
+				}
+			}
+			c := env.clientWithInterceptor(funcs, schedule)
+			r := &metadataSnapshotReconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c), recorder: record.NewFakeRecorder(10)}
+
+			// A second reconciliation right after the first one takes no other snapshot
+			var err error
+			for range 2 {
+				_, err = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(schedule)})
+				if tt.expectError != (err != nil) {
+					t.Fatalf("expected error %v, got %v", tt.expectError, err)
+				}
+			}
+			if calls := env.garage.Calls("CreateMetadataSnapshot"); calls != tt.expectedCalls {
+				t.Errorf("expected %d snapshots, got %d", tt.expectedCalls, calls)
+			}
+			if tt.expectError {
+				return
+			}
+
+			got := &v1.GarageS3MetadataSnapshotSchedule{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(schedule), got); err != nil {
+				t.Fatalf("failed to get schedule: %v", err)
+			}
+			if got.Status.LastScheduleTime == nil || len(got.Status.Nodes) != 1 || !got.Status.Nodes[0].Success {
+				t.Errorf("expected the snapshot of the node in status, got %+v", got.Status)
+			}
+		})
+	}
+}
@@ -0,0 +1,435 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	"abucquet.com/garage-s3-operator/api/v1beta2"
+	"abucquet.com/garage-s3-operator/internal/garagefake"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+	metricsserver "sigs.k8s.io/controller-runtime/pkg/metrics/server"
+	"sigs.k8s.io/controller-runtime/pkg/webhook"
+)
+
+// The envtest suite runs the manager against a real API server loading the CRDs of config/crd,
+// with the fake Garage backend and the conversion webhook. It needs the binaries installed by setup-envtest, found through
+// KUBEBUILDER_ASSETS, and is skipped otherwise.
+var (
+	k8sClient  client.Client
+	testGarage *garagefake.Server
+)
+
+const (
+	envtestTimeout  = 30 * time.Second
+	envtestInterval = 250 * time.Millisecond
+)
+
+func TestMain(m *testing.M) {
+	os.Exit(runTests(m))
+}
+
+// runTests starts the envtest API server and the manager around the tests when the
+// envtest binaries are available.
+func runTests(m *testing.M) int {
+	if os.Getenv("KUBEBUILDER_ASSETS") == "" {
+		return m.Run()
+	}
+
+	ctrl.SetLogger(zap.New(zap.UseDevMode(envEnabled("DEBUG"))))
+	// The scheme enables the conversion webhook of the convertible CRDs, served by the manager
+	testEnv := &envtest.Environment{
+		Scheme:                scheme,
+		CRDDirectoryPaths:     []string{filepath.Join("..", "..", "config", "crd")},
+		ErrorIfCRDPathMissing: true,
+	}
+	config, err := testEnv.Start()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to start envtest: %v\n", err)
+		return 1
+	}
+	defer func() {
+		if err := testEnv.Stop(); err != nil {
+			fmt.Fprintf(os.Stderr, "Failed to stop envtest: %v\n", err)
+		}
+	}()
+
+	testGarage = garagefake.NewServer(fakeAdminToken)
+	defer testGarage.Close()
+
+	webhookOptions := testEnv.WebhookInstallOptions
+	mgr, err := ctrl.NewManager(config, ctrl.Options{
+		Scheme:  scheme,
+		Metrics: metricsserver.Options{BindAddress: "0"},
+		WebhookServer: webhook.NewServer(webhook.Options{
+			Host:    webhookOptions.LocalServingHost,
+			Port:    webhookOptions.LocalServingPort,
+			CertDir: webhookOptions.LocalServingCertDir,
+		}),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create manager: %v\n", err)
+		return 1
+	}
+	if err := setupControllers(mgr, NewGarageClientFactory(mgr.GetClient())); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create controllers: %v\n", err)
+		return 1
+	}
+	if err := setupWebhooks(mgr); err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create webhooks: %v\n", err)
+		return 1
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		if err := mgr.Start(ctx); err != nil {
+			fmt.Fprintf(os.Stderr, "Problem running manager: %v\n", err)
+		}
+	}()
+
+	// Tests use a direct client, to observe the API server without the cache delay
+	k8sClient, err = client.New(config, client.Options{Scheme: scheme})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Failed to create client: %v\n", err)
+		return 1
+	}
+	return m.Run()
+}
+
+// requireEnvtest skips the test when the envtest API server isn't running.
+func requireEnvtest(t *testing.T) {
+	t.Helper()
+	if k8sClient == nil {
+		t.Skip("KUBEBUILDER_ASSETS not set, skipping envtest suite")
+	}
+}
+
+// newEnvtestNamespace creates a namespace holding the admin token Secret of the fake Garage,
+// namespaces can't be removed by envtest which has no namespace controller.
+func newEnvtestNamespace(t *testing.T) string {
+	t.Helper()
+	ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{GenerateName: "envtest-"}}
+	if err := k8sClient.Create(context.Background(), ns); err != nil {
+		t.Fatalf("failed to create namespace: %v", err)
+	}
+	secret := &corev1.Secret{
+		ObjectMeta: metav1.ObjectMeta{Name: fakeAdminTokenSecret, Namespace: ns.Name},
+		Data:       map[string][]byte{"token": []byte(fakeAdminToken)},
+	}
+	if err := k8sClient.Create(context.Background(), secret); err != nil {
+		t.Fatalf("failed to create admin token secret: %v", err)
+	}
+	return ns.Name
+}
+
+// newEnvtestInstance creates a GarageS3Instance pointing to the fake Garage and waits for it to be connected.
+func newEnvtestInstance(t *testing.T, namespace string) *v1.GarageS3Instance {
+	t.Helper()
+	host, port := testGarage.Address()
+	instance := &v1.GarageS3Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "garage", Namespace: namespace},
+		Spec: v1.GarageS3InstanceSpec{
+			Url:              host,
+			Port:             port,
+			AdminTokenSecret: fakeAdminTokenSecret,
+		},
+	}
+	if err := k8sClient.Create(context.Background(), instance); err != nil {
+		t.Fatalf("failed to create instance: %v", err)
+	}
+	waitForReady(t, instance, "Connected", func() []metav1.Condition { return instance.Status.Conditions })
+	return instance
+}
+
+// eventually polls the condition until it returns nil, failing the test with its last error on timeout.
+func eventually(t *testing.T, condition func() error) {
+	t.Helper()
+	var last error
+	err := wait.PollUntilContextTimeout(context.Background(), envtestInterval, envtestTimeout, true, func(ctx context.Context) (bool, error) {
+		last = condition()
+		return last == nil, nil
+	})
+	if err != nil {
+		t.Fatalf("condition not met after %v: %v", envtestTimeout, last)
+	}
+}
+
+// waitForReady waits for the Ready condition of the object to have the given reason,
+// conditions returning the conditions of the refreshed object.
+func waitForReady(t *testing.T, obj client.Object, reason string, conditions func() []metav1.Condition) {
+	t.Helper()
+	eventually(t, func() error {
+		if err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(obj), obj); err != nil {
+			return err
+		}
+		cond := meta.FindStatusCondition(conditions(), "Ready")
+		if cond == nil || cond.Reason != reason {
+			return fmt.Errorf("expected Ready reason %s, got %+v", reason, cond)
+		}
+		return nil
+	})
+}
+
+// waitForDeletion waits for the object to be removed from the API server.
+func waitForDeletion(t *testing.T, obj client.Object) {
+	t.Helper()
+	eventually(t, func() error {
+		err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(obj), obj)
+		if apierrors.IsNotFound(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		return fmt.Errorf("still present with finalizers %v", obj.GetFinalizers())
+	})
+}
+
+func TestEnvtest_SpecRoundTrip(t *testing.T) {
+	requireEnvtest(t)
+	namespace := newEnvtestNamespace(t)
+	maxObjects, maxBytes := int64(100), int64(1<<30)
+	expectedNodes, maxBuckets := 3, 10
+	capacity := resource.MustParse("100Gi")
+
+	// Specs setting most fields, including CRD defaults, to survive the round trip unchanged
+	objects := []client.Object{
+		&v1.GarageS3Instance{
+			ObjectMeta: metav1.ObjectMeta{Name: "roundtrip", Namespace: namespace},
+			Spec: v1.GarageS3InstanceSpec{
+				Url:              "garage.invalid",
+				Port:             3903,
+				AdminTokenSecret: fakeAdminTokenSecret,
+				Endpoints:        []string{"garage-0.invalid:3903", "garage-1.invalid"},
+				Tenancy: &v1.GarageS3TenancyPolicy{
+					NamespaceSelector:      &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}},
+					MaxBucketsPerNamespace: &maxBuckets,
+					MaxBucketQuota:         &v1.GarageS3BucketQuota{MaxBytes: &maxBytes},
+					BucketNamePrefix:       "{namespace}-",
+				},
+				BucketDefaults: &v1.GarageS3BucketDefaults{
+					Quota:         &v1.GarageS3BucketQuota{MaxObjects: &maxObjects},
+					WebsiteAccess: &v1.GarageS3WebsiteAccess{Enabled: true, IndexDocument: "index.html"},
+					AliasPattern:  "{namespace}-{name}",
+				},
+				Bootstrap: &v1.GarageS3BootstrapPolicy{
+					ZoneLabel:     "topology.kubernetes.io/zone",
+					Capacity:      &capacity,
+					ExpectedNodes: &expectedNodes,
+				},
+				Peers:                 []string{"0123456789abcdef@garage-0.invalid:3901"},
+				PeerService:           &v1.GarageS3PeerService{Name: "garage", RPCPort: 3901},
+				RequireLayoutApproval: true,
+				BlockErrors:           &v1.GarageS3BlockErrorPolicy{AutoRetry: true, UnrecoverableAfter: 5},
+				WorkerVariables:       map[string]string{"resync-tranquility": "2"},
+				NodeWorkerVariables: []v1.GarageS3NodeWorkerVariables{
+					{NodeID: "0123", Variables: map[string]string{"resync-worker-count": "4"}},
+				},
+			},
+		},
+		&v1.GarageS3Bucket{
+			ObjectMeta: metav1.ObjectMeta{Name: "roundtrip", Namespace: namespace},
+			Spec: v1.GarageS3BucketSpec{
+				InstanceRef:       v1.GarageS3InstanceRef{Kind: v1.GarageS3InstanceKind, Name: "roundtrip", Namespace: namespace},
+				WebsiteAccess:     &v1.GarageS3WebsiteAccess{Enabled: true, IndexDocument: "index.html", ErrorDocument: "404.html"},
+				Quota:             &v1.GarageS3BucketQuota{MaxObjects: &maxObjects, MaxBytes: &maxBytes},
+				AdditionalAliases: []string{"roundtrip-alias"},
+				Permissions: []v1.GarageS3BucketPermission{
+					{AccessKeyName: "reader", Read: true},
+					{AccessKeyName: "writer", AccessKeyNamespace: "other", Read: true, Write: true, Owner: true},
+				},
+			},
+		},
+		&v1.GarageS3AccessKey{
+			ObjectMeta: metav1.ObjectMeta{Name: "roundtrip", Namespace: namespace},
+			Spec: v1.GarageS3AccessKeySpec{
+				InstanceRef:     v1.GarageS3InstanceRef{Kind: v1.GarageS3InstanceKind, Name: "roundtrip", Namespace: namespace},
+				CanCreateBucket: true,
+				Expiration:      "2030-01-30T12:00:00Z",
+			},
+		},
+		&v1.GarageS3RepairJob{
+			ObjectMeta: metav1.ObjectMeta{Name: "roundtrip", Namespace: namespace},
+			Spec: v1.GarageS3RepairJobSpec{
+				InstanceRef:  v1.GarageS3InstanceRef{Kind: v1.GarageS3InstanceKind, Name: "roundtrip", Namespace: namespace},
+				Repair:       "scrub",
+				ScrubCommand: "start",
+				Nodes:        []string{"0123"},
+				Schedule:     "0 3 * * 0",
+			},
+		},
+	}
+
+	for _, obj := range objects {
+		expected := obj.DeepCopyObject().(client.Object)
+		if err := k8sClient.Create(context.Background(), obj); err != nil {
+			t.Fatalf("failed to create %T: %v", obj, err)
+		}
+		got := expected.DeepCopyObject().(client.Object)
+		if err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(obj), got); err != nil {
+			t.Fatalf("failed to get %T: %v", obj, err)
+		}
+		expectedSpec := reflect.ValueOf(expected).Elem().FieldByName("Spec").Interface()
+		gotSpec := reflect.ValueOf(got).Elem().FieldByName("Spec").Interface()
+		if !equality.Semantic.DeepEqual(expectedSpec, gotSpec) {
+			t.Errorf("%T spec changed by the API server:\nexpected %+v\ngot      %+v", obj, expectedSpec, gotSpec)
+		}
+	}
+}
+
+func TestEnvtest_AccessKeyLifecycle(t *testing.T) {
+	requireEnvtest(t)
+	namespace := newEnvtestNamespace(t)
+	instance := newEnvtestInstance(t, namespace)
+	if !reflect.DeepEqual(instance.Finalizers, []string{instanceFinalizer}) {
+		t.Errorf("expected the instance finalizer, got %v", instance.Finalizers)
+	}
+
+	ak := &v1.GarageS3AccessKey{
+		ObjectMeta: metav1.ObjectMeta{Name: "lifecycle-key", Namespace: namespace},
+		Spec: v1.GarageS3AccessKeySpec{
+			InstanceRef:     v1.GarageS3InstanceRef{Name: instance.Name, Namespace: namespace},
+			CanCreateBucket: true,
+		},
+	}
+	if err := k8sClient.Create(context.Background(), ak); err != nil {
+		t.Fatalf("failed to create access key: %v", err)
+	}
+	waitForReady(t, ak, "Ready", func() []metav1.Condition { return ak.Status.Conditions })
+	if !reflect.DeepEqual(ak.Finalizers, []string{accessKeyFinalizer}) {
+		t.Errorf("expected the access key finalizer, got %v", ak.Finalizers)
+	}
+	if ak.Spec.InstanceRef.Kind != v1.GarageS3InstanceKind {
+		t.Errorf("expected the instance kind defaulted by the CRD, got %q", ak.Spec.InstanceRef.Kind)
+	}
+
+	// The status subresource ignores status changes made through the main resource
+	updated := ak.DeepCopyObject().(*v1.GarageS3AccessKey)
+	updated.Status.Secret = "overwritten"
+	if err := k8sClient.Update(context.Background(), updated); err != nil {
+		t.Fatalf("failed to update access key: %v", err)
+	}
+	if updated.Status.Secret != ak.Status.Secret {
+		t.Errorf("expected status secret %q to be kept, got %q", ak.Status.Secret, updated.Status.Secret)
+	}
+
+	// The credentials Secret is controlled by the access key, to be garbage collected with it
+	secret := &corev1.Secret{}
+	if err := k8sClient.Get(context.Background(), client.ObjectKey{Name: ak.Status.Secret, Namespace: namespace}, secret); err != nil {
+		t.Fatalf("failed to get the access key Secret: %v", err)
+	}
+	owner := metav1.GetControllerOf(secret)
+	if owner == nil || owner.UID != ak.UID || owner.Kind != "GarageS3AccessKey" {
+		t.Errorf("expected the Secret to be controlled by the access key, got %+v", owner)
+	}
+	key, found := testGarage.KeyByName(ak.Name)
+	if !found || string(secret.Data["AWS_ACCESS_KEY"]) != key.ID {
+		t.Errorf("expected the Secret to hold the credentials of the Garage key")
+	}
+
+	// Deleting the access key removes the Garage key before the finalizer
+	if err := k8sClient.Delete(context.Background(), ak); err != nil {
+		t.Fatalf("failed to delete access key: %v", err)
+	}
+	waitForDeletion(t, ak)
+	if _, found := testGarage.KeyByName(ak.Name); found {
+		t.Error("expected the key to be deleted from Garage")
+	}
+}
+
+func TestEnvtest_InstanceDeletionWaitsForChildren(t *testing.T) {
+	requireEnvtest(t)
+	namespace := newEnvtestNamespace(t)
+	instance := newEnvtestInstance(t, namespace)
+
+	bucket := &v1.GarageS3Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "ordering-bucket", Namespace: namespace},
+		Spec: v1.GarageS3BucketSpec{
+			InstanceRef: v1.GarageS3InstanceRef{Name: instance.Name, Namespace: namespace},
+		},
+	}
+	if err := k8sClient.Create(context.Background(), bucket); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	waitForReady(t, bucket, "Ready", func() []metav1.Condition { return bucket.Status.Conditions })
+	if _, found := testGarage.BucketByAlias(bucket.Name); !found {
+		t.Fatal("expected the bucket to exist in Garage")
+	}
+
+	// The instance is kept while the bucket refers to it
+	if err := k8sClient.Delete(context.Background(), instance); err != nil {
+		t.Fatalf("failed to delete instance: %v", err)
+	}
+	waitForReady(t, instance, "ChildResourcesExist", func() []metav1.Condition { return instance.Status.Conditions })
+	if instance.DeletionTimestamp == nil {
+		t.Error("expected the instance to be marked for deletion")
+	}
+
+	// Deleting the bucket removes it from Garage, then releases the instance
+	if err := k8sClient.Delete(context.Background(), bucket); err != nil {
+		t.Fatalf("failed to delete bucket: %v", err)
+	}
+	waitForDeletion(t, bucket)
+	if _, found := testGarage.BucketByAlias(bucket.Name); found {
+		t.Error("expected the bucket to be deleted from Garage")
+	}
+	waitForDeletion(t, instance)
+}
+
+func TestEnvtest_ConversionWebhook(t *testing.T) {
+	requireEnvtest(t)
+	namespace := newEnvtestNamespace(t)
+	host, port := testGarage.Address()
+
+	// An instance created in v1beta2 is stored and reconciled in v1
+	created := &v1beta2.GarageS3Instance{
+		ObjectMeta: metav1.ObjectMeta{Name: "converted", Namespace: namespace},
+		Spec: v1beta2.GarageS3InstanceSpec{
+			Endpoints:        []string{fmt.Sprintf("http://%s:%d", host, port)},
+			AdminTokenSecret: fakeAdminTokenSecret,
+		},
+	}
+	if err := k8sClient.Create(context.Background(), created); err != nil {
+		t.Fatalf("failed to create v1beta2 instance: %v", err)
+	}
+	instance := &v1.GarageS3Instance{ObjectMeta: created.ObjectMeta}
+	waitForReady(t, instance, "Connected", func() []metav1.Condition { return instance.Status.Conditions })
+	if instance.Spec.Url != host || instance.Spec.Port != port || len(instance.Spec.Endpoints) != 0 {
+		t.Errorf("expected url %s and port %d, got %+v", host, port, instance.Spec)
+	}
+
+	// A bucket created in v1 is read in v1beta2 with its permission set
+	bucket := &v1.GarageS3Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: "converted", Namespace: namespace},
+		Spec: v1.GarageS3BucketSpec{
+			InstanceRef: v1.GarageS3InstanceRef{Name: instance.Name, Namespace: namespace},
+			Permissions: []v1.GarageS3BucketPermission{{AccessKeyName: "writer", Read: true, Write: true}},
+		},
+	}
+	if err := k8sClient.Create(context.Background(), bucket); err != nil {
+		t.Fatalf("failed to create bucket: %v", err)
+	}
+	converted := &v1beta2.GarageS3Bucket{}
+	if err := k8sClient.Get(context.Background(), client.ObjectKeyFromObject(bucket), converted); err != nil {
+		t.Fatalf("failed to get v1beta2 bucket: %v", err)
+	}
+	expected := []v1beta2.GarageS3Permission{v1beta2.PermissionRead, v1beta2.PermissionWrite}
+	if len(converted.Spec.Permissions) != 1 || !reflect.DeepEqual(converted.Spec.Permissions[0].Allow, expected) {
+		t.Errorf("expected the writer to be allowed %v, got %+v", expected, converted.Spec.Permissions)
+	}
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// policyViolation is returned when a resource is refused by the tenancy policy of its instance.
+type policyViolation struct {
+	message string
+}
+
+func (e *policyViolation) Error() string {
+	return e.message
+}
+
+func newPolicyViolation(format string, args ...any) error {
+	return &policyViolation{message: fmt.Sprintf(format, args...)}
+}
+
+// CheckNamespaceAllowed verifies the namespace matches the namespace selector of the policy.
+func CheckNamespaceAllowed(ctx context.Context, c client.Reader, policy *v1.GarageS3TenancyPolicy, namespace string) error {
+	if policy == nil || policy.NamespaceSelector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(policy.NamespaceSelector)
+	if err != nil {
+		return newPolicyViolation("invalid namespaceSelector in tenancy policy: %v", err)
+	}
+	ns := &corev1.Namespace{}
+	if err := c.Get(ctx, client.ObjectKey{Name: namespace}, ns); err != nil {
+		return err
+	}
+	if !selector.Matches(labels.Set(ns.Labels)) {
+		return newPolicyViolation("namespace %s is not allowed to use this instance", namespace)
+	}
+	return nil
+}
+
+// positionInNamespace returns the rank of obj among the given objects, ordered by
+// creation time then name. It is used to decide which resources fit in a per-namespace limit.
+func positionInNamespace(obj client.Object, objs []client.Object) int {
+	sort.Slice(objs, func(i, j int) bool {
+		ti, tj := objs[i].GetCreationTimestamp(), objs[j].GetCreationTimestamp()
+		if !ti.Equal(&tj) {
+			return ti.Before(&tj)
+		}
+		return objs[i].GetName() < objs[j].GetName()
+	})
+	for i, o := range objs {
+		if o.GetName() == obj.GetName() {
+			return i
+		}
+	}
+	return len(objs)
+}
+
+// CheckBucketPolicy verifies the bucket complies with the tenancy policy of its instance,
+// and returns the quota to apply on the bucket.
+func CheckBucketPolicy(ctx context.Context, c client.Reader, instance garageInstance, bucket *v1.GarageS3Bucket) (*v1.GarageS3BucketQuota, error) {
+	policy := instance.GetInstanceSpec().Tenancy
+	if policy == nil {
+		return bucket.Spec.Quota, nil
+	}
+
+	if err := CheckNamespaceAllowed(ctx, c, policy, bucket.Namespace); err != nil {
+		return nil, err
+	}
+
+	// Aliases are global bucket names too, and domain aliases are the website domains of the
+	// bucket. The alias given by the instance bucket defaults is trusted.
+	defaults := instance.GetInstanceSpec().BucketDefaults
+	prefix := strings.ReplaceAll(policy.BucketNamePrefix, "{namespace}", bucket.Namespace)
+	if prefix != "" && !strings.HasPrefix(bucket.Name, prefix) {
+		return nil, newPolicyViolation("bucket name must start with %q", prefix)
+	}
+	for _, alias := range bucket.Spec.AdditionalAliases {
+		if alias != defaultAlias(defaults, bucket) && !aliasAllowed(policy, bucket.Namespace, alias) {
+			if len(policy.WebsiteDomains) > 0 && isDomainAlias(alias) {
+				return nil, newPolicyViolation("alias %s must start with %q or be one of the website domains of the tenancy policy", alias, prefix)
+			}
+			return nil, newPolicyViolation("alias %s must start with %q", alias, prefix)
+		}
+	}
+
+	// Access keys of other namespaces may only be granted by the instance bucket defaults
+	for _, p := range bucket.Spec.Permissions {
+		if p.AccessKeyNamespace != "" && p.AccessKeyNamespace != bucket.Namespace && !defaultPermission(defaults, p, bucket.Namespace) {
+			return nil, newPolicyViolation("access key %s/%s is not in the bucket namespace", p.AccessKeyNamespace, p.AccessKeyName)
+		}
+	}
+
+	if policy.MaxBucketsPerNamespace != nil {
+		bucketList := &v1.GarageS3BucketList{}
+		if err := c.List(ctx, bucketList, client.InNamespace(bucket.Namespace)); err != nil {
+			return nil, err
+		}
+		var siblings []client.Object
+		for i := range bucketList.Items {
+			if RefersToInstance(bucketList.Items[i].Spec.InstanceRef, instance) {
+				siblings = append(siblings, &bucketList.Items[i])
+			}
+		}
+		if positionInNamespace(bucket, siblings) >= *policy.MaxBucketsPerNamespace {
+			return nil, newPolicyViolation("namespace %s already has the maximum of %d buckets on this instance", bucket.Namespace, *policy.MaxBucketsPerNamespace)
+		}
+	}
+
+	return effectiveBucketQuota(bucket.Spec.Quota, policy)
+}
+
+// aliasAllowed returns true when an additional alias of a bucket of the namespace starts with
+// the name prefix of the policy, or is a domain matching its website domains.
+func aliasAllowed(policy *v1.GarageS3TenancyPolicy, namespace string, alias string) bool {
+	prefix := strings.ReplaceAll(policy.BucketNamePrefix, "{namespace}", namespace)
+	if prefix != "" && strings.HasPrefix(alias, prefix) {
+		return true
+	}
+	if isDomainAlias(alias) && len(policy.WebsiteDomains) > 0 {
+		return DomainAllowed(policy, namespace, alias)
+	}
+	return prefix == "" && len(policy.WebsiteDomains) == 0
+}
+
+// DomainAllowed returns true when the domain matches one of the website domains of the policy,
+// exactly or below a *.<domain> wildcard.
+func DomainAllowed(policy *v1.GarageS3TenancyPolicy, namespace string, domain string) bool {
+	for _, pattern := range policy.WebsiteDomains {
+		pattern = strings.ReplaceAll(pattern, "{namespace}", namespace)
+		if suffix, found := strings.CutPrefix(pattern, "*."); found {
+			if strings.HasSuffix(domain, "."+suffix) {
+				return true
+			}
+		} else if domain == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// effectiveBucketQuota fills unset quota values with the policy defaults and checks
+// them against the policy maximum. Values left unlimited are capped to the maximum.
+func effectiveBucketQuota(quota *v1.GarageS3BucketQuota, policy *v1.GarageS3TenancyPolicy) (*v1.GarageS3BucketQuota, error) {
+	effective := quota.DeepCopy()
+	if effective == nil {
+		effective = &v1.GarageS3BucketQuota{}
+	}
+	if defaults := policy.DefaultBucketQuota.DeepCopy(); defaults != nil {
+		if effective.MaxObjects == nil {
+			effective.MaxObjects = defaults.MaxObjects
+		}
+		if effective.MaxBytes == nil {
+			effective.MaxBytes = defaults.MaxBytes
+		}
+	}
+	if maxQuota := policy.MaxBucketQuota.DeepCopy(); maxQuota != nil {
+		if maxQuota.MaxObjects != nil {
+			if effective.MaxObjects == nil {
+				effective.MaxObjects = maxQuota.MaxObjects
+			} else if *effective.MaxObjects > *maxQuota.MaxObjects {
+				return nil, newPolicyViolation("quota maxObjects %d exceeds the maximum of %d", *effective.MaxObjects, *maxQuota.MaxObjects)
+			}
+		}
+		if maxQuota.MaxBytes != nil {
+			if effective.MaxBytes == nil {
+				effective.MaxBytes = maxQuota.MaxBytes
+			} else if *effective.MaxBytes > *maxQuota.MaxBytes {
+				return nil, newPolicyViolation("quota maxBytes %d exceeds the maximum of %d", *effective.MaxBytes, *maxQuota.MaxBytes)
+			}
+		}
+	}
+	if effective.MaxObjects == nil && effective.MaxBytes == nil {
+		return quota, nil
+	}
+	return effective, nil
+}
+
+// CheckAccessKeyPolicy verifies the access key complies with the tenancy policy of its instance.
+func CheckAccessKeyPolicy(ctx context.Context, c client.Reader, instance garageInstance, ak *v1.GarageS3AccessKey) error {
+	policy := instance.GetInstanceSpec().Tenancy
+	if policy == nil {
+		return nil
+	}
+
+	if err := CheckNamespaceAllowed(ctx, c, policy, ak.Namespace); err != nil {
+		return err
+	}
+
+	if policy.MaxAccessKeysPerNamespace != nil {
+		keyList := &v1.GarageS3AccessKeyList{}
+		if err := c.List(ctx, keyList, client.InNamespace(ak.Namespace)); err != nil {
+			return err
+		}
+		var siblings []client.Object
+		for i := range keyList.Items {
+			if RefersToInstance(keyList.Items[i].Spec.InstanceRef, instance) {
+				siblings = append(siblings, &keyList.Items[i])
+			}
+		}
+		if positionInNamespace(ak, siblings) >= *policy.MaxAccessKeysPerNamespace {
+			return newPolicyViolation("namespace %s already has the maximum of %d access keys on this instance", ak.Namespace, *policy.MaxAccessKeysPerNamespace)
+		}
+	}
+	return nil
+}
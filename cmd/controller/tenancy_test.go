@@ -0,0 +1,210 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func int64Ptr(v int64) *int64 { return &v }
+func intPtr(v int) *int       { return &v }
+
+func newPolicyBucket(name string, created time.Time, quota *v1.GarageS3BucketQuota) *v1.GarageS3Bucket {
+	return &v1.GarageS3Bucket{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "team-a", CreationTimestamp: metav1.NewTime(created)},
+		Spec: v1.GarageS3BucketSpec{
+			InstanceRef: v1.GarageS3InstanceRef{Name: "garage", Namespace: "garage"},
+			Quota:       quota,
+		},
+	}
+}
+
+func withAliases(bucket *v1.GarageS3Bucket, aliases ...string) *v1.GarageS3Bucket {
+	bucket.Spec.AdditionalAliases = aliases
+	return bucket
+}
+
+func withPermissions(bucket *v1.GarageS3Bucket, permissions ...v1.GarageS3BucketPermission) *v1.GarageS3Bucket {
+	bucket.Spec.Permissions = permissions
+	return bucket
+}
+
+func TestCheckBucketPolicy(t *testing.T) {
+	now := time.Now()
+	teamA := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"tenant": "true"}}}
+
+	tests := []struct {
+		name      string
+		policy    *v1.GarageS3TenancyPolicy
+		defaults  *v1.GarageS3BucketDefaults
+		bucket    *v1.GarageS3Bucket
+		others    []client.Object
+		wantQuota *v1.GarageS3BucketQuota
+		violation bool
+	}{
+		{
+			name:      "no policy keeps bucket quota",
+			bucket:    newPolicyBucket("b", now, &v1.GarageS3BucketQuota{MaxObjects: int64Ptr(5)}),
+			wantQuota: &v1.GarageS3BucketQuota{MaxObjects: int64Ptr(5)},
+		},
+		{
+			name:   "namespace selector matches",
+			policy: &v1.GarageS3TenancyPolicy{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "true"}}},
+			bucket: newPolicyBucket("b", now, nil),
+		},
+		{
+			name:      "namespace selector does not match",
+			policy:    &v1.GarageS3TenancyPolicy{NamespaceSelector: &metav1.LabelSelector{MatchLabels: map[string]string{"tenant": "false"}}},
+			bucket:    newPolicyBucket("b", now, nil),
+			violation: true,
+		},
+		{
+			name:      "missing prefix",
+			policy:    &v1.GarageS3TenancyPolicy{BucketNamePrefix: "{namespace}-"},
+			bucket:    newPolicyBucket("b", now, nil),
+			violation: true,
+		},
+		{
+			name:   "prefix with namespace placeholder",
+			policy: &v1.GarageS3TenancyPolicy{BucketNamePrefix: "{namespace}-"},
+			bucket: newPolicyBucket("team-a-b", now, nil),
+		},
+		{
+			name:      "alias without prefix",
+			policy:    &v1.GarageS3TenancyPolicy{BucketNamePrefix: "{namespace}-"},
+			bucket:    withAliases(newPolicyBucket("team-a-b", now, nil), "team-a-c", "shared"),
+			violation: true,
+		},
+		{
+			name:   "aliases with prefix",
+			policy: &v1.GarageS3TenancyPolicy{BucketNamePrefix: "{namespace}-"},
+			bucket: withAliases(newPolicyBucket("team-a-b", now, nil), "team-a-c", "team-a-www.example.com"),
+		},
+		{
+			name:      "domain alias without prefix",
+			policy:    &v1.GarageS3TenancyPolicy{BucketNamePrefix: "{namespace}-"},
+			bucket:    withAliases(newPolicyBucket("team-a-b", now, nil), "www.example.com"),
+			violation: true,
+		},
+		{
+			name:   "domain alias within website domains",
+			policy: &v1.GarageS3TenancyPolicy{BucketNamePrefix: "{namespace}-", WebsiteDomains: []string{"*.{namespace}.example.com", "example.org"}},
+			bucket: withAliases(newPolicyBucket("team-a-b", now, nil), "www.team-a.example.com", "example.org"),
+		},
+		{
+			name:      "domain alias outside website domains",
+			policy:    &v1.GarageS3TenancyPolicy{WebsiteDomains: []string{"*.{namespace}.example.com"}},
+			bucket:    withAliases(newPolicyBucket("b", now, nil), "www.team-b.example.com"),
+			violation: true,
+		},
+		{
+			name:     "alias of the instance pattern",
+			policy:   &v1.GarageS3TenancyPolicy{BucketNamePrefix: "{namespace}-"},
+			defaults: &v1.GarageS3BucketDefaults{AliasPattern: "shared-{name}"},
+			bucket:   withAliases(newPolicyBucket("team-a-b", now, nil), "shared-team-a-b"),
+		},
+		{
+			name:   "access key of the bucket namespace",
+			policy: &v1.GarageS3TenancyPolicy{},
+			bucket: withPermissions(newPolicyBucket("b", now, nil), v1.GarageS3BucketPermission{AccessKeyName: "k", AccessKeyNamespace: "team-a", Read: true}),
+		},
+		{
+			name:      "access key of another namespace",
+			policy:    &v1.GarageS3TenancyPolicy{},
+			bucket:    withPermissions(newPolicyBucket("b", now, nil), v1.GarageS3BucketPermission{AccessKeyName: "k", AccessKeyNamespace: "team-b", Read: true}),
+			violation: true,
+		},
+		{
+			name:     "read-only key of the bucket defaults",
+			policy:   &v1.GarageS3TenancyPolicy{},
+			defaults: &v1.GarageS3BucketDefaults{ReadOnlyAccessKey: &v1.GarageS3AccessKeyRef{Name: "reader", Namespace: "ops"}},
+			bucket:   withPermissions(newPolicyBucket("b", now, nil), v1.GarageS3BucketPermission{AccessKeyName: "reader", AccessKeyNamespace: "ops", Read: true}),
+		},
+		{
+			name:      "write access on the read-only key of the bucket defaults",
+			policy:    &v1.GarageS3TenancyPolicy{},
+			defaults:  &v1.GarageS3BucketDefaults{ReadOnlyAccessKey: &v1.GarageS3AccessKeyRef{Name: "reader", Namespace: "ops"}},
+			bucket:    withPermissions(newPolicyBucket("b", now, nil), v1.GarageS3BucketPermission{AccessKeyName: "reader", AccessKeyNamespace: "ops", Read: true, Write: true}),
+			violation: true,
+		},
+		{
+			name:   "within bucket limit",
+			policy: &v1.GarageS3TenancyPolicy{MaxBucketsPerNamespace: intPtr(2)},
+			bucket: newPolicyBucket("b", now, nil),
+			others: []client.Object{newPolicyBucket("older", now.Add(-time.Hour), nil)},
+		},
+		{
+			name:      "over bucket limit",
+			policy:    &v1.GarageS3TenancyPolicy{MaxBucketsPerNamespace: intPtr(1)},
+			bucket:    newPolicyBucket("b", now, nil),
+			others:    []client.Object{newPolicyBucket("older", now.Add(-time.Hour), nil)},
+			violation: true,
+		},
+		{
+			name:      "default quota fills unset values",
+			policy:    &v1.GarageS3TenancyPolicy{DefaultBucketQuota: &v1.GarageS3BucketQuota{MaxObjects: int64Ptr(10), MaxBytes: int64Ptr(100)}},
+			bucket:    newPolicyBucket("b", now, &v1.GarageS3BucketQuota{MaxBytes: int64Ptr(50)}),
+			wantQuota: &v1.GarageS3BucketQuota{MaxObjects: int64Ptr(10), MaxBytes: int64Ptr(50)},
+		},
+		{
+			name:      "unlimited quota capped to maximum",
+			policy:    &v1.GarageS3TenancyPolicy{MaxBucketQuota: &v1.GarageS3BucketQuota{MaxBytes: int64Ptr(1000)}},
+			bucket:    newPolicyBucket("b", now, nil),
+			wantQuota: &v1.GarageS3BucketQuota{MaxBytes: int64Ptr(1000)},
+		},
+		{
+			name:      "quota over maximum",
+			policy:    &v1.GarageS3TenancyPolicy{MaxBucketQuota: &v1.GarageS3BucketQuota{MaxBytes: int64Ptr(1000)}},
+			bucket:    newPolicyBucket("b", now, &v1.GarageS3BucketQuota{MaxBytes: int64Ptr(2000)}),
+			violation: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			instance := &v1.GarageS3Instance{
+				ObjectMeta: metav1.ObjectMeta{Name: "garage", Namespace: "garage"},
+				Spec:       v1.GarageS3InstanceSpec{Tenancy: tt.policy, BucketDefaults: tt.defaults},
+			}
+			fakeClient := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(append(tt.others, teamA, tt.bucket)...).
+				Build()
+
+			quota, err := CheckBucketPolicy(context.Background(), fakeClient, instance, tt.bucket)
+			var violation *policyViolation
+			if tt.violation {
+				if !errors.As(err, &violation) {
+					t.Fatalf("expected policy violation, got: %v", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !quotaEqual(quota, tt.wantQuota) {
+				t.Errorf("expected quota %+v, got %+v", tt.wantQuota, quota)
+			}
+		})
+	}
+}
+
+func quotaEqual(a, b *v1.GarageS3BucketQuota) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	eq := func(x, y *int64) bool {
+		if x == nil || y == nil {
+			return x == y
+		}
+		return *x == *y
+	}
+	return eq(a.MaxObjects, b.MaxObjects) && eq(a.MaxBytes, b.MaxBytes)
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Path of the on-demand TLS ask endpoint, the domain being given in the domain query parameter
+const websiteAskPath = "/ask"
+
+// Maximum time the ask server waits for the requests in progress when stopping
+const websiteAskShutdownTimeout = 5 * time.Second
+
+// CheckWebsiteDomains asks Garage whether it serves a website on each domain alias of an
+// enabled website. Domains Garage answers about are reported, served or not, while other
+// failures are returned. The check time of the bucket status is kept while the answer is the
+// same, so that unchanged answers don't write the status again.
+func (r *bucket_reconciler) CheckWebsiteDomains(apiCtx context.Context, garageClient *garage.APIClient, bucket *v1.GarageS3Bucket) ([]v1.GarageS3WebsiteDomainStatus, error) {
+	website := bucket.Spec.WebsiteAccess
+	if website == nil || !website.Enabled {
+		return nil, nil
+	}
+	now := metav1.Now()
+	var statuses []v1.GarageS3WebsiteDomainStatus
+	for _, domain := range WebsiteDomains(&bucket.Spec) {
+		status := v1.GarageS3WebsiteDomainStatus{Domain: domain, Served: true, LastCheckTime: now}
+		resp, err := garageClient.SpecialEndpointsAPI.CheckDomain(apiCtx).Domain(domain).Execute()
+		if err != nil {
+			if resp == nil || resp.StatusCode >= http.StatusInternalServerError {
+				return nil, fmt.Errorf("failed to check domain %s: %w", domain, err)
+			}
+			status.Served = false
+			status.Message = garageErrorMessage(err)
+		}
+		for _, previous := range bucket.Status.WebsiteDomains {
+			if previous.Domain == domain && previous.Served == status.Served && previous.Message == status.Message {
+				status.LastCheckTime = previous.LastCheckTime
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// garageErrorMessage returns the message of an admin API error response, or the error itself.
+func garageErrorMessage(err error) string {
+	var apiErr interface{ Body() []byte }
+	if errors.As(err, &apiErr) {
+		var body struct {
+			Message string `json:"message"`
+		}
+		if json.Unmarshal(apiErr.Body(), &body) == nil && body.Message != "" {
+			return body.Message
+		}
+	}
+	return err.Error()
+}
+
+// websiteAskServer answers the on-demand TLS ask requests of reverse proxies such as Caddy,
+// allowing certificates for the domains Garage serves according to the bucket statuses.
+type websiteAskServer struct {
+	client.Reader
+	// Address the server listens on, as <host>:<port>
+	Addr string
+}
+
+// IsWebsiteDomain returns true when a bucket reports Garage serving its website on the domain.
+func (s *websiteAskServer) IsWebsiteDomain(ctx context.Context, domain string) (bool, error) {
+	buckets := &v1.GarageS3BucketList{}
+	if err := s.List(ctx, buckets); err != nil {
+		return false, fmt.Errorf("failed to list GarageS3Buckets: %w", err)
+	}
+	for _, bucket := range buckets.Items {
+		for _, status := range bucket.Status.WebsiteDomains {
+			if status.Domain == domain && status.Served {
+				return true, nil
+			}
+		}
+	}
+	return false, nil
+}
+
+// ServeHTTP answers 200 for a website domain and 404 for other domains.
+func (s *websiteAskServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	if domain == "" {
+		http.Error(w, "missing domain parameter", http.StatusBadRequest)
+		return
+	}
+	known, err := s.IsWebsiteDomain(r.Context(), domain)
+	if err != nil {
+		log.FromContext(r.Context()).Error(err, "Failed to answer website domain ask request", "Domain", domain)
+		http.Error(w, "failed to look up website domains", http.StatusInternalServerError)
+		return
+	}
+	if !known {
+		http.Error(w, fmt.Sprintf("%s is not a website domain", domain), http.StatusNotFound)
+		return
+	}
+	fmt.Fprintf(w, "%s is a website domain\n", domain)
+}
+
+// Start serves the ask endpoint until the context is done.
+func (s *websiteAskServer) Start(ctx context.Context) error {
+	mux := http.NewServeMux()
+	mux.Handle(websiteAskPath, s)
+	server := &http.Server{Addr: s.Addr, Handler: mux, ReadHeaderTimeout: 5 * time.Second}
+
+	errs := make(chan error, 1)
+	go func() {
+		errs <- server.ListenAndServe()
+	}()
+	select {
+	case err := <-errs:
+		return fmt.Errorf("website ask server failed: %w", err)
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), websiteAskShutdownTimeout)
+		defer cancel()
+		return server.Shutdown(shutdownCtx)
+	}
+}
+
+// NeedLeaderElection returns false, every replica answering the ask requests.
+func (s *websiteAskServer) NeedLeaderElection() bool {
+	return false
+}
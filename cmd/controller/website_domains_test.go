@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestBucketReconciler_WebsiteDomains(t *testing.T) {
+	aliases := []string{"assets", "www.example.com"}
+	checkTime := metav1.NewTime(time.Date(2026, 1, 10, 0, 0, 0, 0, time.UTC))
+	tests := []struct {
+		name            string
+		website         *v1.GarageS3WebsiteAccess
+		fault           int
+		previous        []v1.GarageS3WebsiteDomainStatus
+		expectedReason  string
+		expectedRequeue time.Duration
+		expected        []v1.GarageS3WebsiteDomainStatus
+	}{
+		{
+			name:            "served domain",
+			website:         &v1.GarageS3WebsiteAccess{Enabled: true},
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			expected:        []v1.GarageS3WebsiteDomainStatus{{Domain: "www.example.com", Served: true}},
+		},
+		{
+			name:            "domain refused by Garage",
+			website:         &v1.GarageS3WebsiteAccess{Enabled: true},
+			fault:           http.StatusBadRequest,
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			expected:        []v1.GarageS3WebsiteDomainStatus{{Domain: "www.example.com", Message: "fault injected on CheckDomain"}},
+		},
+		{
+			name:            "unchanged answer keeps the check time",
+			website:         &v1.GarageS3WebsiteAccess{Enabled: true},
+			previous:        []v1.GarageS3WebsiteDomainStatus{{Domain: "www.example.com", Served: true, LastCheckTime: checkTime}},
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			expected:        []v1.GarageS3WebsiteDomainStatus{{Domain: "www.example.com", Served: true, LastCheckTime: checkTime}},
+		},
+		{
+			name:            "changed answer updates the check time",
+			website:         &v1.GarageS3WebsiteAccess{Enabled: true},
+			fault:           http.StatusBadRequest,
+			previous:        []v1.GarageS3WebsiteDomainStatus{{Domain: "www.example.com", Served: true, LastCheckTime: checkTime}},
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+			expected:        []v1.GarageS3WebsiteDomainStatus{{Domain: "www.example.com", Message: "fault injected on CheckDomain"}},
+		},
+		{
+			name:            "disabled website",
+			website:         &v1.GarageS3WebsiteAccess{Enabled: false},
+			expectedReason:  "Ready",
+			expectedRequeue: bucketRequeueInterval,
+		},
+		{
+			name:            "check failure",
+			website:         &v1.GarageS3WebsiteAccess{Enabled: true},
+			fault:           http.StatusInternalServerError,
+			expectedReason:  "GarageAPIError",
+			expectedRequeue: bucketErrorRequeueInterval,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			env := newGarageTestEnv(t)
+			if tt.fault != 0 {
+				env.garage.InjectFault("CheckDomain", tt.fault, 1)
+			}
+			bucket := newTestBucket(env, v1.GarageS3BucketSpec{AdditionalAliases: aliases, WebsiteAccess: tt.website})
+			bucket.Status.WebsiteDomains = tt.previous
+			c := env.client(bucket)
+			r := &bucket_reconciler{Client: c, scheme: scheme, garageClients: NewGarageClientFactory(c)}
+
+			result, err := r.Reconcile(context.Background(), ctrl.Request{NamespacedName: client.ObjectKeyFromObject(bucket)})
+			if (tt.fault == http.StatusInternalServerError) != (err != nil) {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if result.RequeueAfter != tt.expectedRequeue {
+				t.Errorf("expected RequeueAfter=%v, got %v", tt.expectedRequeue, result.RequeueAfter)
+			}
+			got := &v1.GarageS3Bucket{}
+			if err := c.Get(context.Background(), client.ObjectKeyFromObject(bucket), got); err != nil {
+				t.Fatalf("failed to get bucket: %v", err)
+			}
+			if cond := readyCondition(t, got.Status.Conditions); cond.Reason != tt.expectedReason {
+				t.Errorf("expected reason %s, got %s: %s", tt.expectedReason, cond.Reason, cond.Message)
+			}
+			if len(got.Status.WebsiteDomains) != len(tt.expected) {
+				t.Fatalf("expected domains %+v, got %+v", tt.expected, got.Status.WebsiteDomains)
+			}
+			for i, status := range got.Status.WebsiteDomains {
+				want := tt.expected[i]
+				if status.Domain != want.Domain || status.Served != want.Served || status.Message != want.Message || status.LastCheckTime.IsZero() {
+					t.Errorf("expected domain %+v, got %+v", want, status)
+				}
+				// The check time is only the one of the previous status when the answer is the same
+				if !want.LastCheckTime.IsZero() != status.LastCheckTime.Equal(&checkTime) {
+					t.Errorf("expected check time %v, got %v", want.LastCheckTime, status.LastCheckTime)
+				}
+			}
+		})
+	}
+}
+
+func TestWebsiteAskServer(t *testing.T) {
+	bucket := func(name string, domains ...v1.GarageS3WebsiteDomainStatus) client.Object {
+		return &v1.GarageS3Bucket{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default"},
+			Status:     v1.GarageS3BucketStatus{WebsiteDomains: domains},
+		}
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(
+		bucket("site", v1.GarageS3WebsiteDomainStatus{Domain: "www.example.com", Served: true}),
+		bucket("pending", v1.GarageS3WebsiteDomainStatus{Domain: "new.example.com", Message: "Domain 'new.example.com' is not managed by Garage"}),
+	).Build()
+	server := &websiteAskServer{Reader: c}
+
+	tests := []struct {
+		query    string
+		expected int
+	}{
+		{query: "?domain=www.example.com", expected: http.StatusOK},
+		{query: "?domain=new.example.com", expected: http.StatusNotFound},
+		{query: "?domain=unknown.example.com", expected: http.StatusNotFound},
+		{query: "", expected: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		recorder := httptest.NewRecorder()
+		server.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, websiteAskPath+tt.query, nil))
+		if recorder.Code != tt.expected {
+			t.Errorf("ask%s: expected %d, got %d", tt.query, tt.expected, recorder.Code)
+		}
+	}
+}
@@ -0,0 +1,318 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+	garage "git.deuxfleurs.fr/garage-sdk/garage-admin-sdk-golang"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// Consecutive errors after which a worker is reported unhealthy
+	unhealthyConsecutiveErrors = 3
+
+	// Name prefix of the block resync workers
+	resyncWorkerName = "Block resync worker"
+)
+
+// workerInfo is a background worker of a node, in the wire format of the worker API.
+// Its state is either a string, or an object keyed by the state for throttled workers.
+type workerInfo struct {
+	ID                int64           `json:"id"`
+	Name              string          `json:"name"`
+	State             json.RawMessage `json:"state"`
+	Errors            int64           `json:"errors"`
+	ConsecutiveErrors int64           `json:"consecutiveErrors"`
+	PersistentErrors  *int64          `json:"persistentErrors"`
+	QueueLength       *int64          `json:"queueLength"`
+	Progress          *string         `json:"progress"`
+	LastError         *struct {
+		Message string `json:"message"`
+	} `json:"lastError"`
+}
+
+// workerList is the response of a worker listing on several nodes, in its wire format.
+type workerList struct {
+	Success map[string][]workerInfo `json:"success"`
+	Error   map[string]string       `json:"error"`
+}
+
+// workerVariables is the response of a worker variable call on several nodes, in its wire format.
+type workerVariables struct {
+	Success map[string]map[string]string `json:"success"`
+	Error   map[string]string            `json:"error"`
+}
+
+// WorkerState returns the state of a worker: busy, throttled, idle or done.
+func WorkerState(raw json.RawMessage) string {
+	state := ""
+	if err := json.Unmarshal(raw, &state); err == nil {
+		return state
+	}
+	object := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &object); err == nil {
+		for key := range object {
+			return key
+		}
+	}
+	return "unknown"
+}
+
+// WorkerStatuses converts a worker listing to worker statuses, sorted by node and worker.
+func WorkerStatuses(list workerList) []v1.GarageS3WorkerStatus {
+	workers := []v1.GarageS3WorkerStatus{}
+	for nodeID, infos := range list.Success {
+		for _, info := range infos {
+			worker := v1.GarageS3WorkerStatus{
+				NodeID:            nodeID,
+				WorkerID:          info.ID,
+				Name:              info.Name,
+				State:             WorkerState(info.State),
+				Errors:            info.Errors,
+				ConsecutiveErrors: info.ConsecutiveErrors,
+			}
+			if info.PersistentErrors != nil {
+				worker.PersistentErrors = *info.PersistentErrors
+			}
+			if info.QueueLength != nil {
+				worker.QueueLength = *info.QueueLength
+			}
+			if info.Progress != nil {
+				worker.Progress = *info.Progress
+			}
+			if info.LastError != nil {
+				worker.LastError = info.LastError.Message
+			}
+			workers = append(workers, worker)
+		}
+	}
+	sort.Slice(workers, func(i, j int) bool {
+		if workers[i].NodeID != workers[j].NodeID {
+			return workers[i].NodeID < workers[j].NodeID
+		}
+		return workers[i].WorkerID < workers[j].WorkerID
+	})
+	return workers
+}
+
+// WorkerUnhealthy returns true for a worker failing repeatedly or holding persistent errors,
+// such as blocks the resync or scrub workers could not process.
+func WorkerUnhealthy(worker v1.GarageS3WorkerStatus) bool {
+	return worker.ConsecutiveErrors >= unhealthyConsecutiveErrors || worker.PersistentErrors > 0
+}
+
+// SummarizeWorkers summarizes the workers of each node, sorted by node.
+func SummarizeWorkers(list workerList) []v1.GarageS3NodeWorkers {
+	nodes := map[string]*v1.GarageS3NodeWorkers{}
+	for nodeID := range list.Success {
+		nodes[nodeID] = &v1.GarageS3NodeWorkers{NodeID: nodeID, States: map[string]int{}}
+	}
+	for _, worker := range WorkerStatuses(list) {
+		node := nodes[worker.NodeID]
+		node.States[worker.State]++
+		node.Errors += worker.Errors
+		// Every resync worker reports the length of the shared resync queue
+		if strings.HasPrefix(worker.Name, resyncWorkerName) && worker.QueueLength > node.ResyncQueueLength {
+			node.ResyncQueueLength = worker.QueueLength
+		}
+		if WorkerUnhealthy(worker) {
+			node.Unhealthy = append(node.Unhealthy, worker)
+		}
+	}
+	for nodeID, message := range list.Error {
+		nodes[nodeID] = &v1.GarageS3NodeWorkers{NodeID: nodeID, Error: message}
+	}
+
+	summary := []v1.GarageS3NodeWorkers{}
+	for _, node := range nodes {
+		summary = append(summary, *node)
+	}
+	sort.Slice(summary, func(i, j int) bool { return summary[i].NodeID < summary[j].NodeID })
+	return summary
+}
+
+// ReconcileWorkers summarizes the background workers of every node into the instance
+// status and metrics, and sets the WorkersHealthy condition.
+func (r *instance_reconciler) ReconcileWorkers(ctx context.Context, garageClient *garage.APIClient, apiCtx context.Context, instance garageInstance) error {
+	instanceStatus := instance.GetInstanceStatus()
+	list, err := ListWorkers(garageClient, apiCtx, []string{"*"})
+	if err != nil {
+		return err
+	}
+	if message, failed := list.Error["*"]; failed {
+		meta.SetStatusCondition(&instanceStatus.Conditions, metav1.Condition{
+			Type:    "WorkersHealthy",
+			Status:  metav1.ConditionUnknown,
+			Reason:  "GarageAPIError",
+			Message: "Failed to list workers: " + message,
+		})
+		return nil
+	}
+
+	summary := SummarizeWorkers(list)
+	instanceStatus.Workers = summary
+
+	ResetInstanceMetrics(r.kind, instance, workersGauge, unhealthyWorkersGauge, resyncQueueGauge)
+	unhealthy := []string{}
+	for _, node := range summary {
+		labels := nodeLabels(r.kind, instance, node.NodeID)
+		for state, count := range node.States {
+			stateLabels := nodeLabels(r.kind, instance, node.NodeID)
+			stateLabels["state"] = state
+			workersGauge.With(stateLabels).Set(float64(count))
+		}
+		unhealthyWorkersGauge.With(labels).Set(float64(len(node.Unhealthy)))
+		resyncQueueGauge.With(labels).Set(float64(node.ResyncQueueLength))
+		for _, worker := range node.Unhealthy {
+			unhealthy = append(unhealthy, fmt.Sprintf("%s on node %.16s", worker.Name, node.NodeID))
+		}
+	}
+
+	if len(unhealthy) > 0 {
+		meta.SetStatusCondition(&instanceStatus.Conditions, metav1.Condition{
+			Type:    "WorkersHealthy",
+			Status:  metav1.ConditionFalse,
+			Reason:  "WorkerErrors",
+			Message: "Unhealthy workers: " + strings.Join(unhealthy, ", "),
+		})
+		return nil
+	}
+	meta.SetStatusCondition(&instanceStatus.Conditions, metav1.Condition{
+		Type:    "WorkersHealthy",
+		Status:  metav1.ConditionTrue,
+		Reason:  "WorkersHealthy",
+		Message: "No background worker is failing",
+	})
+	return nil
+}
+
+// ListWorkers lists the background workers of the targeted nodes.
+func ListWorkers(garageClient *garage.APIClient, apiCtx context.Context, targets []string) (workerList, error) {
+	list := workerList{Success: map[string][]workerInfo{}, Error: map[string]string{}}
+	for _, target := range targets {
+		res, _, err := garageClient.WorkerAPI.ListWorkers(apiCtx).Node(target).LocalListWorkersRequest(garage.LocalListWorkersRequest{}).Execute()
+		if err != nil {
+			list.Error[target] = err.Error()
+			continue
+		}
+		response := workerList{}
+		if err := convertJSON(res, &response); err != nil {
+			return workerList{}, err
+		}
+		for nodeID, workers := range response.Success {
+			list.Success[nodeID] = workers
+		}
+		for nodeID, message := range response.Error {
+			list.Error[nodeID] = message
+		}
+	}
+	return list, nil
+}
+
+// DesiredWorkerVariables returns the worker variables of each node, the variables
+// of a node overriding the ones of the instance. Node IDs may be given as prefixes.
+func DesiredWorkerVariables(spec *v1.GarageS3InstanceSpec, ids []string) (map[string]map[string]string, []v1.GarageS3NodeWorkerVariablesStatus) {
+	desired := map[string]map[string]string{}
+	for _, id := range ids {
+		desired[id] = map[string]string{}
+		for variable, value := range spec.WorkerVariables {
+			desired[id][variable] = value
+		}
+	}
+	unresolved := []v1.GarageS3NodeWorkerVariablesStatus{}
+	for _, node := range spec.NodeWorkerVariables {
+		id, err := ResolveNodeID(node.NodeID, ids)
+		if err != nil {
+			unresolved = append(unresolved, v1.GarageS3NodeWorkerVariablesStatus{NodeID: node.NodeID, Error: err.Error()})
+			continue
+		}
+		for variable, value := range node.Variables {
+			desired[id][variable] = value
+		}
+	}
+	return desired, unresolved
+}
+
+// WorkerVariableChanges returns the variables whose current value differs from the desired one, sorted.
+func WorkerVariableChanges(desired map[string]string, current map[string]string) []string {
+	changes := []string{}
+	for variable, value := range desired {
+		if current[variable] != value {
+			changes = append(changes, variable)
+		}
+	}
+	sort.Strings(changes)
+	return changes
+}
+
+// ReconcileWorkerVariables sets the worker variables of the instance on the nodes which
+// are up, correcting any drift, and reports their current values in the instance status.
+func (r *instance_reconciler) ReconcileWorkerVariables(ctx context.Context, garageClient *garage.APIClient, apiCtx context.Context, instance garageInstance, status *garage.GetClusterStatusResponse) {
+	log := log.FromContext(ctx)
+	spec := instance.GetInstanceSpec()
+	if len(spec.WorkerVariables) == 0 && len(spec.NodeWorkerVariables) == 0 {
+		instance.GetInstanceStatus().WorkerVariables = nil
+		return
+	}
+
+	ids := []string{}
+	for _, node := range status.Nodes {
+		if node.IsUp {
+			ids = append(ids, node.Id)
+		}
+	}
+	desired, reported := DesiredWorkerVariables(spec, ids)
+
+	for _, id := range ids {
+		node := v1.GarageS3NodeWorkerVariablesStatus{NodeID: id, Variables: map[string]string{}}
+		res, _, err := garageClient.WorkerAPI.GetWorkerVariable(apiCtx).Node(id).LocalGetWorkerVariableRequest(garage.LocalGetWorkerVariableRequest{}).Execute()
+		if err != nil {
+			node.Error = "failed to get worker variables: " + err.Error()
+			reported = append(reported, node)
+			continue
+		}
+		variables := workerVariables{}
+		if err := convertJSON(res, &variables); err != nil {
+			node.Error = "failed to get worker variables: " + err.Error()
+			reported = append(reported, node)
+			continue
+		}
+		if message, failed := variables.Error[id]; failed {
+			node.Error = "failed to get worker variables: " + message
+			reported = append(reported, node)
+			continue
+		}
+		current := variables.Success[id]
+		for variable := range desired[id] {
+			node.Variables[variable] = current[variable]
+		}
+
+		for _, variable := range WorkerVariableChanges(desired[id], current) {
+			value := desired[id][variable]
+			set, _, err := garageClient.WorkerAPI.SetWorkerVariable(apiCtx).Node(id).LocalSetWorkerVariableRequest(garage.LocalSetWorkerVariableRequest{Variable: variable, Value: value}).Execute()
+			if err == nil {
+				response := multiNodeResponse{}
+				if err = convertJSON(set, &response); err == nil && response.Error[id] != "" {
+					err = errors.New(response.Error[id])
+				}
+			}
+			if err != nil {
+				node.Error = "failed to set " + variable + ": " + err.Error()
+				continue
+			}
+			log.Info("Set worker variable", "Node", id, "Variable", variable, "Value", value, "Previous", current[variable])
+			node.Variables[variable] = value
+		}
+		reported = append(reported, node)
+	}
+	sort.Slice(reported, func(i, j int) bool { return reported[i].NodeID < reported[j].NodeID })
+	instance.GetInstanceStatus().WorkerVariables = reported
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"testing"
+
+	v1 "abucquet.com/garage-s3-operator/api/v1"
+)
+
+func TestWorkerStatuses(t *testing.T) {
+	raw := `{"success": {"a": [
+		{"id": 7, "name": "Scrub worker", "state": {"throttled": {"durationSecs": 1.5}}, "errors": 0, "progress": "12.5%"},
+		{"id": 2, "name": "Block resync worker #1", "state": "idle", "errors": 3, "lastError": {"message": "timeout", "secsAgo": 10}}
+	]}, "error": {}}`
+	list := workerList{}
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	workers := WorkerStatuses(list)
+
+	if len(workers) != 2 || workers[0].WorkerID != 2 || workers[1].WorkerID != 7 {
+		t.Fatalf("unexpected workers %+v", workers)
+	}
+	if workers[0].State != "idle" || workers[0].Errors != 3 || workers[0].LastError != "timeout" {
+		t.Errorf("unexpected worker %+v", workers[0])
+	}
+	if workers[1].State != "throttled" || workers[1].Progress != "12.5%" {
+		t.Errorf("unexpected worker %+v", workers[1])
+	}
+}
+
+func TestDesiredWorkerVariables(t *testing.T) {
+	spec := &v1.GarageS3InstanceSpec{
+		WorkerVariables: map[string]string{"resync-tranquility": "2", "resync-worker-count": "1"},
+		NodeWorkerVariables: []v1.GarageS3NodeWorkerVariables{
+			{NodeID: "a1b2", Variables: map[string]string{"resync-worker-count": "4"}},
+			{NodeID: "ffff", Variables: map[string]string{"scrub-tranquility": "0"}},
+		},
+	}
+	ids := []string{"563e1ac825ee3323", "a1b2c3d4e5f60718"}
+
+	desired, unresolved := DesiredWorkerVariables(spec, ids)
+
+	if desired["563e1ac825ee3323"]["resync-worker-count"] != "1" || desired["a1b2c3d4e5f60718"]["resync-worker-count"] != "4" || desired["a1b2c3d4e5f60718"]["resync-tranquility"] != "2" {
+		t.Errorf("unexpected desired variables %v", desired)
+	}
+	if len(unresolved) != 1 || unresolved[0].NodeID != "ffff" || unresolved[0].Error == "" {
+		t.Errorf("unexpected unresolved nodes %+v", unresolved)
+	}
+
+	changes := WorkerVariableChanges(desired["a1b2c3d4e5f60718"], map[string]string{"resync-tranquility": "2", "resync-worker-count": "1", "scrub-tranquility": "4"})
+	if len(changes) != 1 || changes[0] != "resync-worker-count" {
+		t.Errorf("unexpected changes %v", changes)
+	}
+}
+
+func TestSummarizeWorkers(t *testing.T) {
+	raw := `{"success": {
+		"a": [
+			{"id": 1, "name": "Block resync worker #1", "state": "busy", "errors": 5, "consecutiveErrors": 4, "queueLength": 120},
+			{"id": 2, "name": "Block resync worker #2", "state": "idle", "errors": 0, "consecutiveErrors": 0, "queueLength": 120},
+			{"id": 3, "name": "Scrub worker", "state": {"throttled": {"durationSecs": 2}}, "errors": 1, "consecutiveErrors": 0, "persistentErrors": 2}
+		],
+		"b": [
+			{"id": 1, "name": "Block resync worker #1", "state": "idle", "errors": 1, "consecutiveErrors": 1, "queueLength": 0}
+		]
+	}, "error": {"c": "timeout"}}`
+	list := workerList{}
+	if err := json.Unmarshal([]byte(raw), &list); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	summary := SummarizeWorkers(list)
+
+	if len(summary) != 3 || summary[0].NodeID != "a" || summary[2].Error != "timeout" {
+		t.Fatalf("unexpected summary %+v", summary)
+	}
+	a := summary[0]
+	if a.States["busy"] != 1 || a.States["idle"] != 1 || a.States["throttled"] != 1 || a.Errors != 6 || a.ResyncQueueLength != 120 {
+		t.Errorf("unexpected summary of node a %+v", a)
+	}
+	if len(a.Unhealthy) != 2 || a.Unhealthy[0].WorkerID != 1 || a.Unhealthy[1].Name != "Scrub worker" {
+		t.Errorf("unexpected unhealthy workers %+v", a.Unhealthy)
+	}
+	if len(summary[1].Unhealthy) != 0 {
+		t.Errorf("unexpected unhealthy workers %+v", summary[1].Unhealthy)
+	}
+}
@@ -0,0 +1,345 @@
+package garagefake
+
+import (
+	"encoding/xml"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// CORSRule is a CORS rule of a bucket, set through the S3 API.
+type CORSRule struct {
+	ID             string   `xml:"ID,omitempty"`
+	AllowedOrigins []string `xml:"AllowedOrigin"`
+	AllowedMethods []string `xml:"AllowedMethod"`
+	AllowedHeaders []string `xml:"AllowedHeader,omitempty"`
+	ExposeHeaders  []string `xml:"ExposeHeader,omitempty"`
+	MaxAgeSeconds  *int32   `xml:"MaxAgeSeconds,omitempty"`
+}
+
+// LifecycleRule is a lifecycle rule of a bucket, set through the S3 API.
+type LifecycleRule struct {
+	ID                                 string  `xml:"ID,omitempty"`
+	Status                             string  `xml:"Status"`
+	Prefix                             *string `xml:"Filter>Prefix"`
+	ExpirationDays                     *int32  `xml:"Expiration>Days,omitempty"`
+	ExpirationDate                     string  `xml:"Expiration>Date,omitempty"`
+	AbortIncompleteMultipartUploadDays *int32  `xml:"AbortIncompleteMultipartUpload>DaysAfterInitiation,omitempty"`
+}
+
+// WebsiteRedirectAll is the host every request of a website is redirected to.
+type WebsiteRedirectAll struct {
+	HostName string `xml:"HostName"`
+	Protocol string `xml:"Protocol,omitempty"`
+}
+
+// RoutingRule is a routing rule of a website, set through the S3 API.
+type RoutingRule struct {
+	Condition *RoutingCondition `xml:"Condition,omitempty"`
+	Redirect  RoutingRedirect   `xml:"Redirect"`
+}
+
+// RoutingCondition is the condition of a routing rule.
+type RoutingCondition struct {
+	KeyPrefixEquals             string `xml:"KeyPrefixEquals,omitempty"`
+	HttpErrorCodeReturnedEquals string `xml:"HttpErrorCodeReturnedEquals,omitempty"`
+}
+
+// RoutingRedirect is the redirection of a routing rule.
+type RoutingRedirect struct {
+	HostName             string `xml:"HostName,omitempty"`
+	HttpRedirectCode     string `xml:"HttpRedirectCode,omitempty"`
+	Protocol             string `xml:"Protocol,omitempty"`
+	ReplaceKeyPrefixWith string `xml:"ReplaceKeyPrefixWith,omitempty"`
+	ReplaceKeyWith       string `xml:"ReplaceKeyWith,omitempty"`
+}
+
+// S3URL returns the URL of the S3 API, which serves buckets by global alias on the path.
+// Requests are authenticated by the access key ID of their signature, which isn't verified,
+// and need the owner permission on the bucket.
+func (s *Server) S3URL() string {
+	return s.s3.URL
+}
+
+// SetCORSRules replaces the CORS rules of a bucket.
+func (s *Server) SetCORSRules(bucketID string, rules ...CORSRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket, found := s.buckets[bucketID]; found {
+		bucket.CORSRules = rules
+	}
+}
+
+// SetLifecycleRules replaces the lifecycle rules of a bucket.
+func (s *Server) SetLifecycleRules(bucketID string, rules ...LifecycleRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket, found := s.buckets[bucketID]; found {
+		bucket.LifecycleRules = rules
+	}
+}
+
+// SetWebsiteRoutingRules replaces the website routing rules of a bucket.
+func (s *Server) SetWebsiteRoutingRules(bucketID string, rules ...RoutingRule) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket, found := s.buckets[bucketID]; found {
+		bucket.WebsiteRoutingRules = rules
+	}
+}
+
+// s3Subresources are the operations of each bucket subresource by method, keyed by the
+// query parameter of the subresource.
+var s3Subresources = map[string]map[string]string{
+	"cors": {
+		http.MethodGet:    "GetBucketCors",
+		http.MethodPut:    "PutBucketCors",
+		http.MethodDelete: "DeleteBucketCors",
+	},
+	"website": {
+		http.MethodGet:    "GetBucketWebsite",
+		http.MethodPut:    "PutBucketWebsite",
+		http.MethodDelete: "DeleteBucketWebsite",
+	},
+	"lifecycle": {
+		http.MethodGet:    "GetBucketLifecycleConfiguration",
+		http.MethodPut:    "PutBucketLifecycleConfiguration",
+		http.MethodDelete: "DeleteBucketLifecycle",
+	},
+}
+
+// s3Operation returns the name of the S3 operation of a request, as used by the SDKs.
+func s3Operation(r *http.Request) string {
+	for parameter, operations := range s3Subresources {
+		if r.URL.Query().Has(parameter) {
+			return operations[r.Method]
+		}
+	}
+	return ""
+}
+
+// s3AccessKeyID returns the access key ID of the AWS signature v4 Authorization header.
+func s3AccessKeyID(authorization string) string {
+	_, credential, found := strings.Cut(authorization, "Credential=")
+	if !found {
+		return ""
+	}
+	keyID, _, _ := strings.Cut(credential, "/")
+	return keyID
+}
+
+// Namespace of the S3 API XML documents
+const s3Namespace = "http://s3.amazonaws.com/doc/2006-03-01/"
+
+// s3Error is an error response of the S3 API.
+type s3Error struct {
+	XMLName  xml.Name `xml:"Error"`
+	Code     string   `xml:"Code"`
+	Message  string   `xml:"Message"`
+	Resource string   `xml:"Resource"`
+}
+
+func writeS3Error(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	writeXML(w, status, s3Error{Code: code, Message: message, Resource: r.URL.Path})
+}
+
+func writeXML(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/xml")
+	w.WriteHeader(status)
+	_, _ = w.Write([]byte(xml.Header))
+	_ = xml.NewEncoder(w).Encode(body)
+}
+
+func (s *Server) serveS3(w http.ResponseWriter, r *http.Request) {
+	operation := s3Operation(r)
+	if operation == "" {
+		writeS3Error(w, r, http.StatusNotImplemented, "NotImplemented", "unsupported request "+r.Method+" "+r.URL.String())
+		return
+	}
+
+	latency, status := s.delay(operation)
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if status != 0 {
+		writeS3Error(w, r, status, "InjectedFault", "fault injected on "+operation)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	key := s.keys[s3AccessKeyID(r.Header.Get("Authorization"))]
+	if key == nil {
+		writeS3Error(w, r, http.StatusForbidden, "InvalidAccessKeyId", "unknown access key")
+		return
+	}
+	bucket := s.bucketByAlias(strings.Trim(r.URL.Path, "/"))
+	if bucket == nil {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+	if !bucket.Permissions[key.ID].Owner {
+		writeS3Error(w, r, http.StatusForbidden, "AccessDenied", "access key is not owner of the bucket")
+		return
+	}
+	s.s3Handlers()[operation](w, r, bucket)
+}
+
+func (s *Server) s3Handlers() map[string]func(http.ResponseWriter, *http.Request, *Bucket) {
+	return map[string]func(http.ResponseWriter, *http.Request, *Bucket){
+		"GetBucketCors":    s.getBucketCors,
+		"PutBucketCors":    s.putBucketCors,
+		"DeleteBucketCors": s.deleteBucketCors,
+
+		"GetBucketLifecycleConfiguration": s.getBucketLifecycle,
+		"PutBucketLifecycleConfiguration": s.putBucketLifecycle,
+		"DeleteBucketLifecycle":           s.deleteBucketLifecycle,
+
+		"GetBucketWebsite":    s.getBucketWebsite,
+		"PutBucketWebsite":    s.putBucketWebsite,
+		"DeleteBucketWebsite": s.deleteBucketWebsite,
+	}
+}
+
+// decodeXMLBody decodes the request body, answering a malformed XML error on failure.
+func decodeXMLBody(w http.ResponseWriter, r *http.Request, out any) bool {
+	if err := xml.NewDecoder(r.Body).Decode(out); err != nil {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", err.Error())
+		return false
+	}
+	return true
+}
+
+// ---- CORS
+
+type corsConfiguration struct {
+	XMLName xml.Name   `xml:"CORSConfiguration"`
+	Xmlns   string     `xml:"xmlns,attr,omitempty"`
+	Rules   []CORSRule `xml:"CORSRule"`
+}
+
+func (s *Server) getBucketCors(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	if len(bucket.CORSRules) == 0 {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchCORSConfiguration", "the CORS configuration does not exist")
+		return
+	}
+	writeXML(w, http.StatusOK, corsConfiguration{Xmlns: s3Namespace, Rules: bucket.CORSRules})
+}
+
+func (s *Server) putBucketCors(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	request := corsConfiguration{}
+	if !decodeXMLBody(w, r, &request) {
+		return
+	}
+	if len(request.Rules) == 0 {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", "at least one CORS rule is required")
+		return
+	}
+	bucket.CORSRules = request.Rules
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteBucketCors(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	bucket.CORSRules = nil
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---- lifecycle
+
+type lifecycleConfiguration struct {
+	XMLName xml.Name        `xml:"LifecycleConfiguration"`
+	Xmlns   string          `xml:"xmlns,attr,omitempty"`
+	Rules   []LifecycleRule `xml:"Rule"`
+}
+
+func (s *Server) getBucketLifecycle(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	if len(bucket.LifecycleRules) == 0 {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchLifecycleConfiguration", "the lifecycle configuration does not exist")
+		return
+	}
+	writeXML(w, http.StatusOK, lifecycleConfiguration{Xmlns: s3Namespace, Rules: bucket.LifecycleRules})
+}
+
+func (s *Server) putBucketLifecycle(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	request := lifecycleConfiguration{}
+	if !decodeXMLBody(w, r, &request) {
+		return
+	}
+	if len(request.Rules) == 0 {
+		writeS3Error(w, r, http.StatusBadRequest, "MalformedXML", "at least one lifecycle rule is required")
+		return
+	}
+	for _, rule := range request.Rules {
+		if rule.ExpirationDays == nil && rule.ExpirationDate == "" && rule.AbortIncompleteMultipartUploadDays == nil {
+			writeS3Error(w, r, http.StatusBadRequest, "InvalidRequest", "lifecycle rule without action")
+			return
+		}
+	}
+	bucket.LifecycleRules = request.Rules
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteBucketLifecycle(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	bucket.LifecycleRules = nil
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ---- website
+
+type websiteConfiguration struct {
+	XMLName               xml.Name            `xml:"WebsiteConfiguration"`
+	Xmlns                 string              `xml:"xmlns,attr,omitempty"`
+	IndexDocument         string              `xml:"IndexDocument>Suffix,omitempty"`
+	ErrorDocument         *string             `xml:"ErrorDocument>Key,omitempty"`
+	RedirectAllRequestsTo *WebsiteRedirectAll `xml:"RedirectAllRequestsTo,omitempty"`
+	RoutingRules          []RoutingRule       `xml:"RoutingRules>RoutingRule,omitempty"`
+}
+
+func (s *Server) getBucketWebsite(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	if !bucket.WebsiteAccess {
+		writeS3Error(w, r, http.StatusNotFound, "NoSuchWebsiteConfiguration", "the website configuration does not exist")
+		return
+	}
+	writeXML(w, http.StatusOK, websiteConfiguration{
+		Xmlns:                 s3Namespace,
+		IndexDocument:         bucket.IndexDocument,
+		ErrorDocument:         bucket.ErrorDocument,
+		RedirectAllRequestsTo: bucket.WebsiteRedirectAll,
+		RoutingRules:          bucket.WebsiteRoutingRules,
+	})
+}
+
+func (s *Server) putBucketWebsite(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	request := websiteConfiguration{}
+	if !decodeXMLBody(w, r, &request) {
+		return
+	}
+	if request.RedirectAllRequestsTo != nil {
+		if request.IndexDocument != "" || request.ErrorDocument != nil || len(request.RoutingRules) > 0 {
+			writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "RedirectAllRequestsTo excludes the other website settings")
+			return
+		}
+	} else if request.IndexDocument == "" {
+		writeS3Error(w, r, http.StatusBadRequest, "InvalidArgument", "IndexDocument is required")
+		return
+	}
+	bucket.WebsiteAccess = true
+	bucket.IndexDocument = request.IndexDocument
+	bucket.ErrorDocument = request.ErrorDocument
+	bucket.WebsiteRedirectAll = request.RedirectAllRequestsTo
+	bucket.WebsiteRoutingRules = request.RoutingRules
+	w.WriteHeader(http.StatusOK)
+}
+
+func (s *Server) deleteBucketWebsite(w http.ResponseWriter, r *http.Request, bucket *Bucket) {
+	bucket.WebsiteAccess = false
+	bucket.IndexDocument = ""
+	bucket.ErrorDocument = nil
+	bucket.WebsiteRedirectAll = nil
+	bucket.WebsiteRoutingRules = nil
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,1134 @@
+// Package garagefake is an in-memory fake of the Garage v2 admin API, served over HTTP
+// for tests. It covers access keys, buckets, aliases, permissions, cluster health,
+// status and layout with staged role changes, metadata snapshots and repair launches, the
+// CheckDomain special endpoint, and lets tests inject faults and latency per operation. The
+// bucket settings only exposed by the S3 API are served by a second, path-style S3 endpoint.
+package garagefake
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// AnyOperation matches every operation when injecting faults or latency.
+const AnyOperation = "*"
+
+// Key is an access key held by the fake.
+type Key struct {
+	ID           string
+	Secret       string
+	Name         string
+	Created      time.Time
+	Expiration   *time.Time
+	CreateBucket bool
+}
+
+// Permission is the permission of an access key on a bucket.
+type Permission struct {
+	Owner bool
+	Read  bool
+	Write bool
+}
+
+// Bucket is a bucket held by the fake.
+type Bucket struct {
+	ID             string
+	Created        time.Time
+	GlobalAliases  []string
+	Permissions    map[string]Permission
+	WebsiteAccess  bool
+	IndexDocument  string
+	ErrorDocument  *string
+	MaxObjects     *int64
+	MaxSize        *int64
+	Objects        int64
+	Bytes          int64
+	CORSRules      []CORSRule
+	LifecycleRules []LifecycleRule
+	// Website settings only exposed by the S3 API
+	WebsiteRedirectAll  *WebsiteRedirectAll
+	WebsiteRoutingRules []RoutingRule
+}
+
+// Node is a node of the fake cluster.
+type Node struct {
+	ID       string
+	Hostname string
+	Zone     string
+	Capacity *int64
+	IsUp     bool
+}
+
+// RoleChange is a staged change of the role of a node: its removal, or a zone and capacity.
+type RoleChange struct {
+	ID       string
+	Remove   bool
+	Zone     string
+	Capacity *int64
+}
+
+// roleChange is a staged role change in the wire format of the layout API.
+type roleChange struct {
+	ID       string   `json:"id"`
+	Remove   bool     `json:"remove,omitempty"`
+	Zone     string   `json:"zone,omitempty"`
+	Capacity *int64   `json:"capacity,omitempty"`
+	Tags     []string `json:"tags,omitempty"`
+}
+
+// fault makes an operation fail with the given status, count times or forever when negative.
+type fault struct {
+	status int
+	count  int
+}
+
+// Server is the fake admin API server. It must be closed once the test is done.
+type Server struct {
+	server *httptest.Server
+	s3     *httptest.Server
+	token  string
+
+	mu            sync.Mutex
+	keys          map[string]*Key
+	buckets       map[string]*Bucket
+	nodes         []Node
+	layoutVersion int64
+	staged        []RoleChange
+	// Nodes whose role was removed from the layout
+	removed map[string]bool
+	faults  map[string]*fault
+	latency map[string]time.Duration
+	calls   map[string]int
+}
+
+// NewServer starts a fake admin API accepting the given admin token, with a single
+// healthy storage node in layout version 1.
+func NewServer(token string) *Server {
+	capacity := int64(1 << 30)
+	s := &Server{
+		token:         token,
+		keys:          map[string]*Key{},
+		buckets:       map[string]*Bucket{},
+		nodes:         []Node{{ID: randomHex(32), Hostname: "garage-0", Zone: "garage", Capacity: &capacity, IsUp: true}},
+		layoutVersion: 1,
+		removed:       map[string]bool{},
+		faults:        map[string]*fault{},
+		latency:       map[string]time.Duration{},
+		calls:         map[string]int{},
+	}
+	s.server = httptest.NewServer(http.HandlerFunc(s.serveHTTP))
+	s.s3 = httptest.NewServer(http.HandlerFunc(s.serveS3))
+	return s
+}
+
+// Close shuts the server down.
+func (s *Server) Close() {
+	s.server.Close()
+	s.s3.Close()
+}
+
+// Host returns the host:port the server listens on.
+func (s *Server) Host() string {
+	return s.server.Listener.Addr().String()
+}
+
+// Address returns the host and port the server listens on, as set in an instance spec.
+func (s *Server) Address() (string, int) {
+	host, port, _ := net.SplitHostPort(s.Host())
+	portNumber, _ := strconv.Atoi(port)
+	return host, portNumber
+}
+
+// InjectFault makes the next count calls of the operation fail with the given HTTP
+// status, or every call when count is negative. A status of 0 removes the fault.
+func (s *Server) InjectFault(operation string, status int, count int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if status == 0 {
+		delete(s.faults, operation)
+		return
+	}
+	s.faults[operation] = &fault{status: status, count: count}
+}
+
+// SetLatency delays every call of the operation. A zero duration removes the latency.
+func (s *Server) SetLatency(operation string, latency time.Duration) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if latency == 0 {
+		delete(s.latency, operation)
+		return
+	}
+	s.latency[operation] = latency
+}
+
+// Calls returns the number of calls received by the operation, failed ones included.
+func (s *Server) Calls(operation string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls[operation]
+}
+
+// SetNodes replaces the nodes of the cluster.
+func (s *Server) SetNodes(nodes ...Node) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nodes = nodes
+}
+
+// SetLayoutVersion sets the version of the current cluster layout.
+func (s *Server) SetLayoutVersion(version int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.layoutVersion = version
+}
+
+// LayoutVersion returns the version of the current cluster layout.
+func (s *Server) LayoutVersion() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.layoutVersion
+}
+
+// StageRoleChanges stages role changes, as done with the garage CLI.
+func (s *Server) StageRoleChanges(changes ...RoleChange) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, change := range changes {
+		s.stageRoleChange(change)
+	}
+}
+
+// StagedRoleChanges returns the role changes staged and not yet applied.
+func (s *Server) StagedRoleChanges() []RoleChange {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]RoleChange(nil), s.staged...)
+}
+
+// HasRole returns true when the node has a role in the current layout.
+func (s *Server) HasRole(nodeID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return !s.removed[nodeID]
+}
+
+// AddKey creates an access key and returns a copy of it.
+func (s *Server) AddKey(name string) Key {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return *s.addKey(name)
+}
+
+// AddBucket creates a bucket with the given global aliases and returns a copy of it.
+func (s *Server) AddBucket(globalAliases ...string) Bucket {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return copyBucket(s.addBucket(globalAliases...))
+}
+
+// Allow grants permissions of a key on a bucket.
+func (s *Server) Allow(bucketID string, keyID string, permission Permission) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket, found := s.buckets[bucketID]; found {
+		bucket.Permissions[keyID] = permission
+	}
+}
+
+// SetWebsiteAccess enables website access on a bucket with the given index document,
+// or disables it when the index document is empty.
+func (s *Server) SetWebsiteAccess(bucketID string, indexDocument string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket, found := s.buckets[bucketID]; found {
+		bucket.WebsiteAccess = indexDocument != ""
+		bucket.IndexDocument = indexDocument
+		bucket.ErrorDocument = nil
+		bucket.WebsiteRedirectAll = nil
+		bucket.WebsiteRoutingRules = nil
+	}
+}
+
+// SetBucketUsage sets the objects and bytes stored in a bucket. A bucket holding
+// objects can't be deleted.
+func (s *Server) SetBucketUsage(bucketID string, objects int64, bytes int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket, found := s.buckets[bucketID]; found {
+		bucket.Objects = objects
+		bucket.Bytes = bytes
+	}
+}
+
+// KeyByName returns a copy of the access key with the given name.
+func (s *Server) KeyByName(name string) (Key, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range s.keys {
+		if key.Name == name {
+			return *key, true
+		}
+	}
+	return Key{}, false
+}
+
+// BucketByAlias returns a copy of the bucket with the given global alias.
+func (s *Server) BucketByAlias(alias string) (Bucket, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if bucket := s.bucketByAlias(alias); bucket != nil {
+		return copyBucket(bucket), true
+	}
+	return Bucket{}, false
+}
+
+// BucketCount returns the number of buckets.
+func (s *Server) BucketCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.buckets)
+}
+
+// KeyCount returns the number of access keys.
+func (s *Server) KeyCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.keys)
+}
+
+func (s *Server) addKey(name string) *Key {
+	key := &Key{
+		ID:      "GK" + randomHex(12),
+		Secret:  randomHex(32),
+		Name:    name,
+		Created: time.Now().UTC(),
+	}
+	s.keys[key.ID] = key
+	return key
+}
+
+func (s *Server) addBucket(globalAliases ...string) *Bucket {
+	bucket := &Bucket{
+		ID:            randomHex(32),
+		Created:       time.Now().UTC(),
+		GlobalAliases: append([]string{}, globalAliases...),
+		Permissions:   map[string]Permission{},
+	}
+	s.buckets[bucket.ID] = bucket
+	return bucket
+}
+
+func (s *Server) bucketByAlias(alias string) *Bucket {
+	for _, bucket := range s.buckets {
+		for _, globalAlias := range bucket.GlobalAliases {
+			if globalAlias == alias {
+				return bucket
+			}
+		}
+	}
+	return nil
+}
+
+func copyBucket(bucket *Bucket) Bucket {
+	out := *bucket
+	out.GlobalAliases = append([]string{}, bucket.GlobalAliases...)
+	out.CORSRules = append([]CORSRule(nil), bucket.CORSRules...)
+	out.LifecycleRules = append([]LifecycleRule(nil), bucket.LifecycleRules...)
+	out.WebsiteRoutingRules = append([]RoutingRule(nil), bucket.WebsiteRoutingRules...)
+	out.Permissions = map[string]Permission{}
+	for id, permission := range bucket.Permissions {
+		out.Permissions[id] = permission
+	}
+	return out
+}
+
+func randomHex(size int) string {
+	b := make([]byte, size)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// apiError is an error response of the admin API.
+type apiError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Region  string `json:"region"`
+	Path    string `json:"path"`
+}
+
+func writeError(w http.ResponseWriter, r *http.Request, status int, code string, message string) {
+	writeJSON(w, status, apiError{Code: code, Message: message, Region: "garage", Path: r.URL.Path})
+}
+
+func writeJSON(w http.ResponseWriter, status int, body any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(body)
+}
+
+// delay returns the latency and fault of an operation, counting the call.
+func (s *Server) delay(operation string) (time.Duration, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.calls[operation]++
+
+	latency := s.latency[operation] + s.latency[AnyOperation]
+	status := 0
+	for _, name := range []string{operation, AnyOperation} {
+		f, found := s.faults[name]
+		if !found {
+			continue
+		}
+		status = f.status
+		if f.count > 0 {
+			f.count--
+			if f.count == 0 {
+				delete(s.faults, name)
+			}
+		}
+		break
+	}
+	return latency, status
+}
+
+func (s *Server) serveHTTP(w http.ResponseWriter, r *http.Request) {
+	operation, found := strings.CutPrefix(r.URL.Path, "/v2/")
+	// CheckDomain is a special endpoint, outside of the versioned API and without authentication
+	public := r.URL.Path == "/check"
+	if public {
+		operation = "CheckDomain"
+	} else if !found {
+		writeError(w, r, http.StatusNotFound, "NotFound", "unknown path "+r.URL.Path)
+		return
+	}
+	if !public && r.Header.Get("Authorization") != "Bearer "+s.token {
+		writeError(w, r, http.StatusForbidden, "AccessDenied", "invalid admin token")
+		return
+	}
+
+	latency, status := s.delay(operation)
+	if latency > 0 {
+		select {
+		case <-time.After(latency):
+		case <-r.Context().Done():
+			return
+		}
+	}
+	if status != 0 {
+		writeError(w, r, status, "InjectedFault", "fault injected on "+operation)
+		return
+	}
+
+	handler, found := s.handlers()[operation]
+	if !found {
+		writeError(w, r, http.StatusNotFound, "NotFound", "unsupported operation "+operation)
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	handler(w, r)
+}
+
+func (s *Server) handlers() map[string]http.HandlerFunc {
+	return map[string]http.HandlerFunc{
+		"GetClusterHealth":            s.getClusterHealth,
+		"GetClusterStatus":            s.getClusterStatus,
+		"GetClusterLayout":            s.getClusterLayout,
+		"UpdateClusterLayout":         s.updateClusterLayout,
+		"PreviewClusterLayoutChanges": s.previewClusterLayoutChanges,
+		"ApplyClusterLayout":          s.applyClusterLayout,
+		"RevertClusterLayout":         s.revertClusterLayout,
+		"GetClusterLayoutHistory":     s.getClusterLayoutHistory,
+		"GetNodeInfo":                 s.getNodeInfo,
+		"CheckDomain":                 s.checkDomain,
+		"ListKeys":                    s.listKeys,
+		"CreateKey":                   s.createKey,
+		"GetKeyInfo":                  s.getKeyInfo,
+		"UpdateKey":                   s.updateKey,
+		"DeleteKey":                   s.deleteKey,
+		"ListBuckets":                 s.listBuckets,
+		"CreateBucket":                s.createBucket,
+		"GetBucketInfo":               s.getBucketInfo,
+		"UpdateBucket":                s.updateBucket,
+		"DeleteBucket":                s.deleteBucket,
+		"AddBucketAlias":              s.addBucketAlias,
+		"RemoveBucketAlias":           s.removeBucketAlias,
+		"AllowBucketKey":              s.allowBucketKey,
+		"DenyBucketKey":               s.denyBucketKey,
+		"CreateMetadataSnapshot":      s.nodeOperation,
+		"LaunchRepairOperation":       s.nodeOperation,
+		"ListBlockErrors":             s.emptyNodeResponse,
+		"ListWorkers":                 s.emptyNodeResponse,
+	}
+}
+
+// decodeBody decodes the request body, answering a bad request on failure.
+func decodeBody(w http.ResponseWriter, r *http.Request, out any) bool {
+	if err := json.NewDecoder(r.Body).Decode(out); err != nil {
+		writeError(w, r, http.StatusBadRequest, "InvalidRequest", err.Error())
+		return false
+	}
+	return true
+}
+
+// ---- cluster
+
+func (s *Server) getClusterHealth(w http.ResponseWriter, r *http.Request) {
+	up := 0
+	for _, node := range s.nodes {
+		if node.IsUp {
+			up++
+		}
+	}
+	status := "healthy"
+	if up < len(s.nodes) {
+		status = "degraded"
+	}
+	if up == 0 {
+		status = "unavailable"
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"status":           status,
+		"knownNodes":       len(s.nodes),
+		"connectedNodes":   up,
+		"storageNodes":     len(s.nodes),
+		"storageNodesUp":   up,
+		"partitions":       256,
+		"partitionsQuorum": 256,
+		"partitionsAllOk":  256,
+	})
+}
+
+func (s *Server) getClusterStatus(w http.ResponseWriter, r *http.Request) {
+	nodes := []map[string]any{}
+	for _, node := range s.nodes {
+		var role any
+		if !s.removed[node.ID] {
+			role = map[string]any{"zone": node.Zone, "tags": []string{}, "capacity": node.Capacity}
+		}
+		nodes = append(nodes, map[string]any{
+			"id":              node.ID,
+			"garageVersion":   "v2.1.0",
+			"addr":            "10.0.0.1:3901",
+			"hostname":        node.Hostname,
+			"isUp":            node.IsUp,
+			"lastSeenSecsAgo": nil,
+			"role":            role,
+			"draining":        false,
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"layoutVersion": s.layoutVersion, "nodes": nodes})
+}
+
+func (s *Server) getClusterLayout(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.layout(s.nodes, s.removed, s.layoutVersion))
+}
+
+// layout returns the layout of the given node roles, with the staged changes.
+func (s *Server) layout(nodes []Node, removed map[string]bool, version int64) map[string]any {
+	roles := []map[string]any{}
+	for _, node := range nodes {
+		if removed[node.ID] {
+			continue
+		}
+		roles = append(roles, map[string]any{
+			"id":               node.ID,
+			"zone":             node.Zone,
+			"tags":             []string{},
+			"capacity":         node.Capacity,
+			"storedPartitions": 256,
+			"usableCapacity":   node.Capacity,
+		})
+	}
+	staged := []roleChange{}
+	for _, change := range s.staged {
+		staged = append(staged, roleChange{ID: change.ID, Remove: change.Remove, Zone: change.Zone, Capacity: change.Capacity})
+	}
+	return map[string]any{
+		"version":           version,
+		"roles":             roles,
+		"parameters":        map[string]any{"zoneRedundancy": "maximum"},
+		"partitionSize":     1 << 22,
+		"stagedRoleChanges": staged,
+		"stagedParameters":  nil,
+	}
+}
+
+// stageRoleChange stages a role change, replacing the one staged for the same node.
+func (s *Server) stageRoleChange(change RoleChange) {
+	for i := range s.staged {
+		if s.staged[i].ID == change.ID {
+			s.staged[i] = change
+			return
+		}
+	}
+	s.staged = append(s.staged, change)
+}
+
+// stagedLayout returns the node roles once the staged changes are applied, and an error
+// message when the layout would have no storage node.
+func (s *Server) stagedLayout() ([]Node, map[string]bool, string) {
+	nodes := append([]Node(nil), s.nodes...)
+	removed := map[string]bool{}
+	for id := range s.removed {
+		removed[id] = true
+	}
+	for _, change := range s.staged {
+		for i := range nodes {
+			if nodes[i].ID != change.ID {
+				continue
+			}
+			if change.Remove {
+				removed[change.ID] = true
+			} else {
+				nodes[i].Zone = change.Zone
+				nodes[i].Capacity = change.Capacity
+				delete(removed, change.ID)
+			}
+		}
+	}
+	for _, node := range nodes {
+		if !removed[node.ID] && node.Capacity != nil {
+			return nodes, removed, ""
+		}
+	}
+	return nodes, removed, "The new layout has no storage node"
+}
+
+func (s *Server) updateClusterLayout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Roles []roleChange `json:"roles"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	for _, change := range req.Roles {
+		s.stageRoleChange(RoleChange{ID: change.ID, Remove: change.Remove, Zone: change.Zone, Capacity: change.Capacity})
+	}
+	writeJSON(w, http.StatusOK, s.layout(s.nodes, s.removed, s.layoutVersion))
+}
+
+func (s *Server) previewClusterLayoutChanges(w http.ResponseWriter, r *http.Request) {
+	if len(s.staged) == 0 {
+		writeJSON(w, http.StatusOK, map[string]any{"error": "No changes to the cluster layout"})
+		return
+	}
+	nodes, removed, message := s.stagedLayout()
+	if message != "" {
+		writeJSON(w, http.StatusOK, map[string]any{"error": message})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message":   []string{fmt.Sprintf("%d role changes", len(s.staged))},
+		"newLayout": s.layout(nodes, removed, s.layoutVersion+1),
+	})
+}
+
+func (s *Server) applyClusterLayout(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Version int64 `json:"version"`
+	}
+	if !decodeBody(w, r, &req) {
+		return
+	}
+	if req.Version != s.layoutVersion+1 {
+		writeError(w, r, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("layout version %d is not the next one", req.Version))
+		return
+	}
+	nodes, removed, message := s.stagedLayout()
+	if len(s.staged) == 0 || message != "" {
+		writeError(w, r, http.StatusBadRequest, "InvalidRequest", "no valid layout changes staged")
+		return
+	}
+	s.nodes, s.removed, s.staged = nodes, removed, nil
+	s.layoutVersion = req.Version
+	writeJSON(w, http.StatusOK, map[string]any{
+		"message": []string{fmt.Sprintf("Layout version %d applied", s.layoutVersion)},
+		"layout":  s.layout(s.nodes, s.removed, s.layoutVersion),
+	})
+}
+
+func (s *Server) revertClusterLayout(w http.ResponseWriter, r *http.Request) {
+	s.staged = nil
+	writeJSON(w, http.StatusOK, s.layout(s.nodes, s.removed, s.layoutVersion))
+}
+
+// getClusterLayoutHistory reports the current layout version as synced on every node.
+func (s *Server) getClusterLayoutHistory(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"currentVersion": s.layoutVersion,
+		"minAck":         s.layoutVersion,
+		"versions":       []map[string]any{{"version": s.layoutVersion, "status": "Current"}},
+	})
+}
+
+// getNodeInfo answers for the first node, the one serving the admin API.
+func (s *Server) getNodeInfo(w http.ResponseWriter, r *http.Request) {
+	node := s.nodes[0]
+	success := map[string]any{node.ID: map[string]any{"nodeId": node.ID, "garageVersion": "v2.1.0"}}
+	writeJSON(w, http.StatusOK, map[string]any{"success": success, "error": map[string]string{}})
+}
+
+// emptyNodeResponse answers the multi-node operations with no result on every node.
+func (s *Server) emptyNodeResponse(w http.ResponseWriter, r *http.Request) {
+	success := map[string][]any{}
+	for _, node := range s.nodes {
+		success[node.ID] = []any{}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": success, "error": map[string]string{}})
+}
+
+// nodeOperation answers the multi-node operations without result, succeeding on the node
+// of the request, or on every node for *.
+func (s *Server) nodeOperation(w http.ResponseWriter, r *http.Request) {
+	target := r.URL.Query().Get("node")
+	success := map[string]map[string]any{}
+	for _, node := range s.nodes {
+		if target == "*" || target == node.ID {
+			success[node.ID] = map[string]any{}
+		}
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"success": success, "error": map[string]string{}})
+}
+
+// checkDomain answers whether a website is served on the domain, the fake having no root
+// domain for the web endpoint: the domain has to be a global alias of a website bucket.
+func (s *Server) checkDomain(w http.ResponseWriter, r *http.Request) {
+	domain := r.URL.Query().Get("domain")
+	bucket := s.bucketByAlias(domain)
+	if domain == "" || bucket == nil || !bucket.WebsiteAccess {
+		writeError(w, r, http.StatusBadRequest, "InvalidRequest", fmt.Sprintf("Domain '%s' is not managed by Garage", domain))
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain")
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintf(w, "Domain '%s' is managed by Garage", domain)
+}
+
+// ---- access keys
+
+type keyPerm struct {
+	CreateBucket *bool `json:"createBucket"`
+}
+
+type updateKeyRequest struct {
+	Name         *string    `json:"name"`
+	Allow        *keyPerm   `json:"allow"`
+	Deny         *keyPerm   `json:"deny"`
+	Expiration   *time.Time `json:"expiration"`
+	NeverExpires bool       `json:"neverExpires"`
+}
+
+func (s *Server) keyInfo(key *Key, showSecret bool) map[string]any {
+	buckets := []map[string]any{}
+	for _, bucket := range s.sortedBuckets() {
+		if permission, found := bucket.Permissions[key.ID]; found {
+			buckets = append(buckets, map[string]any{
+				"id":            bucket.ID,
+				"globalAliases": bucket.GlobalAliases,
+				"localAliases":  []string{},
+				"permissions":   bucketPermission(permission),
+			})
+		}
+	}
+	var secret *string
+	if showSecret {
+		secret = &key.Secret
+	}
+	return map[string]any{
+		"accessKeyId":     key.ID,
+		"name":            key.Name,
+		"created":         key.Created,
+		"expiration":      key.Expiration,
+		"expired":         key.Expiration != nil && key.Expiration.Before(time.Now()),
+		"secretAccessKey": secret,
+		"permissions":     map[string]any{"createBucket": key.CreateBucket},
+		"buckets":         buckets,
+	}
+}
+
+// findKey returns the key of the id or search query parameter.
+func (s *Server) findKey(r *http.Request) *Key {
+	if id := r.URL.Query().Get("id"); id != "" {
+		return s.keys[id]
+	}
+	if search := r.URL.Query().Get("search"); search != "" {
+		for _, key := range s.keys {
+			if key.Name == search || strings.HasPrefix(key.ID, search) {
+				return key
+			}
+		}
+	}
+	return nil
+}
+
+func applyKeyUpdate(key *Key, request updateKeyRequest) {
+	if request.Name != nil {
+		key.Name = *request.Name
+	}
+	if request.Allow != nil && request.Allow.CreateBucket != nil && *request.Allow.CreateBucket {
+		key.CreateBucket = true
+	}
+	if request.Deny != nil && request.Deny.CreateBucket != nil && *request.Deny.CreateBucket {
+		key.CreateBucket = false
+	}
+	if request.NeverExpires {
+		key.Expiration = nil
+	} else if request.Expiration != nil {
+		expiration := *request.Expiration
+		key.Expiration = &expiration
+	}
+}
+
+func (s *Server) listKeys(w http.ResponseWriter, r *http.Request) {
+	keys := []map[string]any{}
+	for _, key := range s.keys {
+		keys = append(keys, map[string]any{
+			"id":         key.ID,
+			"name":       key.Name,
+			"created":    key.Created,
+			"expiration": key.Expiration,
+			"expired":    key.Expiration != nil && key.Expiration.Before(time.Now()),
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i]["id"].(string) < keys[j]["id"].(string) })
+	writeJSON(w, http.StatusOK, keys)
+}
+
+func (s *Server) createKey(w http.ResponseWriter, r *http.Request) {
+	request := updateKeyRequest{}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	name := ""
+	if request.Name != nil {
+		name = *request.Name
+	}
+	key := s.addKey(name)
+	applyKeyUpdate(key, request)
+	writeJSON(w, http.StatusOK, s.keyInfo(key, true))
+}
+
+func (s *Server) getKeyInfo(w http.ResponseWriter, r *http.Request) {
+	key := s.findKey(r)
+	if key == nil {
+		writeError(w, r, http.StatusNotFound, "NoSuchAccessKey", "access key not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.keyInfo(key, r.URL.Query().Get("showSecretKey") == "true"))
+}
+
+func (s *Server) updateKey(w http.ResponseWriter, r *http.Request) {
+	key := s.findKey(r)
+	if key == nil {
+		writeError(w, r, http.StatusNotFound, "NoSuchAccessKey", "access key not found")
+		return
+	}
+	request := updateKeyRequest{}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	applyKeyUpdate(key, request)
+	writeJSON(w, http.StatusOK, s.keyInfo(key, false))
+}
+
+func (s *Server) deleteKey(w http.ResponseWriter, r *http.Request) {
+	key := s.findKey(r)
+	if key == nil {
+		writeError(w, r, http.StatusNotFound, "NoSuchAccessKey", "access key not found")
+		return
+	}
+	delete(s.keys, key.ID)
+	for _, bucket := range s.buckets {
+		delete(bucket.Permissions, key.ID)
+	}
+	w.WriteHeader(http.StatusOK)
+}
+
+// ---- buckets
+
+type bucketKeyPerm struct {
+	Owner *bool `json:"owner"`
+	Read  *bool `json:"read"`
+	Write *bool `json:"write"`
+}
+
+type updateBucketRequest struct {
+	WebsiteAccess *struct {
+		Enabled       bool    `json:"enabled"`
+		IndexDocument *string `json:"indexDocument"`
+		ErrorDocument *string `json:"errorDocument"`
+	} `json:"websiteAccess"`
+	Quotas *struct {
+		MaxSize    *int64 `json:"maxSize"`
+		MaxObjects *int64 `json:"maxObjects"`
+	} `json:"quotas"`
+}
+
+type bucketAliasRequest struct {
+	BucketID    string `json:"bucketId"`
+	GlobalAlias string `json:"globalAlias"`
+}
+
+type bucketKeyPermChange struct {
+	BucketID    string        `json:"bucketId"`
+	AccessKeyID string        `json:"accessKeyId"`
+	Permissions bucketKeyPerm `json:"permissions"`
+}
+
+func bucketPermission(permission Permission) map[string]bool {
+	return map[string]bool{"owner": permission.Owner, "read": permission.Read, "write": permission.Write}
+}
+
+func (s *Server) sortedBuckets() []*Bucket {
+	buckets := []*Bucket{}
+	for _, bucket := range s.buckets {
+		buckets = append(buckets, bucket)
+	}
+	sort.Slice(buckets, func(i, j int) bool { return buckets[i].ID < buckets[j].ID })
+	return buckets
+}
+
+func (s *Server) bucketInfo(bucket *Bucket) map[string]any {
+	keys := []map[string]any{}
+	for id, permission := range bucket.Permissions {
+		name := ""
+		if key, found := s.keys[id]; found {
+			name = key.Name
+		}
+		keys = append(keys, map[string]any{
+			"accessKeyId":        id,
+			"name":               name,
+			"permissions":        bucketPermission(permission),
+			"bucketLocalAliases": []string{},
+		})
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i]["accessKeyId"].(string) < keys[j]["accessKeyId"].(string) })
+	var websiteConfig map[string]any
+	if bucket.WebsiteAccess {
+		websiteConfig = map[string]any{"indexDocument": bucket.IndexDocument, "errorDocument": bucket.ErrorDocument}
+	}
+	return map[string]any{
+		"id":                             bucket.ID,
+		"created":                        bucket.Created,
+		"globalAliases":                  bucket.GlobalAliases,
+		"websiteAccess":                  bucket.WebsiteAccess,
+		"websiteConfig":                  websiteConfig,
+		"keys":                           keys,
+		"objects":                        bucket.Objects,
+		"bytes":                          bucket.Bytes,
+		"unfinishedUploads":              0,
+		"unfinishedMultipartUploads":     0,
+		"unfinishedMultipartUploadParts": 0,
+		"unfinishedMultipartUploadBytes": 0,
+		"quotas":                         map[string]any{"maxSize": bucket.MaxSize, "maxObjects": bucket.MaxObjects},
+	}
+}
+
+// findBucket returns the bucket of the id, globalAlias or search query parameter.
+func (s *Server) findBucket(r *http.Request) *Bucket {
+	query := r.URL.Query()
+	if id := query.Get("id"); id != "" {
+		return s.buckets[id]
+	}
+	if alias := query.Get("globalAlias"); alias != "" {
+		return s.bucketByAlias(alias)
+	}
+	if search := query.Get("search"); search != "" {
+		if bucket := s.bucketByAlias(search); bucket != nil {
+			return bucket
+		}
+		for _, bucket := range s.buckets {
+			if strings.HasPrefix(bucket.ID, search) {
+				return bucket
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) listBuckets(w http.ResponseWriter, r *http.Request) {
+	buckets := []map[string]any{}
+	for _, bucket := range s.sortedBuckets() {
+		buckets = append(buckets, map[string]any{
+			"id":            bucket.ID,
+			"created":       bucket.Created,
+			"globalAliases": bucket.GlobalAliases,
+			"localAliases":  []any{},
+		})
+	}
+	writeJSON(w, http.StatusOK, buckets)
+}
+
+func (s *Server) createBucket(w http.ResponseWriter, r *http.Request) {
+	request := struct {
+		GlobalAlias *string `json:"globalAlias"`
+	}{}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	aliases := []string{}
+	if request.GlobalAlias != nil {
+		if s.bucketByAlias(*request.GlobalAlias) != nil {
+			writeError(w, r, http.StatusConflict, "BucketAlreadyExists", "bucket "+*request.GlobalAlias+" already exists")
+			return
+		}
+		aliases = append(aliases, *request.GlobalAlias)
+	}
+	writeJSON(w, http.StatusOK, s.bucketInfo(s.addBucket(aliases...)))
+}
+
+func (s *Server) getBucketInfo(w http.ResponseWriter, r *http.Request) {
+	bucket := s.findBucket(r)
+	if bucket == nil {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+	writeJSON(w, http.StatusOK, s.bucketInfo(bucket))
+}
+
+func (s *Server) updateBucket(w http.ResponseWriter, r *http.Request) {
+	bucket := s.findBucket(r)
+	if bucket == nil {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+	request := updateBucketRequest{}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	if website := request.WebsiteAccess; website != nil {
+		if website.Enabled && website.IndexDocument == nil {
+			writeError(w, r, http.StatusBadRequest, "InvalidRequest", "indexDocument is required to enable website access")
+			return
+		}
+		if !website.Enabled && (website.IndexDocument != nil || website.ErrorDocument != nil) {
+			writeError(w, r, http.StatusBadRequest, "InvalidRequest", "indexDocument and errorDocument must be unset to disable website access")
+			return
+		}
+		bucket.WebsiteAccess = website.Enabled
+		bucket.IndexDocument = ""
+		bucket.ErrorDocument = nil
+		bucket.WebsiteRedirectAll = nil
+		bucket.WebsiteRoutingRules = nil
+		if website.Enabled {
+			bucket.IndexDocument = *website.IndexDocument
+			bucket.ErrorDocument = website.ErrorDocument
+		}
+	}
+	if quotas := request.Quotas; quotas != nil {
+		bucket.MaxSize = quotas.MaxSize
+		bucket.MaxObjects = quotas.MaxObjects
+	}
+	writeJSON(w, http.StatusOK, s.bucketInfo(bucket))
+}
+
+func (s *Server) deleteBucket(w http.ResponseWriter, r *http.Request) {
+	bucket := s.findBucket(r)
+	if bucket == nil {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+	if bucket.Objects > 0 {
+		writeError(w, r, http.StatusBadRequest, "BucketNotEmpty", "bucket is not empty")
+		return
+	}
+	delete(s.buckets, bucket.ID)
+	w.WriteHeader(http.StatusOK)
+}
+
+// ---- aliases
+
+func (s *Server) addBucketAlias(w http.ResponseWriter, r *http.Request) {
+	request := bucketAliasRequest{}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	bucket, found := s.buckets[request.BucketID]
+	if !found {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+	if other := s.bucketByAlias(request.GlobalAlias); other != nil && other != bucket {
+		writeError(w, r, http.StatusConflict, "BucketAlreadyExists", "alias "+request.GlobalAlias+" is used by another bucket")
+		return
+	}
+	if s.bucketByAlias(request.GlobalAlias) == nil {
+		bucket.GlobalAliases = append(bucket.GlobalAliases, request.GlobalAlias)
+	}
+	writeJSON(w, http.StatusOK, s.bucketInfo(bucket))
+}
+
+func (s *Server) removeBucketAlias(w http.ResponseWriter, r *http.Request) {
+	request := bucketAliasRequest{}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	bucket, found := s.buckets[request.BucketID]
+	if !found {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+	aliases := []string{}
+	for _, alias := range bucket.GlobalAliases {
+		if alias != request.GlobalAlias {
+			aliases = append(aliases, alias)
+		}
+	}
+	bucket.GlobalAliases = aliases
+	writeJSON(w, http.StatusOK, s.bucketInfo(bucket))
+}
+
+// ---- permissions
+
+// changePermission sets or clears the permissions of the request flagged true.
+func (s *Server) changePermission(w http.ResponseWriter, r *http.Request, value bool) {
+	request := bucketKeyPermChange{}
+	if !decodeBody(w, r, &request) {
+		return
+	}
+	bucket, found := s.buckets[request.BucketID]
+	if !found {
+		writeError(w, r, http.StatusNotFound, "NoSuchBucket", "bucket not found")
+		return
+	}
+	if _, found := s.keys[request.AccessKeyID]; !found {
+		writeError(w, r, http.StatusNotFound, "NoSuchAccessKey", "access key not found")
+		return
+	}
+	permission := bucket.Permissions[request.AccessKeyID]
+	if request.Permissions.Owner != nil && *request.Permissions.Owner {
+		permission.Owner = value
+	}
+	if request.Permissions.Read != nil && *request.Permissions.Read {
+		permission.Read = value
+	}
+	if request.Permissions.Write != nil && *request.Permissions.Write {
+		permission.Write = value
+	}
+	if permission == (Permission{}) {
+		delete(bucket.Permissions, request.AccessKeyID)
+	} else {
+		bucket.Permissions[request.AccessKeyID] = permission
+	}
+	writeJSON(w, http.StatusOK, s.bucketInfo(bucket))
+}
+
+func (s *Server) allowBucketKey(w http.ResponseWriter, r *http.Request) {
+	s.changePermission(w, r, true)
+}
+
+func (s *Server) denyBucketKey(w http.ResponseWriter, r *http.Request) {
+	s.changePermission(w, r, false)
+}
@@ -0,0 +1,361 @@
+package garagefake
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+const testToken = "test-admin-token"
+
+// call performs an admin API call and decodes the response into out when given.
+func call(t *testing.T, ctx context.Context, s *Server, method string, path string, body string, out any) int {
+	t.Helper()
+	req, err := http.NewRequestWithContext(ctx, method, "http://"+s.Host()+"/v2/"+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return 0
+	}
+	defer resp.Body.Close()
+	if out != nil && resp.StatusCode == http.StatusOK {
+		if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+			t.Fatalf("failed to decode %s response: %v", path, err)
+		}
+	}
+	return resp.StatusCode
+}
+
+func TestServerRejectsInvalidToken(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, "http://"+s.Host()+"/v2/GetClusterHealth", nil)
+	req.Header.Set("Authorization", "Bearer wrong")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("expected 403, got %d", resp.StatusCode)
+	}
+}
+
+func TestServerBuckets(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+	ctx := context.Background()
+	key := s.AddKey("app")
+
+	info := map[string]any{}
+	if status := call(t, ctx, s, http.MethodPost, "CreateBucket", `{"globalAlias":"photos"}`, &info); status != http.StatusOK {
+		t.Fatalf("CreateBucket: expected 200, got %d", status)
+	}
+	id := info["id"].(string)
+	if status := call(t, ctx, s, http.MethodPost, "CreateBucket", `{"globalAlias":"photos"}`, nil); status != http.StatusConflict {
+		t.Errorf("CreateBucket with a used alias: expected 409, got %d", status)
+	}
+
+	call(t, ctx, s, http.MethodPost, "AddBucketAlias", `{"bucketId":"`+id+`","globalAlias":"images"}`, nil)
+	call(t, ctx, s, http.MethodPost, "RemoveBucketAlias", `{"bucketId":"`+id+`","globalAlias":"photos"}`, nil)
+	call(t, ctx, s, http.MethodPost, "AllowBucketKey", `{"bucketId":"`+id+`","accessKeyId":"`+key.ID+`","permissions":{"read":true,"write":true}}`, nil)
+	call(t, ctx, s, http.MethodPost, "DenyBucketKey", `{"bucketId":"`+id+`","accessKeyId":"`+key.ID+`","permissions":{"write":true}}`, nil)
+	call(t, ctx, s, http.MethodPost, "UpdateBucket?id="+id, `{"websiteAccess":{"enabled":true,"indexDocument":"index.html"},"quotas":{"maxObjects":10}}`, nil)
+
+	bucket, found := s.BucketByAlias("images")
+	if !found {
+		t.Fatal("expected the bucket to be found by its new alias")
+	}
+	if _, found := s.BucketByAlias("photos"); found {
+		t.Error("expected the removed alias not to resolve")
+	}
+	if bucket.Permissions[key.ID] != (Permission{Read: true}) {
+		t.Errorf("expected read permission only, got %+v", bucket.Permissions[key.ID])
+	}
+	if !bucket.WebsiteAccess || bucket.IndexDocument != "index.html" || bucket.MaxObjects == nil || *bucket.MaxObjects != 10 {
+		t.Errorf("unexpected bucket settings: %+v", bucket)
+	}
+
+	s.SetBucketUsage(id, 1, 100)
+	if status := call(t, ctx, s, http.MethodPost, "DeleteBucket?id="+id, "", nil); status != http.StatusBadRequest {
+		t.Errorf("DeleteBucket of a bucket holding objects: expected 400, got %d", status)
+	}
+	s.SetBucketUsage(id, 0, 0)
+	if status := call(t, ctx, s, http.MethodPost, "DeleteBucket?id="+id, "", nil); status != http.StatusOK {
+		t.Errorf("DeleteBucket: expected 200, got %d", status)
+	}
+	if s.BucketCount() != 0 {
+		t.Errorf("expected no bucket left, got %d", s.BucketCount())
+	}
+}
+
+func TestServerKeys(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+	ctx := context.Background()
+
+	created := map[string]any{}
+	call(t, ctx, s, http.MethodPost, "CreateKey", `{"name":"app","allow":{"createBucket":true}}`, &created)
+	id := created["accessKeyId"].(string)
+	if created["secretAccessKey"] == nil {
+		t.Error("expected the secret key in the CreateKey response")
+	}
+
+	updated := map[string]any{}
+	call(t, ctx, s, http.MethodPost, "UpdateKey?id="+id, `{"deny":{"createBucket":true}}`, &updated)
+	if updated["secretAccessKey"] != nil {
+		t.Error("expected no secret key in the UpdateKey response")
+	}
+	shown := map[string]any{}
+	call(t, ctx, s, http.MethodGet, "GetKeyInfo?id="+id+"&showSecretKey=true", "", &shown)
+	if shown["secretAccessKey"] != created["secretAccessKey"] {
+		t.Errorf("expected GetKeyInfo to show the secret key %v, got %v", created["secretAccessKey"], shown["secretAccessKey"])
+	}
+
+	key, found := s.KeyByName("app")
+	if !found || key.ID != id || key.CreateBucket {
+		t.Errorf("unexpected key %+v (found %v)", key, found)
+	}
+
+	call(t, ctx, s, http.MethodPost, "DeleteKey?id="+id, "", nil)
+	if status := call(t, ctx, s, http.MethodGet, "GetKeyInfo?id="+id, "", nil); status != http.StatusNotFound {
+		t.Errorf("GetKeyInfo of a deleted key: expected 404, got %d", status)
+	}
+}
+
+// callS3 performs an S3 API call signed with the access key, and returns the status and body.
+func callS3(t *testing.T, s *Server, method string, path string, keyID string, body string) (int, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, s.S3URL()+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatalf("failed to build request: %v", err)
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+keyID+"/20250101/garage/s3/aws4_request, SignedHeaders=host, Signature=0")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer resp.Body.Close()
+	out, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(out)
+}
+
+func TestServerCheckDomain(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+	bucket := s.AddBucket("site", "www.example.com")
+	s.AddBucket("files.example.com")
+
+	check := func(domain string) int {
+		// CheckDomain needs no admin token
+		resp, err := http.Get("http://" + s.Host() + "/check?domain=" + domain)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+	if status := check("www.example.com"); status != http.StatusBadRequest {
+		t.Errorf("domain of a bucket without website: expected 400, got %d", status)
+	}
+	s.SetWebsiteAccess(bucket.ID, "index.html")
+	if status := check("www.example.com"); status != http.StatusOK {
+		t.Errorf("domain of a website bucket: expected 200, got %d", status)
+	}
+	for _, domain := range []string{"files.example.com", "unknown.example.com"} {
+		if status := check(domain); status != http.StatusBadRequest {
+			t.Errorf("domain %s: expected 400, got %d", domain, status)
+		}
+	}
+}
+
+func TestServerLayout(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+	ctx := context.Background()
+	capacity := int64(1 << 30)
+	s.SetNodes(
+		Node{ID: "a", Hostname: "garage-0", Zone: "dc1", Capacity: &capacity, IsUp: true},
+		Node{ID: "b", Hostname: "garage-1", Zone: "dc1", Capacity: &capacity, IsUp: true},
+	)
+
+	if status := call(t, ctx, s, http.MethodPost, "UpdateClusterLayout", `{"roles":[{"id":"b","remove":true}]}`, nil); status != http.StatusOK {
+		t.Fatalf("UpdateClusterLayout: expected 200, got %d", status)
+	}
+	preview := map[string]any{}
+	if status := call(t, ctx, s, http.MethodPost, "PreviewClusterLayoutChanges", "", &preview); status != http.StatusOK || preview["newLayout"] == nil {
+		t.Fatalf("PreviewClusterLayoutChanges: expected a new layout, got %d %v", status, preview)
+	}
+	if status := call(t, ctx, s, http.MethodPost, "ApplyClusterLayout", `{"version":3}`, nil); status != http.StatusBadRequest {
+		t.Errorf("ApplyClusterLayout of a wrong version: expected 400, got %d", status)
+	}
+	if status := call(t, ctx, s, http.MethodPost, "ApplyClusterLayout", `{"version":2}`, nil); status != http.StatusOK {
+		t.Fatalf("ApplyClusterLayout: expected 200, got %d", status)
+	}
+	if s.LayoutVersion() != 2 || s.HasRole("b") || !s.HasRole("a") || len(s.StagedRoleChanges()) != 0 {
+		t.Errorf("expected the role of b removed in version 2, got version %d and staged %v", s.LayoutVersion(), s.StagedRoleChanges())
+	}
+
+	// Removing the last storage node is refused, and reverting drops the staged changes
+	s.StageRoleChanges(RoleChange{ID: "a", Remove: true})
+	preview = map[string]any{}
+	if call(t, ctx, s, http.MethodPost, "PreviewClusterLayoutChanges", "", &preview); preview["error"] == nil {
+		t.Errorf("expected a preview error without storage node, got %v", preview)
+	}
+	if status := call(t, ctx, s, http.MethodPost, "RevertClusterLayout", "", nil); status != http.StatusOK || len(s.StagedRoleChanges()) != 0 {
+		t.Errorf("RevertClusterLayout: expected no staged changes, got %d %v", status, s.StagedRoleChanges())
+	}
+}
+
+func TestServerS3CORS(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+	key := s.AddKey("owner")
+	bucket := s.AddBucket("site")
+	cors := `<CORSConfiguration><CORSRule><AllowedOrigin>https://app.example.com</AllowedOrigin><AllowedMethod>GET</AllowedMethod><MaxAgeSeconds>60</MaxAgeSeconds></CORSRule></CORSConfiguration>`
+
+	if status, _ := callS3(t, s, http.MethodPut, "/site?cors", key.ID, cors); status != http.StatusForbidden {
+		t.Errorf("PutBucketCors without owner permission: expected 403, got %d", status)
+	}
+	s.Allow(bucket.ID, key.ID, Permission{Owner: true})
+	if status, _ := callS3(t, s, http.MethodGet, "/site?cors", key.ID, ""); status != http.StatusNotFound {
+		t.Errorf("GetBucketCors without rules: expected 404, got %d", status)
+	}
+	if status, _ := callS3(t, s, http.MethodPut, "/site?cors", key.ID, cors); status != http.StatusOK {
+		t.Fatalf("PutBucketCors: expected 200, got %d", status)
+	}
+
+	got, _ := s.BucketByAlias("site")
+	if len(got.CORSRules) != 1 || got.CORSRules[0].AllowedOrigins[0] != "https://app.example.com" || *got.CORSRules[0].MaxAgeSeconds != 60 {
+		t.Errorf("unexpected CORS rules %+v", got.CORSRules)
+	}
+	if status, body := callS3(t, s, http.MethodGet, "/site?cors", key.ID, ""); status != http.StatusOK || !strings.Contains(body, "<AllowedMethod>GET</AllowedMethod>") {
+		t.Errorf("GetBucketCors: expected 200 with the rule, got %d: %s", status, body)
+	}
+	if status, _ := callS3(t, s, http.MethodDelete, "/site?cors", key.ID, ""); status != http.StatusNoContent {
+		t.Errorf("DeleteBucketCors: expected 204, got %d", status)
+	}
+	if got, _ := s.BucketByAlias("site"); len(got.CORSRules) != 0 {
+		t.Errorf("expected the CORS rules to be deleted, got %+v", got.CORSRules)
+	}
+}
+
+func TestServerS3Lifecycle(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+	key := s.AddKey("owner")
+	bucket := s.AddBucket("logs")
+	s.Allow(bucket.ID, key.ID, Permission{Owner: true})
+
+	empty := `<LifecycleConfiguration><Rule><Status>Enabled</Status><Filter><Prefix>tmp/</Prefix></Filter></Rule></LifecycleConfiguration>`
+	if status, _ := callS3(t, s, http.MethodPut, "/logs?lifecycle", key.ID, empty); status != http.StatusBadRequest {
+		t.Errorf("PutBucketLifecycleConfiguration of a rule without action: expected 400, got %d", status)
+	}
+	lifecycle := `<LifecycleConfiguration><Rule><ID>tmp</ID><Status>Enabled</Status><Filter><Prefix>tmp/</Prefix></Filter><Expiration><Days>1</Days></Expiration></Rule></LifecycleConfiguration>`
+	if status, _ := callS3(t, s, http.MethodPut, "/logs?lifecycle", key.ID, lifecycle); status != http.StatusOK {
+		t.Fatalf("PutBucketLifecycleConfiguration: expected 200, got %d", status)
+	}
+	got, _ := s.BucketByAlias("logs")
+	if len(got.LifecycleRules) != 1 || *got.LifecycleRules[0].Prefix != "tmp/" || *got.LifecycleRules[0].ExpirationDays != 1 {
+		t.Errorf("unexpected lifecycle rules %+v", got.LifecycleRules)
+	}
+	if status, body := callS3(t, s, http.MethodGet, "/logs?lifecycle", key.ID, ""); status != http.StatusOK || !strings.Contains(body, "<Expiration><Days>1</Days></Expiration>") {
+		t.Errorf("GetBucketLifecycleConfiguration: expected 200 with the rule, got %d: %s", status, body)
+	}
+	callS3(t, s, http.MethodDelete, "/logs?lifecycle", key.ID, "")
+	if status, _ := callS3(t, s, http.MethodGet, "/logs?lifecycle", key.ID, ""); status != http.StatusNotFound {
+		t.Errorf("GetBucketLifecycleConfiguration after deletion: expected 404, got %d", status)
+	}
+}
+
+func TestServerS3Website(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+	key := s.AddKey("owner")
+	bucket := s.AddBucket("site")
+	s.Allow(bucket.ID, key.ID, Permission{Owner: true})
+
+	if status, _ := callS3(t, s, http.MethodGet, "/site?website", key.ID, ""); status != http.StatusNotFound {
+		t.Errorf("GetBucketWebsite of a bucket without website: expected 404, got %d", status)
+	}
+	invalid := `<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RedirectAllRequestsTo><HostName>example.com</HostName></RedirectAllRequestsTo></WebsiteConfiguration>`
+	if status, _ := callS3(t, s, http.MethodPut, "/site?website", key.ID, invalid); status != http.StatusBadRequest {
+		t.Errorf("PutBucketWebsite with a redirection and an index document: expected 400, got %d", status)
+	}
+	website := `<WebsiteConfiguration><IndexDocument><Suffix>index.html</Suffix></IndexDocument><RoutingRules><RoutingRule><Condition><KeyPrefixEquals>old/</KeyPrefixEquals></Condition><Redirect><ReplaceKeyPrefixWith>new/</ReplaceKeyPrefixWith></Redirect></RoutingRule></RoutingRules></WebsiteConfiguration>`
+	if status, _ := callS3(t, s, http.MethodPut, "/site?website", key.ID, website); status != http.StatusOK {
+		t.Fatalf("PutBucketWebsite: expected 200, got %d", status)
+	}
+	got, _ := s.BucketByAlias("site")
+	if !got.WebsiteAccess || got.IndexDocument != "index.html" || len(got.WebsiteRoutingRules) != 1 || got.WebsiteRoutingRules[0].Redirect.ReplaceKeyPrefixWith != "new/" {
+		t.Errorf("unexpected website settings %+v", got)
+	}
+
+	// Website access updates of the admin API replace the S3 settings
+	call(t, context.Background(), s, http.MethodPost, "UpdateBucket?id="+bucket.ID, `{"websiteAccess":{"enabled":true,"indexDocument":"index.html"}}`, nil)
+	if got, _ := s.BucketByAlias("site"); len(got.WebsiteRoutingRules) != 0 {
+		t.Errorf("expected the routing rules to be removed, got %+v", got.WebsiteRoutingRules)
+	}
+	if status := call(t, context.Background(), s, http.MethodPost, "UpdateBucket?id="+bucket.ID, `{"websiteAccess":{"enabled":false,"indexDocument":"index.html"}}`, nil); status != http.StatusBadRequest {
+		t.Errorf("UpdateBucket disabling website access with an index document: expected 400, got %d", status)
+	}
+}
+
+func TestServerFaults(t *testing.T) {
+	tests := []struct {
+		name      string
+		operation string
+		count     int
+		expected  []int
+	}{
+		{name: "once", operation: "ListBuckets", count: 1, expected: []int{503, 200, 200}},
+		{name: "twice", operation: "ListBuckets", count: 2, expected: []int{503, 503, 200}},
+		{name: "forever", operation: "ListBuckets", count: -1, expected: []int{503, 503, 503}},
+		{name: "any operation", operation: AnyOperation, count: 1, expected: []int{503, 200, 200}},
+		{name: "other operation", operation: "ListKeys", count: -1, expected: []int{200, 200, 200}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer(testToken)
+			defer s.Close()
+			s.InjectFault(tt.operation, http.StatusServiceUnavailable, tt.count)
+			for i, expected := range tt.expected {
+				if status := call(t, context.Background(), s, http.MethodGet, "ListBuckets", "", nil); status != expected {
+					t.Errorf("call %d: expected %d, got %d", i, expected, status)
+				}
+			}
+			if calls := s.Calls("ListBuckets"); calls != len(tt.expected) {
+				t.Errorf("expected %d calls, got %d", len(tt.expected), calls)
+			}
+		})
+	}
+}
+
+func TestServerLatency(t *testing.T) {
+	s := NewServer(testToken)
+	defer s.Close()
+	s.SetLatency("GetClusterHealth", time.Second)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if status := call(t, ctx, s, http.MethodGet, "GetClusterHealth", "", nil); status != 0 {
+		t.Errorf("expected the call to time out, got status %d", status)
+	}
+
+	s.SetLatency("GetClusterHealth", 0)
+	health := map[string]any{}
+	if status := call(t, context.Background(), s, http.MethodGet, "GetClusterHealth", "", &health); status != http.StatusOK {
+		t.Fatalf("expected 200 once the latency is removed, got %d", status)
+	}
+	if health["status"] != "healthy" {
+		t.Errorf("expected a healthy cluster, got %v", health["status"])
+	}
+}